@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/utils/uuid"
+
+	"cloud.google.com/go/firestore"
+)
+
+// パスワードの最小文字数（サインアップと同じ）
+const minPasswordLength = 8
+
+// 出力するユーザー情報（パスワードのハッシュなどは含めない）
+type userView struct {
+	ID                    string    `json:"id"`
+	Name                  string    `json:"name"`
+	Email                 string    `json:"email"`
+	Role                  string    `json:"role"`
+	Suspended             bool      `json:"suspended"`
+	PasswordResetRequired bool      `json:"passwordResetRequired"`
+	CreatedAt             time.Time `json:"createdAt"`
+}
+
+// 出力するチャット情報
+type chatView struct {
+	ID           string    `json:"id"`
+	Participants []string  `json:"participants"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// データ移行の定義
+type migration struct {
+	Name        string                                 `json:"name"`
+	Description string                                 `json:"description"`
+	run         func(ctx context.Context) (int, error) // 移行を実行し、更新したドキュメントの数を返す
+}
+
+// 実行できるデータ移行の一覧
+var migrations = []migration{
+	{
+		Name:        "rewrap-data-keys",
+		Description: "チャットのデータ鍵を有効なマスター鍵で再暗号化する（マスター鍵のローテーション後に実行）",
+		run: func(ctx context.Context) (int, error) {
+			return withEncryption(ctx, firebase.RewrapDataKeys)
+		},
+	},
+	{
+		Name:        "encrypt-messages",
+		Description: "保存時暗号化を有効にする前の平文のメッセージを暗号化する",
+		run: func(ctx context.Context) (int, error) {
+			return withEncryption(ctx, firebase.EncryptPlaintextMessages)
+		},
+	},
+	{
+		Name:        "normalize-emails",
+		Description: "保存されているメールアドレスを小文字にする（大文字を含むメールアドレスで登録したユーザーがログインできるようにする）",
+		run:         repository.NormalizeUserEmails,
+	},
+}
+
+// ユーザーを作成する
+func userCreate(args []string) (*result, error) {
+	fs := newFlagSet()
+	name := fs.String("name", "", "名前")
+	email := fs.String("email", "", "メールアドレス")
+	password := fs.String("password", "", "パスワード（8文字以上）")
+	admin := fs.Bool("admin", false, "管理者として作成する")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	*name = strings.TrimSpace(*name)
+	*email = domain.NormalizeEmail(*email)
+	if *name == "" || !strings.Contains(*email, "@") {
+		return nil, fmt.Errorf("%w: 名前と有効なメールアドレスを指定してください", errUsage)
+	}
+	if len(*password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: パスワードは8文字以上で指定してください", errUsage)
+	}
+
+	existing, err := repository.GetUserByEmail(*email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("このメールアドレスは既に登録されています: %s", existing.ID)
+	}
+
+	user, err := repository.CreateUser(*name, *email, *password)
+	if err != nil {
+		return nil, fmt.Errorf("ユーザーの作成に失敗: %w", err)
+	}
+	if *admin {
+		if err := repository.UpdateUserRole(user.ID, domain.RoleAdmin); err != nil {
+			return nil, fmt.Errorf("権限の変更に失敗: %w", err)
+		}
+		user.Role = domain.RoleAdmin
+	}
+	audit(domain.AuditUserCreated, user.ID, map[string]string{"role": user.Role})
+	return &result{Message: "ユーザーを作成しました: " + user.ID, Data: newUserView(user)}, nil
+}
+
+// 設定の管理者のメールアドレスで登録済みのアカウントを管理者にする（最初の管理者の用意に使う）
+// メールアドレスは登録時に確認していないため、アカウントの持ち主を確かめてから実行する
+// 権限を付与するのは実行した時だけなので、後から管理画面で権限を外すことができる
+func userSeedAdmins(args []string) (*result, error) {
+	if err := parseFlags(newFlagSet(), args); err != nil {
+		return nil, err
+	}
+	emails := config.Config.AdminEmailList()
+	if len(emails) == 0 {
+		return nil, errors.New("管理者のメールアドレスが設定されていません（ADMIN_EMAILS または [admin] emails）")
+	}
+
+	var granted []string
+	var lines []string
+	for _, email := range emails {
+		user, err := repository.GetUserByEmail(email)
+		if err != nil {
+			return nil, fmt.Errorf("ユーザーの取得に失敗: %w", err)
+		}
+		switch {
+		case user == nil:
+			lines = append(lines, "登録されていません: "+email)
+		case user.IsBot() || user.Suspended:
+			lines = append(lines, "ボットまたは停止中のアカウントのため変更しません: "+user.ID)
+		case user.IsAdmin():
+			lines = append(lines, "既に管理者です: "+user.ID)
+		default:
+			if err := repository.UpdateUserRole(user.ID, domain.RoleAdmin); err != nil {
+				return nil, fmt.Errorf("権限の変更に失敗: %w", err)
+			}
+			// 認証情報のバージョンを進めたので既存のセッションは使えないが、残さないよう削除する
+			count, err := repository.DeleteUserSessions(context.Background(), user.ID)
+			if err != nil {
+				return nil, fmt.Errorf("セッションの削除に失敗: %w", err)
+			}
+			audit(domain.AuditRoleChanged, user.ID, map[string]string{"old_role": user.Role, "new_role": domain.RoleAdmin, "sessions": strconv.Itoa(count)})
+			granted = append(granted, user.ID)
+			lines = append(lines, "管理者にしました: "+user.ID)
+		}
+	}
+	return &result{Message: strings.Join(lines, "\n"), Data: map[string]any{"granted": granted}}, nil
+}
+
+// アカウントを停止する
+func userSuspend(args []string) (*result, error) {
+	user, err := parseUser(args)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return nil, errors.New("管理者のアカウントは停止できません（先に権限を外してください）")
+	}
+	if err := repository.SuspendUser(user.ID); err != nil {
+		return nil, fmt.Errorf("アカウントの停止に失敗: %w", err)
+	}
+	user.Suspended = true
+	audit(domain.AuditUserSuspended, user.ID, nil)
+	return &result{Message: "アカウントを停止しました: " + user.ID, Data: newUserView(user)}, nil
+}
+
+// アカウントの停止を解除する
+func userUnsuspend(args []string) (*result, error) {
+	user, err := parseUser(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.UnsuspendUser(user.ID); err != nil {
+		return nil, fmt.Errorf("アカウントの停止の解除に失敗: %w", err)
+	}
+	user.Suspended = false
+	audit(domain.AuditUserUnsuspended, user.ID, nil)
+	return &result{Message: "アカウントの停止を解除しました: " + user.ID, Data: newUserView(user)}, nil
+}
+
+// ユーザーを削除する（取り消せないため -yes を必須にする）
+// 猶予期間を待たずに、ユーザーに紐づくデータもすぐに消去する
+func userDelete(args []string) (*result, error) {
+	fs := newFlagSet()
+	userRef := fs.String("user", "", "ユーザーIDまたはメールアドレス")
+	deleteMessages := fs.Bool("delete-messages", false, "送信したメッセージも削除する（指定しない場合は送信者を削除されたユーザーに置き換える）")
+	yes := fs.Bool("yes", false, "削除を確認する")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	if !*yes {
+		return nil, fmt.Errorf("%w: 削除は取り消せないため -yes を指定してください", errUsage)
+	}
+	user, err := findUser(*userRef)
+	if err != nil {
+		return nil, err
+	}
+	user.DeleteMessages = *deleteMessages
+	if err := repository.DeleteUser(context.Background(), user); err != nil {
+		return nil, fmt.Errorf("ユーザーの削除に失敗: %w", err)
+	}
+	messages := "anonymized"
+	if user.DeleteMessages {
+		messages = "deleted"
+	}
+	audit(domain.AuditUserDeleted, user.ID, map[string]string{"email": user.Email, "messages": messages})
+	return &result{Message: "ユーザーを削除しました: " + user.ID, Data: newUserView(user)}, nil
+}
+
+// パスワードを設定する、または次回ログイン時に再設定を求める
+// どちらの場合も既存のセッションは使えなくなる
+func userResetPassword(args []string) (*result, error) {
+	fs := newFlagSet()
+	userRef := fs.String("user", "", "ユーザーIDまたはメールアドレス")
+	password := fs.String("password", "", "新しいパスワード（8文字以上）")
+	require := fs.Bool("require", false, "次回ログイン時にパスワードの再設定を求める")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	if (*password == "") == !*require {
+		return nil, fmt.Errorf("%w: -password と -require のどちらか一方を指定してください", errUsage)
+	}
+	if *password != "" && len(*password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: パスワードは8文字以上で指定してください", errUsage)
+	}
+	user, err := findUser(*userRef)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsBot() {
+		return nil, errors.New("ボットにはパスワードがありません")
+	}
+
+	message := "パスワードを設定しました: " + user.ID
+	action := domain.AuditPasswordReset
+	if *require {
+		if err := repository.RequirePasswordReset(user.ID); err != nil {
+			return nil, fmt.Errorf("パスワードの再設定の要求に失敗: %w", err)
+		}
+		user.PasswordResetRequired = true
+		message = "パスワードの再設定を要求しました: " + user.ID
+		action = domain.AuditPasswordResetRequired
+	} else {
+		hashedPassword, err := uuid.HashPassword(*password)
+		if err != nil {
+			return nil, err
+		}
+		if err := repository.UpdateUserCredential(user.ID, "Password", hashedPassword); err != nil {
+			return nil, fmt.Errorf("パスワードの更新に失敗: %w", err)
+		}
+		user.PasswordResetRequired = false
+	}
+
+	// 認証情報のバージョンを進めたので既存のセッションは使えないが、残さないよう削除する
+	count, err := repository.DeleteUserSessions(context.Background(), user.ID)
+	audit(action, user.ID, map[string]string{"sessions": strconv.Itoa(count)})
+	if err != nil {
+		return nil, fmt.Errorf("セッションの削除に失敗: %w", err)
+	}
+	return &result{Message: message, Data: newUserView(user)}, nil
+}
+
+// ユーザーのセッションをすべて削除する
+func sessionRevoke(args []string) (*result, error) {
+	user, err := parseUser(args)
+	if err != nil {
+		return nil, err
+	}
+	count, err := repository.DeleteUserSessions(context.Background(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("セッションの削除に失敗: %w", err)
+	}
+	audit(domain.AuditSessionsRevoked, user.ID, map[string]string{"sessions": strconv.Itoa(count)})
+	if err := repository.UpdateUserField(user.ID, "IsOnline", false); err != nil {
+		return nil, fmt.Errorf("オンライン状態の更新に失敗: %w", err)
+	}
+	return &result{
+		Message: fmt.Sprintf("%d件のセッションを削除しました: %s", count, user.ID),
+		Data:    map[string]any{"userId": user.ID, "deleted": count},
+	}, nil
+}
+
+// 有効期限が切れたセッションを削除する
+func sessionPurge(args []string) (*result, error) {
+	if err := parseFlags(newFlagSet(), args); err != nil {
+		return nil, err
+	}
+	count, err := repository.PurgeExpiredSessions(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("セッションの削除に失敗: %w", err)
+	}
+	return &result{
+		Message: fmt.Sprintf("有効期限が切れた%d件のセッションを削除しました", count),
+		Data:    map[string]any{"deleted": count},
+	}, nil
+}
+
+// 削除予定日時を過ぎたアカウントを削除する（サーバーが定期的に行う処理をすぐに実行する）
+func userDeleteScheduled(args []string) (*result, error) {
+	if err := parseFlags(newFlagSet(), args); err != nil {
+		return nil, err
+	}
+	count, err := repository.DeleteScheduledAccounts(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("予約されたアカウントの削除に失敗（%d件は削除済み）: %w", count, err)
+	}
+	return &result{
+		Message: fmt.Sprintf("削除予定日時を過ぎた%d件のアカウントを削除しました", count),
+		Data:    map[string]any{"deleted": count},
+	}, nil
+}
+
+// ユーザーが参加しているチャットを更新日時の新しい順に一覧する
+func chatList(args []string) (*result, error) {
+	user, err := parseUser(args)
+	if err != nil {
+		return nil, err
+	}
+	chats, err := firebase.GetAllChats(context.Background(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("チャットの取得に失敗: %w", err)
+	}
+
+	views := make([]chatView, 0, len(chats))
+	for _, chat := range chats {
+		view := chatView{}
+		view.ID, _ = chat["id"].(string)
+		view.CreatedAt, _ = chat["createdAt"].(time.Time)
+		view.UpdatedAt, _ = chat["updatedAt"].(time.Time)
+		participants, _ := chat["participants"].([]interface{})
+		for _, p := range participants {
+			if id, ok := p.(string); ok {
+				view.Participants = append(view.Participants, id)
+			}
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].UpdatedAt.After(views[j].UpdatedAt) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d件のチャット", len(views))
+	for _, view := range views {
+		fmt.Fprintf(&b, "\n%s\t%s\t%s", view.ID, view.UpdatedAt.Format("2006-01-02 15:04"), strings.Join(view.Participants, ","))
+	}
+	return &result{Message: b.String(), Data: views}, nil
+}
+
+// デフォルトのアイコンをアップロードし直す（既存のアイコンは上書きする）
+func iconsSeed(args []string) (*result, error) {
+	if err := parseFlags(newFlagSet(), args); err != nil {
+		return nil, err
+	}
+	count, err := firebase.SeedDefaultIcons(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("デフォルトアイコンのアップロードに失敗: %w", err)
+	}
+	return &result{
+		Message: fmt.Sprintf("%d件のデフォルトアイコンをアップロードしました", count),
+		Data:    map[string]any{"uploaded": count},
+	}, nil
+}
+
+// 実行できるデータ移行を一覧する
+func migrateList(args []string) (*result, error) {
+	if err := parseFlags(newFlagSet(), args); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for i, m := range migrations {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\t%s", m.Name, m.Description)
+	}
+	return &result{Message: b.String(), Data: migrations}, nil
+}
+
+// データ移行を実行する
+func migrateRun(args []string) (*result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: データ移行の名前を1つ指定してください", errUsage)
+	}
+	for _, m := range migrations {
+		if m.Name != args[0] {
+			continue
+		}
+		count, err := m.run(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("データ移行 %s に失敗（%d件は更新済み）: %w", m.Name, count, err)
+		}
+		return &result{
+			Message: fmt.Sprintf("データ移行 %s を実行しました（%d件を更新）", m.Name, count),
+			Data:    map[string]any{"migration": m.Name, "updated": count},
+		}, nil
+	}
+	return nil, fmt.Errorf("不明なデータ移行です: %s（migrate list で一覧を確認してください）", args[0])
+}
+
+// 保存時暗号化を初期化して移行を実行する
+func withEncryption(ctx context.Context, run func(context.Context, *firestore.Client) (int, error)) (int, error) {
+	if err := firebase.InitEncryption(); err != nil {
+		return 0, fmt.Errorf("保存時暗号化の初期化に失敗: %w", err)
+	}
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	return run(ctx, client)
+}
+
+// 管理用コマンドの操作を監査ログに記録する（操作したユーザーは空、source に cli を記録する）
+// 記録に失敗しても操作は取り消さない
+func audit(action, targetID string, metadata map[string]string) {
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata["source"] = "cli"
+	event := &domain.AuditEvent{Action: action, TargetID: targetID, Metadata: metadata}
+	if err := repository.RecordAuditEvent(context.Background(), event); err != nil {
+		slog.Error("監査ログの記録に失敗", "error", err, "action", action, "target_user_id", targetID)
+	}
+}
+
+// -user のみを受け付けるコマンドの引数を解析し、ユーザーを取得する
+func parseUser(args []string) (*domain.User, error) {
+	fs := newFlagSet()
+	userRef := fs.String("user", "", "ユーザーIDまたはメールアドレス")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	return findUser(*userRef)
+}
+
+// IDまたはメールアドレス（@ を含む場合）でユーザーを取得する
+func findUser(ref string) (*domain.User, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("%w: -user にユーザーIDまたはメールアドレスを指定してください", errUsage)
+	}
+	if strings.Contains(ref, "@") {
+		user, err := repository.GetUserByEmail(ref)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, fmt.Errorf("ユーザーが見つかりません: %s", ref)
+		}
+		return user, nil
+	}
+	user, err := repository.GetUserByID(ref)
+	if err != nil {
+		return nil, fmt.Errorf("ユーザーが見つかりません: %s: %w", ref, err)
+	}
+	return user, nil
+}
+
+// サブコマンド用のフラグセットを作成する（エラーは呼び出し元で表示する）
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// フラグを解析する（余分な引数は受け付けない）
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: 余分な引数があります: %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// ユーザーを出力用に変換する
+func newUserView(user *domain.User) userView {
+	return userView{
+		ID:                    user.ID,
+		Name:                  user.Name,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		Suspended:             user.Suspended,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt,
+	}
+}
@@ -0,0 +1,119 @@
+// 運用作業のための管理用コマンド
+// サーバーと同じ設定ファイル・リポジトリ層を使い、Firestore・Storage を直接操作する
+//
+// 使い方: go run ./cmd/admin [-json] <コマンド> <サブコマンド> [オプション]
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// コマンドの実行結果
+// Message は人が読むための要約、Data は -json のときに出力する詳細
+type result struct {
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// サブコマンドの定義
+type command struct {
+	usage string                               // 引数の説明
+	help  string                               // コマンドの説明
+	run   func(args []string) (*result, error) // 実行する関数（args はサブコマンド以降の引数）
+}
+
+// 使い方の誤りを表すエラー（使い方を表示して終了コード2で終了する）
+var errUsage = errors.New("使い方が正しくありません")
+
+// コマンドの一覧（"グループ サブコマンド" をキーにする）
+var commands = map[string]command{
+	"user create":           {"-name 名前 -email メールアドレス -password パスワード [-admin]", "ユーザーを作成する", userCreate},
+	"user seed-admins":      {"", "設定の管理者のメールアドレスで登録済みのアカウントを管理者にする", userSeedAdmins},
+	"user suspend":          {"-user ユーザー", "アカウントを停止する", userSuspend},
+	"user unsuspend":        {"-user ユーザー", "アカウントの停止を解除する", userUnsuspend},
+	"user delete":           {"-user ユーザー [-delete-messages] -yes", "ユーザーと紐づくデータをすぐに削除する（相手が残るチャットのメッセージは送信者を削除されたユーザーに置き換える）", userDelete},
+	"user delete-scheduled": {"", "削除予定日時を過ぎたアカウントを削除する", userDeleteScheduled},
+	"user reset-password":   {"-user ユーザー (-password パスワード | -require)", "パスワードを設定する、または次回ログイン時に再設定を求める", userResetPassword},
+	"session revoke":        {"-user ユーザー", "ユーザーのセッションをすべて削除する（強制ログアウト）", sessionRevoke},
+	"session purge":         {"", "有効期限が切れたセッションを削除する", sessionPurge},
+	"chat list":             {"-user ユーザー", "ユーザーが参加しているチャットを一覧する", chatList},
+	"icons seed":            {"", "デフォルトのアイコンを Storage にアップロードし直す", iconsSeed},
+	"migrate list":          {"", "実行できるデータ移行を一覧する", migrateList},
+	"migrate run":           {"名前", "データ移行を実行する", migrateRun},
+}
+
+func main() {
+	// 標準出力はコマンドの結果（-json の場合はJSON）のみにするため、ログは標準エラー出力に出す
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	jsonOutput := flag.Bool("json", false, "結果をJSONで出力する")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "不明なコマンドです: %s %s\n\n", args[0], args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	res, err := cmd.run(args[2:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "%v\n使い方: admin %s %s %s\n", err, args[0], args[1], cmd.usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		if *jsonOutput {
+			printJSON(map[string]string{"error": err.Error()})
+		} else {
+			fmt.Fprintln(os.Stderr, "エラー:", err)
+		}
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		printJSON(res)
+		return
+	}
+	fmt.Println(res.Message)
+}
+
+// 結果をJSONで標準出力に出力する
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "JSONの出力に失敗:", err)
+		os.Exit(1)
+	}
+}
+
+// 使い方を表示する
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("使い方: admin [-json] <コマンド> <サブコマンド> [オプション]\n\n")
+	b.WriteString("ユーザーは -user にIDまたはメールアドレスで指定します。\n\nコマンド:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s\n      %s\n", strings.TrimSpace(name+" "+commands[name].usage), commands[name].help)
+	}
+	b.WriteString("\nオプション:\n  -json\n      結果をJSONで出力する（エラーは {\"error\": ...}）\n")
+	fmt.Fprint(os.Stderr, b.String())
+}
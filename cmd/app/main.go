@@ -1,35 +1,78 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"log/slog"
 	"os"
 
 	"security_chat_app/internal/config"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/metrics"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/infrastructure/router"
+	"security_chat_app/internal/interface/markup"
 	"security_chat_app/internal/usecase/chat"
+	logging "security_chat_app/internal/utils/log"
+	"security_chat_app/internal/web"
+
+	"cloud.google.com/go/firestore"
 )
 
 func main() {
 	// Firebaseの初期化
 	client, err := firebase.InitFirebase()
 	if err != nil {
-		log.Fatalf("Firebase初期化に失敗: %v", err)
+		slog.Error("Firebase初期化に失敗", "error", err)
+		os.Exit(1)
+	}
+
+	// メッセージの保存時暗号化の初期化
+	if err := firebase.InitEncryption(); err != nil {
+		slog.Error("保存時暗号化の初期化に失敗", "error", err)
+		os.Exit(1)
 	}
-	defer client.Close()
+	if config.Config.RewrapDataKeys {
+		count, err := firebase.RewrapDataKeys(context.Background(), client)
+		if err != nil {
+			slog.Error("データ鍵の再暗号化に失敗", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("データ鍵を再暗号化しました", "count", count)
+	}
+
+	// アクティブなセッション数をメトリクスとして公開する
+	metrics.RegisterActiveSessions(repository.CountActiveSessions)
 
 	// チャットリポジトリの作成
 	chatRepo := chat.NewChatRepository(client)
 	chatUsecase := chat.NewChatUsecase(chatRepo)
 	if chatUsecase == nil {
-		log.Fatal("チャットのユースケースの実装に不備があります")
+		slog.Error("チャットのユースケースの実装に不備があります")
+		os.Exit(1)
 	}
 
+	// テンプレートの読み込み（読み込めないテンプレートがある場合は起動しない）
+	assets := web.FS(config.Config.EmbedAssets)
+	if err := markup.LoadTemplates(assets); err != nil {
+		slog.Error("テンプレートの読み込みに失敗", "error", err)
+		os.Exit(1)
+	}
+
+	// バックグラウンドの処理（テンプレートの監視・アカウントの定期的な削除）は終了時に止める
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	if config.Config.ReloadTemplates() {
+		slog.Info("テンプレートの変更を監視します")
+		go markup.WatchTemplates(bgCtx)
+	}
+
+	// 削除予定日時を過ぎたアカウント・期限切れのエクスポートを定期的に削除する
+	go repository.RunAccountMaintenance(bgCtx, config.Config.AccountDeletionInterval)
+
 	// ルーティングの設定
-	httpRouter := router.SetupRouter(chatUsecase)
+	httpRouter := router.SetupRouter(chatUsecase, assets)
 	if httpRouter == nil {
-		log.Fatal("ルーティングの設定に不備があります")
+		slog.Error("ルーティングの設定に不備があります")
+		os.Exit(1)
 	}
 
 	// サーバーを起動
@@ -37,10 +80,41 @@ func main() {
 	port := config.Config.Port
 	if envPort := os.Getenv("PORT"); envPort != "" {
 		port = envPort
-		log.Printf("環境変数PORTを検出しました: %s", envPort)
+		slog.Info("環境変数PORTを検出しました", "port", envPort)
+	}
+	slog.Info("サーバーを起動します", "port", port)
+	srv := router.NewServer(":"+port, httpRouter)
+	serveErr := router.Serve(srv)
+	if serveErr != nil {
+		slog.Error("サーバーの停止中にエラーが発生しました", "error", serveErr)
 	}
-	log.Printf("サーバーを起動します。ポート: %s", port)
-	if err := http.ListenAndServe(":"+port, httpRouter); err != nil {
-		log.Fatal("サーバーの起動に失敗しました")
+
+	// 終了処理
+	stopBackground()
+	shutdown(client)
+	if serveErr != nil {
+		os.Exit(1)
+	}
+}
+
+// 終了処理（ユーザーをオフラインに戻し、Firestoreクライアントとログファイルを閉じる）
+func shutdown(client *firestore.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Config.ShutdownTimeout)
+	defer cancel()
+
+	count, err := repository.MarkOnlineUsersOffline(ctx)
+	if err != nil {
+		slog.Error("ユーザー状態の更新に失敗", "error", err)
+	} else {
+		slog.Info("ユーザーをオフライン状態にしました", "count", count)
+	}
+
+	if err := client.Close(); err != nil {
+		slog.Error("Firestoreクライアントの終了に失敗", "error", err)
+	}
+
+	slog.Info("サーバーを終了します")
+	if err := logging.CloseLogFile(); err != nil {
+		slog.Error("ログファイルの書き出しに失敗", "error", err)
 	}
 }
@@ -2,39 +2,113 @@ package config
 
 import (
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	utils "security_chat_app/internal/utils/log"
 
 	"gopkg.in/go-ini/ini.v1"
 )
 
+// 実行環境
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// アクセスログの形式
+const (
+	AccessLogCombined = "combined" // Combined Log Format
+	AccessLogJSON     = "json"     // Cloud Logging の httpRequest 形式
+	AccessLogOff      = "off"      // 出力しない
+)
+
 type ConfigList struct {
 	Port            string
 	LogFile         string
+	LogLevel        string        // ログの最低レベル（debug / info / warn / error）
+	LogFormat       string        // ログの出力形式（text / json、空の場合は実行環境に従う）
+	LogMaxSize      int           // ログファイルをローテーションするサイズ（MB、0の場合はサイズでローテーションしない）
+	LogMaxAge       time.Duration // ログファイルをローテーションするまでの時間（0の場合は時間でローテーションしない）
+	LogMaxBackups   int           // 残すローテーション済みのログファイルの数（0の場合はすべて残す）
+	AccessLogFormat string        // アクセスログの形式（combined / json / off、空の場合はログの出力形式に従う）
 	Static          string
+	Env             string
+	EmbedAssets     bool   // バイナリに埋め込んだテンプレートと静的ファイルを使うかどうか
+	TemplateReload  string // "true"/"false"（テンプレートの変更を監視して読み込み直すか、空の場合は開発環境のみ）
 	DefaultIconDir  string
 	ServiceKeyPath  string
 	ProjectId       string
 	StorageBucket   string
+
+	SessionAbsoluteTimeout time.Duration // ログインからの最大有効期間
+	SessionIdleTimeout     time.Duration // 無操作で失効するまでの期間
+	CookieSecure           string        // "true"/"false"（空の場合は実行環境に従う）
+	CookieSameSite         string        // "lax"/"strict"/"none"
+
+	MasterKey      string // 保存時暗号化のマスター鍵（Base64, 32バイト）
+	MasterKeyFile  string // マスター鍵ファイルのパス（鍵ID=Base64 を1行ずつ記述）
+	MasterKeyID    string // 有効なマスター鍵のID（空の場合は最後に読み込んだ鍵）
+	RewrapDataKeys bool   // 起動時にデータ鍵を有効なマスター鍵で再暗号化するかどうか
+
+	CSPReportOnly  bool   // CSPを Report-Only で送信するかどうか（違反を報告するがブロックしない）
+	CSPImgSrc      string // 画像の読み込みを許可する追加のオリジン（空白区切り）
+	HSTSMaxAge     int    // HSTSの有効期間（秒、0の場合は送信しない）
+	FrameOptions   string // X-Frame-Options
+	ReferrerPolicy string // Referrer-Policy
+
+	ReadTimeout       time.Duration // リクエスト全体の読み込みのタイムアウト
+	ReadHeaderTimeout time.Duration // リクエストヘッダーの読み込みのタイムアウト
+	WriteTimeout      time.Duration // レスポンスの書き込みのタイムアウト
+	IdleTimeout       time.Duration // Keep-Alive接続の待機時間
+	MaxHeaderBytes    int           // リクエストヘッダーの最大サイズ（バイト）
+	ShutdownTimeout   time.Duration // 終了時に処理中のリクエストを待つ最大時間
+	TrustedProxies    int           // 手前にあるリバースプロキシの数（X-Forwarded-For から接続元を求める、0の場合は接続元のアドレスを使う）
+
+	MetricsToken string // /metrics の取得に必要なBearerトークン（空の場合は認証なし）
+
+	WebhookAllowPrivate string        // "true"/"false"（ループバック・プライベートアドレスへの送信を許可するか、空の場合は開発環境のみ）
+	WebhookTimeout      time.Duration // Webhookの送信1回あたりのタイムアウト
+	WebhookMaxAttempts  int           // Webhookの配信に失敗した場合を含めた最大試行回数
+	WebhookRetryBackoff time.Duration // Webhookの再送までの最初の待ち時間（再送ごとに2倍にする）
+	IncomingRateLimit   int           // 受信用Webhookに1分あたりに投稿できるメッセージの数（Webhookごと・接続元ごと）
+
+	AdminEmails string // 管理者のメールアドレス（カンマ区切り、管理用コマンド user seed-admins で登録済みのアカウントを管理者にする）
+
+	AccountDeletionGracePeriod time.Duration // アカウントの削除を申請してから実際に削除するまでの猶予期間
+	AccountDeletionInterval    time.Duration // 削除予定日時を過ぎたアカウント・期限切れのエクスポートを確認する間隔
+	DataExportExpiry           time.Duration // エクスポートしたデータをダウンロードできる期間
 }
 
 var Config ConfigList
 
 func init() {
 	LoadConfig()
-	utils.LoggingSettings(Config.LogFile)
+	utils.LoggingSettings(utils.Options{
+		File:      Config.LogFile,
+		Level:     Config.LogLevel,
+		Format:    Config.LogFormat,
+		ProjectID: Config.ProjectId,
+
+		MaxSize:    int64(Config.LogMaxSize) * 1024 * 1024,
+		MaxAge:     Config.LogMaxAge,
+		MaxBackups: Config.LogMaxBackups,
+	})
 }
 
 func LoadConfig() {
 	Config = ConfigList{
-		Port:            "8080",
-		LogFile:         "",
-		Static:          "",
-		DefaultIconDir:  "",
-		ServiceKeyPath:  "",
-		ProjectId:       "",
-		StorageBucket:   "",
+		Port:           "8080",
+		LogFile:        "",
+		Static:         "",
+		Env:            "",
+		DefaultIconDir: "",
+		ServiceKeyPath: "",
+		ProjectId:      "",
+		StorageBucket:  "",
 	}
 
 	// 環境変数から読み込む（優先度が最も高い）
@@ -75,9 +149,126 @@ func loadConfigFromEnv(config *ConfigList) {
 	if logFile := os.Getenv("LOG_FILE"); logFile != "" {
 		config.LogFile = logFile
 	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.LogLevel = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.LogFormat = format
+	}
+	if maxSize := os.Getenv("LOG_MAX_SIZE"); maxSize != "" {
+		config.LogMaxSize = parseInt("LOG_MAX_SIZE", maxSize)
+	}
+	if maxAge := os.Getenv("LOG_MAX_AGE"); maxAge != "" {
+		config.LogMaxAge = parseDuration("LOG_MAX_AGE", maxAge)
+	}
+	if maxBackups := os.Getenv("LOG_MAX_BACKUPS"); maxBackups != "" {
+		config.LogMaxBackups = parseInt("LOG_MAX_BACKUPS", maxBackups)
+	}
+	if embed := os.Getenv("EMBED_ASSETS"); embed == "true" {
+		config.EmbedAssets = true
+	}
+	if reload := os.Getenv("TEMPLATE_RELOAD"); reload != "" {
+		config.TemplateReload = reload
+	}
+	if format := os.Getenv("ACCESS_LOG_FORMAT"); format != "" {
+		config.AccessLogFormat = format
+	}
 	if static := os.Getenv("STATIC_DIR"); static != "" {
 		config.Static = static
 	}
+	if env := os.Getenv("APP_ENV"); env != "" {
+		config.Env = env
+	}
+	if timeout := os.Getenv("SESSION_ABSOLUTE_TIMEOUT"); timeout != "" {
+		config.SessionAbsoluteTimeout = parseDuration("SESSION_ABSOLUTE_TIMEOUT", timeout)
+	}
+	if timeout := os.Getenv("SESSION_IDLE_TIMEOUT"); timeout != "" {
+		config.SessionIdleTimeout = parseDuration("SESSION_IDLE_TIMEOUT", timeout)
+	}
+	if secure := os.Getenv("COOKIE_SECURE"); secure != "" {
+		config.CookieSecure = secure
+	}
+	if sameSite := os.Getenv("COOKIE_SAMESITE"); sameSite != "" {
+		config.CookieSameSite = sameSite
+	}
+	if masterKey := os.Getenv("MASTER_KEY"); masterKey != "" {
+		config.MasterKey = masterKey
+	}
+	if masterKeyFile := os.Getenv("MASTER_KEY_FILE"); masterKeyFile != "" {
+		config.MasterKeyFile = masterKeyFile
+	}
+	if masterKeyID := os.Getenv("MASTER_KEY_ID"); masterKeyID != "" {
+		config.MasterKeyID = masterKeyID
+	}
+	if rewrap := os.Getenv("REWRAP_DATA_KEYS"); rewrap == "true" {
+		config.RewrapDataKeys = true
+	}
+	if timeout := os.Getenv("SERVER_READ_TIMEOUT"); timeout != "" {
+		config.ReadTimeout = parseDuration("SERVER_READ_TIMEOUT", timeout)
+	}
+	if timeout := os.Getenv("SERVER_READ_HEADER_TIMEOUT"); timeout != "" {
+		config.ReadHeaderTimeout = parseDuration("SERVER_READ_HEADER_TIMEOUT", timeout)
+	}
+	if timeout := os.Getenv("SERVER_WRITE_TIMEOUT"); timeout != "" {
+		config.WriteTimeout = parseDuration("SERVER_WRITE_TIMEOUT", timeout)
+	}
+	if timeout := os.Getenv("SERVER_IDLE_TIMEOUT"); timeout != "" {
+		config.IdleTimeout = parseDuration("SERVER_IDLE_TIMEOUT", timeout)
+	}
+	if maxHeaderBytes := os.Getenv("SERVER_MAX_HEADER_BYTES"); maxHeaderBytes != "" {
+		config.MaxHeaderBytes = parseInt("SERVER_MAX_HEADER_BYTES", maxHeaderBytes)
+	}
+	if timeout := os.Getenv("SERVER_SHUTDOWN_TIMEOUT"); timeout != "" {
+		config.ShutdownTimeout = parseDuration("SERVER_SHUTDOWN_TIMEOUT", timeout)
+	}
+	if proxies := os.Getenv("SERVER_TRUSTED_PROXIES"); proxies != "" {
+		config.TrustedProxies = parseInt("SERVER_TRUSTED_PROXIES", proxies)
+	}
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		config.MetricsToken = token
+	}
+	if allowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE"); allowPrivate != "" {
+		config.WebhookAllowPrivate = allowPrivate
+	}
+	if timeout := os.Getenv("WEBHOOK_TIMEOUT"); timeout != "" {
+		config.WebhookTimeout = parseDuration("WEBHOOK_TIMEOUT", timeout)
+	}
+	if maxAttempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); maxAttempts != "" {
+		config.WebhookMaxAttempts = parseInt("WEBHOOK_MAX_ATTEMPTS", maxAttempts)
+	}
+	if backoff := os.Getenv("WEBHOOK_RETRY_BACKOFF"); backoff != "" {
+		config.WebhookRetryBackoff = parseDuration("WEBHOOK_RETRY_BACKOFF", backoff)
+	}
+	if rateLimit := os.Getenv("WEBHOOK_INCOMING_RATE_LIMIT"); rateLimit != "" {
+		config.IncomingRateLimit = parseInt("WEBHOOK_INCOMING_RATE_LIMIT", rateLimit)
+	}
+	if adminEmails := os.Getenv("ADMIN_EMAILS"); adminEmails != "" {
+		config.AdminEmails = adminEmails
+	}
+	if gracePeriod := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); gracePeriod != "" {
+		config.AccountDeletionGracePeriod = parseDuration("ACCOUNT_DELETION_GRACE_PERIOD", gracePeriod)
+	}
+	if interval := os.Getenv("ACCOUNT_DELETION_INTERVAL"); interval != "" {
+		config.AccountDeletionInterval = parseDuration("ACCOUNT_DELETION_INTERVAL", interval)
+	}
+	if expiry := os.Getenv("ACCOUNT_EXPORT_EXPIRY"); expiry != "" {
+		config.DataExportExpiry = parseDuration("ACCOUNT_EXPORT_EXPIRY", expiry)
+	}
+	if reportOnly := os.Getenv("CSP_REPORT_ONLY"); reportOnly == "true" {
+		config.CSPReportOnly = true
+	}
+	if imgSrc := os.Getenv("CSP_IMG_SRC"); imgSrc != "" {
+		config.CSPImgSrc = imgSrc
+	}
+	if maxAge := os.Getenv("HSTS_MAX_AGE"); maxAge != "" {
+		config.HSTSMaxAge = parseInt("HSTS_MAX_AGE", maxAge)
+	}
+	if frameOptions := os.Getenv("FRAME_OPTIONS"); frameOptions != "" {
+		config.FrameOptions = frameOptions
+	}
+	if referrerPolicy := os.Getenv("REFERRER_POLICY"); referrerPolicy != "" {
+		config.ReferrerPolicy = referrerPolicy
+	}
 	if defaultIconDir := os.Getenv("DEFAULT_ICON_DIR"); defaultIconDir != "" {
 		config.DefaultIconDir = defaultIconDir
 	}
@@ -114,11 +305,180 @@ func loadConfigValues(cfg *ini.File, config *ConfigList) {
 			config.LogFile = logFile
 		}
 	}
+	if config.LogLevel == "" {
+		config.LogLevel = cfg.Section("web").Key("logLevel").String()
+	}
+	if config.LogFormat == "" {
+		config.LogFormat = cfg.Section("web").Key("logFormat").String()
+	}
+	if config.LogMaxSize == 0 {
+		if maxSize := cfg.Section("web").Key("logMaxSize").String(); maxSize != "" {
+			config.LogMaxSize = parseInt("logMaxSize", maxSize)
+		}
+	}
+	if config.LogMaxAge == 0 {
+		if maxAge := cfg.Section("web").Key("logMaxAge").String(); maxAge != "" {
+			config.LogMaxAge = parseDuration("logMaxAge", maxAge)
+		}
+	}
+	if config.LogMaxBackups == 0 {
+		if maxBackups := cfg.Section("web").Key("logMaxBackups").String(); maxBackups != "" {
+			config.LogMaxBackups = parseInt("logMaxBackups", maxBackups)
+		}
+	}
+	if config.AccessLogFormat == "" {
+		config.AccessLogFormat = cfg.Section("web").Key("accessLogFormat").String()
+	}
+	if !config.EmbedAssets {
+		config.EmbedAssets = cfg.Section("web").Key("embedAssets").MustBool(false)
+	}
+	if config.TemplateReload == "" {
+		config.TemplateReload = cfg.Section("web").Key("templateReload").String()
+	}
 	if config.Static == "" {
 		if static := cfg.Section("web").Key("static").String(); static != "" {
 			config.Static = static
 		}
 	}
+	if config.Env == "" {
+		if env := cfg.Section("web").Key("env").String(); env != "" {
+			config.Env = env
+		}
+	}
+	if config.SessionAbsoluteTimeout == 0 {
+		if timeout := cfg.Section("session").Key("absoluteTimeout").String(); timeout != "" {
+			config.SessionAbsoluteTimeout = parseDuration("absoluteTimeout", timeout)
+		}
+	}
+	if config.SessionIdleTimeout == 0 {
+		if timeout := cfg.Section("session").Key("idleTimeout").String(); timeout != "" {
+			config.SessionIdleTimeout = parseDuration("idleTimeout", timeout)
+		}
+	}
+	if config.CookieSecure == "" {
+		if secure := cfg.Section("session").Key("cookieSecure").String(); secure != "" {
+			config.CookieSecure = secure
+		}
+	}
+	if config.CookieSameSite == "" {
+		if sameSite := cfg.Section("session").Key("cookieSameSite").String(); sameSite != "" {
+			config.CookieSameSite = sameSite
+		}
+	}
+	if config.MasterKey == "" {
+		if masterKey := cfg.Section("encryption").Key("masterKey").String(); masterKey != "" {
+			config.MasterKey = masterKey
+		}
+	}
+	if config.MasterKeyFile == "" {
+		if masterKeyFile := cfg.Section("encryption").Key("masterKeyFile").String(); masterKeyFile != "" {
+			config.MasterKeyFile = masterKeyFile
+		}
+	}
+	if config.MasterKeyID == "" {
+		if masterKeyID := cfg.Section("encryption").Key("masterKeyId").String(); masterKeyID != "" {
+			config.MasterKeyID = masterKeyID
+		}
+	}
+	if !config.RewrapDataKeys {
+		config.RewrapDataKeys = cfg.Section("encryption").Key("rewrapOnStartup").MustBool(false)
+	}
+	if config.ReadTimeout == 0 {
+		if timeout := cfg.Section("server").Key("readTimeout").String(); timeout != "" {
+			config.ReadTimeout = parseDuration("readTimeout", timeout)
+		}
+	}
+	if config.ReadHeaderTimeout == 0 {
+		if timeout := cfg.Section("server").Key("readHeaderTimeout").String(); timeout != "" {
+			config.ReadHeaderTimeout = parseDuration("readHeaderTimeout", timeout)
+		}
+	}
+	if config.WriteTimeout == 0 {
+		if timeout := cfg.Section("server").Key("writeTimeout").String(); timeout != "" {
+			config.WriteTimeout = parseDuration("writeTimeout", timeout)
+		}
+	}
+	if config.IdleTimeout == 0 {
+		if timeout := cfg.Section("server").Key("idleTimeout").String(); timeout != "" {
+			config.IdleTimeout = parseDuration("idleTimeout", timeout)
+		}
+	}
+	if config.MaxHeaderBytes == 0 {
+		if maxHeaderBytes := cfg.Section("server").Key("maxHeaderBytes").String(); maxHeaderBytes != "" {
+			config.MaxHeaderBytes = parseInt("maxHeaderBytes", maxHeaderBytes)
+		}
+	}
+	if config.ShutdownTimeout == 0 {
+		if timeout := cfg.Section("server").Key("shutdownTimeout").String(); timeout != "" {
+			config.ShutdownTimeout = parseDuration("shutdownTimeout", timeout)
+		}
+	}
+	if config.TrustedProxies == 0 {
+		if proxies := cfg.Section("server").Key("trustedProxies").String(); proxies != "" {
+			config.TrustedProxies = parseInt("trustedProxies", proxies)
+		}
+	}
+	if config.MetricsToken == "" {
+		config.MetricsToken = cfg.Section("metrics").Key("token").String()
+	}
+	if config.WebhookAllowPrivate == "" {
+		config.WebhookAllowPrivate = cfg.Section("webhook").Key("allowPrivate").String()
+	}
+	if config.WebhookTimeout == 0 {
+		if timeout := cfg.Section("webhook").Key("timeout").String(); timeout != "" {
+			config.WebhookTimeout = parseDuration("webhook timeout", timeout)
+		}
+	}
+	if config.WebhookMaxAttempts == 0 {
+		if maxAttempts := cfg.Section("webhook").Key("maxAttempts").String(); maxAttempts != "" {
+			config.WebhookMaxAttempts = parseInt("webhook maxAttempts", maxAttempts)
+		}
+	}
+	if config.WebhookRetryBackoff == 0 {
+		if backoff := cfg.Section("webhook").Key("retryBackoff").String(); backoff != "" {
+			config.WebhookRetryBackoff = parseDuration("webhook retryBackoff", backoff)
+		}
+	}
+	if config.IncomingRateLimit == 0 {
+		if rateLimit := cfg.Section("webhook").Key("incomingRateLimit").String(); rateLimit != "" {
+			config.IncomingRateLimit = parseInt("webhook incomingRateLimit", rateLimit)
+		}
+	}
+	if config.AdminEmails == "" {
+		config.AdminEmails = cfg.Section("admin").Key("emails").String()
+	}
+	if config.AccountDeletionGracePeriod == 0 {
+		if gracePeriod := cfg.Section("account").Key("deletionGracePeriod").String(); gracePeriod != "" {
+			config.AccountDeletionGracePeriod = parseDuration("account deletionGracePeriod", gracePeriod)
+		}
+	}
+	if config.AccountDeletionInterval == 0 {
+		if interval := cfg.Section("account").Key("deletionInterval").String(); interval != "" {
+			config.AccountDeletionInterval = parseDuration("account deletionInterval", interval)
+		}
+	}
+	if config.DataExportExpiry == 0 {
+		if expiry := cfg.Section("account").Key("exportExpiry").String(); expiry != "" {
+			config.DataExportExpiry = parseDuration("account exportExpiry", expiry)
+		}
+	}
+	if !config.CSPReportOnly {
+		config.CSPReportOnly = cfg.Section("security").Key("cspReportOnly").MustBool(false)
+	}
+	if config.CSPImgSrc == "" {
+		config.CSPImgSrc = cfg.Section("security").Key("cspImgSrc").String()
+	}
+	if config.HSTSMaxAge == 0 {
+		if maxAge := cfg.Section("security").Key("hstsMaxAge").String(); maxAge != "" {
+			config.HSTSMaxAge = parseInt("hstsMaxAge", maxAge)
+		}
+	}
+	if config.FrameOptions == "" {
+		config.FrameOptions = cfg.Section("security").Key("frameOptions").String()
+	}
+	if config.ReferrerPolicy == "" {
+		config.ReferrerPolicy = cfg.Section("security").Key("referrerPolicy").String()
+	}
 	if config.DefaultIconDir == "" {
 		if defaultIconDir := cfg.Section("firebase").Key("defaultIconDir").String(); defaultIconDir != "" {
 			config.DefaultIconDir = defaultIconDir
@@ -153,6 +513,80 @@ func validateConfig(config *ConfigList) {
 		config.DefaultIconDir = "internal/web/images/defaultIcon"
 	}
 
+	// 実行環境の検証（未設定の場合は開発環境）
+	config.Env = strings.ToLower(config.Env)
+	if config.Env == "" {
+		config.Env = EnvDevelopment
+	}
+	if config.Env != EnvDevelopment && config.Env != EnvProduction {
+		log.Fatalf("エラー: 不明な実行環境です: %s", config.Env)
+	}
+
+	// ログの設定（本番環境では Cloud Logging 向けのJSON形式を既定にする）
+	if _, err := utils.ParseLevel(config.LogLevel); err != nil {
+		log.Fatalf("エラー: %v", err)
+	}
+	config.LogFormat = strings.ToLower(config.LogFormat)
+	if config.LogFormat == "" {
+		config.LogFormat = utils.FormatText
+		if config.Env == EnvProduction {
+			config.LogFormat = utils.FormatJSON
+		}
+	}
+	if config.LogFormat != utils.FormatText && config.LogFormat != utils.FormatJSON {
+		log.Fatalf("エラー: logFormat には text または json を指定してください: %s", config.LogFormat)
+	}
+	if config.LogMaxSize < 0 {
+		config.LogMaxSize = 0
+	}
+	if config.LogMaxAge < 0 {
+		config.LogMaxAge = 0
+	}
+	if config.LogMaxBackups < 0 {
+		config.LogMaxBackups = 0
+	}
+
+	// アクセスログの形式（未設定の場合、JSON形式のログではJSON、それ以外は Combined Log Format）
+	config.AccessLogFormat = strings.ToLower(config.AccessLogFormat)
+	if config.AccessLogFormat == "" {
+		config.AccessLogFormat = AccessLogCombined
+		if config.LogFormat == utils.FormatJSON {
+			config.AccessLogFormat = AccessLogJSON
+		}
+	}
+	switch config.AccessLogFormat {
+	case AccessLogCombined, AccessLogJSON, AccessLogOff:
+	default:
+		log.Fatalf("エラー: accessLogFormat には combined / json / off のいずれかを指定してください: %s", config.AccessLogFormat)
+	}
+
+	// テンプレートの自動再読み込み
+	config.TemplateReload = strings.ToLower(config.TemplateReload)
+	if config.TemplateReload != "" && config.TemplateReload != "true" && config.TemplateReload != "false" {
+		log.Fatalf("エラー: templateReload には true または false を指定してください: %s", config.TemplateReload)
+	}
+
+	// セッションの有効期間
+	if config.SessionAbsoluteTimeout <= 0 {
+		config.SessionAbsoluteTimeout = 30 * 24 * time.Hour
+	}
+	if config.SessionIdleTimeout <= 0 || config.SessionIdleTimeout > config.SessionAbsoluteTimeout {
+		config.SessionIdleTimeout = config.SessionAbsoluteTimeout
+	}
+
+	// クッキーの設定
+	config.CookieSecure = strings.ToLower(config.CookieSecure)
+	if config.CookieSecure != "" && config.CookieSecure != "true" && config.CookieSecure != "false" {
+		log.Fatalf("エラー: cookieSecure には true または false を指定してください: %s", config.CookieSecure)
+	}
+	config.CookieSameSite = strings.ToLower(config.CookieSameSite)
+	if config.CookieSameSite == "" {
+		config.CookieSameSite = "lax"
+	}
+	if config.CookieSameSite == "none" && !config.UseSecureCookie() {
+		log.Fatal("エラー: cookieSameSite=none は Secure なクッキーでのみ使用できます")
+	}
+
 	// ファイルの存在確認（空でない場合のみ）
 	if config.ServiceKeyPath != "" {
 		if _, err := os.Stat(config.ServiceKeyPath); os.IsNotExist(err) {
@@ -160,6 +594,78 @@ func validateConfig(config *ConfigList) {
 		}
 	}
 
+	// HTTPサーバーのタイムアウト
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = 15 * time.Second
+	}
+	if config.ReadHeaderTimeout <= 0 {
+		config.ReadHeaderTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 30 * time.Second
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 120 * time.Second
+	}
+	if config.MaxHeaderBytes <= 0 {
+		config.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = 10 * time.Second
+	}
+	if config.TrustedProxies < 0 {
+		config.TrustedProxies = 0
+	}
+
+	// Webhook
+	config.WebhookAllowPrivate = strings.ToLower(config.WebhookAllowPrivate)
+	if config.WebhookAllowPrivate != "" && config.WebhookAllowPrivate != "true" && config.WebhookAllowPrivate != "false" {
+		log.Fatalf("エラー: webhook の allowPrivate には true または false を指定してください: %s", config.WebhookAllowPrivate)
+	}
+	if config.WebhookTimeout <= 0 {
+		config.WebhookTimeout = 10 * time.Second
+	}
+	if config.WebhookMaxAttempts <= 0 {
+		config.WebhookMaxAttempts = 5
+	}
+	if config.WebhookRetryBackoff <= 0 {
+		config.WebhookRetryBackoff = 5 * time.Second
+	}
+	if config.IncomingRateLimit <= 0 {
+		config.IncomingRateLimit = 30
+	}
+
+	// アカウントの削除
+	if config.AccountDeletionGracePeriod < 0 {
+		log.Fatalf("エラー: account の deletionGracePeriod には0以上の期間を指定してください: %s", config.AccountDeletionGracePeriod)
+	}
+	if config.AccountDeletionGracePeriod == 0 {
+		config.AccountDeletionGracePeriod = 7 * 24 * time.Hour
+	}
+	if config.AccountDeletionInterval <= 0 {
+		config.AccountDeletionInterval = time.Hour
+	}
+	if config.DataExportExpiry <= 0 {
+		config.DataExportExpiry = 48 * time.Hour
+	}
+
+	// セキュリティヘッダー
+	if config.FrameOptions == "" {
+		config.FrameOptions = "DENY"
+	}
+	if config.ReferrerPolicy == "" {
+		config.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+	if config.HSTSMaxAge < 0 {
+		config.HSTSMaxAge = 0
+	}
+
+	if config.MasterKeyFile != "" {
+		if _, err := os.Stat(config.MasterKeyFile); os.IsNotExist(err) {
+			log.Fatalf("エラー: masterKeyFileファイルが見つかりません: %s", config.MasterKeyFile)
+		}
+	}
+
 	// Firebase設定の必須項目チェック
 	if config.ProjectId == "" {
 		log.Fatal("エラー: PROJECT_ID または projectId が設定されていません")
@@ -168,3 +674,77 @@ func validateConfig(config *ConfigList) {
 		log.Fatal("エラー: STORAGE_BUCKET または storageBucket が設定されていません")
 	}
 }
+
+// 期間の文字列を解析する（例: 720h, 30m）
+func parseDuration(name string, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("エラー: %s の形式が不正です: %s", name, value)
+	}
+	return d
+}
+
+// 整数の文字列を解析する
+func parseInt(name string, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("エラー: %s の形式が不正です: %s", name, value)
+	}
+	return n
+}
+
+// IsProduction 本番環境かどうか
+func (c ConfigList) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// UseSecureCookie クッキーにSecure属性を付けるかどうか（本番環境では既定で有効）
+func (c ConfigList) UseSecureCookie() bool {
+	if c.CookieSecure != "" {
+		return c.CookieSecure == "true"
+	}
+	return c.IsProduction()
+}
+
+// ReloadTemplates テンプレートの変更を監視して読み込み直すかどうか（開発環境では既定で有効）
+// 埋め込んだファイルは変更されないため、embedAssets が有効な場合は常に無効
+func (c ConfigList) ReloadTemplates() bool {
+	if c.EmbedAssets {
+		return false
+	}
+	if c.TemplateReload != "" {
+		return c.TemplateReload == "true"
+	}
+	return !c.IsProduction()
+}
+
+// AllowPrivateWebhooks Webhookをループバック・プライベートアドレスに送信できるかどうか（開発環境では既定で有効）
+func (c ConfigList) AllowPrivateWebhooks() bool {
+	if c.WebhookAllowPrivate != "" {
+		return c.WebhookAllowPrivate == "true"
+	}
+	return !c.IsProduction()
+}
+
+// AdminEmailList 管理者のメールアドレスの一覧（小文字にし、空の要素は除く）
+func (c ConfigList) AdminEmailList() []string {
+	var emails []string
+	for _, email := range strings.Split(c.AdminEmails, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// CookieSameSiteMode クッキーのSameSite属性
+func (c ConfigList) CookieSameSiteMode() http.SameSite {
+	switch c.CookieSameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
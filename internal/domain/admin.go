@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// サービス全体の集計（管理画面に表示する）
+type Stats struct {
+	Users    int          // ユーザー数（ボットを含む）
+	Chats    int          // チャット数
+	Messages int          // メッセージ数
+	Daily    []DailyStats // 日ごとの件数（新しい順）
+}
+
+// 1日ごとの件数
+type DailyStats struct {
+	Date     time.Time // 集計した日（0時）
+	Users    int       // 登録したユーザー数
+	Chats    int       // 開始したチャット数
+	Messages int       // 送信したメッセージ数
+}
+
+// ストレージの使用量（ディレクトリごと）
+type StorageUsage struct {
+	Prefix  string // ディレクトリ（例: icons、icons/default）
+	Objects int    // ファイル数
+	Bytes   int64  // 合計サイズ（バイト）
+}
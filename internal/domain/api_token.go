@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 個人用アクセストークンの接頭辞（ログやリポジトリに紛れ込んだ場合に見つけやすくする）
+const APITokenPrefix = "sca_"
+
+// APIトークンで許可する操作の範囲
+const (
+	ScopeReadMessages  = "messages:read" // チャット・メッセージ・ユーザーの閲覧
+	ScopeSendMessages  = "messages:send" // チャットの開始・メッセージの送信
+	ScopeManageProfile = "profile:write" // プロフィール（ユーザー名・アイコン）の変更
+)
+
+// APITokenScope スコープと画面での表示名
+type APITokenScope struct {
+	Name  string // スコープ
+	Label string // 表示名
+}
+
+// APITokenScopes 発行できるスコープの一覧
+var APITokenScopes = []APITokenScope{
+	{Name: ScopeReadMessages, Label: "メッセージの閲覧"},
+	{Name: ScopeSendMessages, Label: "メッセージの送信"},
+	{Name: ScopeManageProfile, Label: "プロフィールの管理"},
+}
+
+// 個人用アクセストークンの構造体
+// トークンそのものは発行時に一度だけ表示し、保存するのはハッシュのみ
+type APIToken struct {
+	ID         string    // トークンのID
+	UserID     string    // 所有者のID
+	Name       string    // トークンの名前（用途の識別用）
+	Scopes     []string  // 許可する操作の範囲
+	TokenHash  string    // トークンのSHA-256ハッシュ
+	Hint       string    // 一覧で識別するためのトークンの先頭部分
+	CreatedAt  time.Time // 発行日時
+	ExpiresAt  time.Time // 有効期限（ゼロ値の場合は無期限）
+	LastUsedAt time.Time // 最終使用日時
+}
+
+// HashAPIToken トークンを保存・照合用のハッシュにする
+// トークンは十分な長さの乱数のため、bcrypt ではなく高速なハッシュで照合する
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIToken 文字列が個人用アクセストークンの形式かどうか
+func IsAPIToken(value string) bool {
+	return strings.HasPrefix(value, APITokenPrefix)
+}
+
+// IsValidAPITokenScope 発行できるスコープかどうか
+func IsValidAPITokenScope(scope string) bool {
+	return slices.ContainsFunc(APITokenScopes, func(s APITokenScope) bool { return s.Name == scope })
+}
+
+// IsExpired トークンの有効期限が切れているかどうか
+func (t *APIToken) IsExpired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// HasScope トークンが操作を許可されているかどうか
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
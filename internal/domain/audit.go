@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// 監査ログの操作の種類
+const (
+	AuditLoginSucceeded        = "login.succeeded"            // ログインの成功
+	AuditLoginFailed           = "login.failed"               // ログインの失敗
+	AuditLogout                = "logout"                     // ログアウト
+	AuditPasswordChanged       = "password.changed"           // 設定ページでのパスワードの変更
+	AuditPasswordReset         = "password.reset"             // パスワードの再設定（ログイン前・管理者による設定）
+	AuditPasswordResetRequired = "password.reset_required"    // 管理者によるパスワードの再設定の要求
+	AuditUsernameChanged       = "username.changed"           // ユーザー名の変更
+	AuditIconChanged           = "icon.changed"               // アイコンの変更
+	AuditSessionsRevoked       = "sessions.revoked"           // セッションの削除（強制ログアウト、管理画面からの場合はAPIトークンの失効を含む）
+	AuditUserCreated           = "user.created"               // 管理者によるユーザーの作成
+	AuditUserDeleted           = "user.deleted"               // ユーザーの削除
+	AuditDeletionScheduled     = "account.deletion_scheduled" // アカウントの削除の予約
+	AuditDeletionCanceled      = "account.deletion_canceled"  // アカウントの削除の取り消し
+	AuditExportRequested       = "export.requested"           // データのエクスポートの申請
+	AuditExportDownloaded      = "export.downloaded"          // エクスポートしたデータのダウンロード
+	AuditUserSuspended         = "user.suspended"             // アカウントの停止
+	AuditUserUnsuspended       = "user.unsuspended"           // アカウントの停止の解除
+	AuditRoleChanged           = "user.role_changed"          // 権限の変更
+	AuditReportModerated       = "report.moderated"           // 通報への対応
+	AuditChatMemberAdded       = "chat.member_added"          // チャットへのメンバーの参加（チャットの開始・ボットの追加）
+	AuditChatMemberRemoved     = "chat.member_removed"        // チャットからのメンバーの削除（ボットを外す）
+)
+
+// 監査ログの操作の種類と表示名
+type AuditAction struct {
+	Name  string // 操作の識別子
+	Label string // 画面に表示する名前
+}
+
+// AuditActions 監査ログに記録する操作の一覧（管理画面の絞り込みの選択肢）
+var AuditActions = []AuditAction{
+	{Name: AuditLoginSucceeded, Label: "ログイン"},
+	{Name: AuditLoginFailed, Label: "ログインの失敗"},
+	{Name: AuditLogout, Label: "ログアウト"},
+	{Name: AuditPasswordChanged, Label: "パスワードの変更"},
+	{Name: AuditPasswordReset, Label: "パスワードの再設定"},
+	{Name: AuditPasswordResetRequired, Label: "パスワードの再設定の要求"},
+	{Name: AuditUsernameChanged, Label: "ユーザー名の変更"},
+	{Name: AuditIconChanged, Label: "アイコンの変更"},
+	{Name: AuditSessionsRevoked, Label: "強制ログアウト"},
+	{Name: AuditUserCreated, Label: "ユーザーの作成"},
+	{Name: AuditUserDeleted, Label: "ユーザーの削除"},
+	{Name: AuditDeletionScheduled, Label: "アカウントの削除の予約"},
+	{Name: AuditDeletionCanceled, Label: "アカウントの削除の取り消し"},
+	{Name: AuditExportRequested, Label: "データのエクスポートの申請"},
+	{Name: AuditExportDownloaded, Label: "エクスポートしたデータのダウンロード"},
+	{Name: AuditUserSuspended, Label: "アカウントの停止"},
+	{Name: AuditUserUnsuspended, Label: "アカウントの停止の解除"},
+	{Name: AuditRoleChanged, Label: "権限の変更"},
+	{Name: AuditReportModerated, Label: "通報への対応"},
+	{Name: AuditChatMemberAdded, Label: "チャットへの参加"},
+	{Name: AuditChatMemberRemoved, Label: "チャットからの削除"},
+}
+
+// AuditActionLabel 監査ログの操作の表示名（不明な場合は識別子をそのまま返す）
+func AuditActionLabel(name string) string {
+	for _, action := range AuditActions {
+		if action.Name == name {
+			return action.Label
+		}
+	}
+	return name
+}
+
+// 監査ログの記録（追記のみで、変更・削除はしない）
+type AuditEvent struct {
+	ID        string            // 記録のID
+	Action    string            // 操作の種類
+	ActorID   string            // 操作したユーザーのID（ログインの失敗・管理用コマンドなど、ユーザーでない場合は空）
+	TargetID  string            // 操作の対象のユーザーのID（対象が無い場合は空）
+	IP        string            // 接続元のIPアドレス（管理用コマンドの場合は空）
+	Metadata  map[string]string // 操作ごとの詳細（変更前後の値、チャットのIDなど）
+	CreatedAt time.Time         // 操作日時
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ブロックの構造体（ブロックしたユーザーとされたユーザーにつき1件）
+// ブロックされたユーザーは、チャットの開始・メッセージの送信・検索・オンライン状態の閲覧ができなくなる
+type Block struct {
+	ID        string    // ブロックのID（ブロックしたユーザーとされたユーザーのIDから決まる）
+	BlockerID string    // ブロックしたユーザーのID
+	BlockedID string    // ブロックされたユーザーのID
+	CreatedAt time.Time // ブロックした日時
+}
+
+// BlockID ブロックのID（同じ相手へのブロックが重複しないよう、2人のIDから決める）
+func BlockID(blockerID, blockedID string) string {
+	return blockerID + "_" + blockedID
+}
@@ -9,12 +9,16 @@ type MessageType string
 
 // チャットの構造体
 type Chat struct {
-	ID        string    // チャットのID
-	IsGroup   bool      // グループチャットかどうか
-	Messages  []Message // メッセージのリスト
-	CreatedAt time.Time // チャットの作成日時
-	UpdatedAt time.Time // チャットの更新日時
-	Contact   Contact   // チャットの相手
+	ID          string    // チャットのID
+	IsGroup     bool      // グループチャットかどうか
+	IsEncrypted bool      // エンドツーエンド暗号化が有効かどうか
+	Messages    []Message // メッセージのリスト
+	CreatedAt   time.Time // チャットの作成日時
+	UpdatedAt   time.Time // チャットの更新日時
+	Contact     Contact   // チャットの相手
+	Bots        []Contact // チャットに追加されたボット
+	IsMuted     bool      // ログイン中のユーザーがミュートしているかどうか
+	UnreadCount int       // 相手からの未読メッセージの数（ミュート中は0）
 }
 
 // チャット参加者の構造体
@@ -28,16 +32,21 @@ type ChatParticipant struct {
 
 // メッセージの構造体
 type Message struct {
-	ID         string      // メッセージのID
-	ChatID     string      // チャットのID
-	SenderID   string      // 送信者のID
-	SenderName string      // 送信者の名前
-	Content    string      // メッセージの内容
-	MediaURL   string      // メッセージのメディアのURL
-	CreatedAt  time.Time   // メッセージの作成日時
-	IsRead     bool        // メッセージが読まれたかどうか
-	ReadBy     []string    // メッセージを読んだユーザーのID
-	ReplyTo    string      // メッセージの返信先のID
+	ID            string       // メッセージのID
+	ChatID        string       // チャットのID
+	SenderID      string       // 送信者のID
+	SenderName    string       // 送信者の名前
+	IsBot         bool         // 送信者がボットかどうか
+	IsIntegration bool         // 受信用Webhookから投稿されたメッセージかどうか
+	Content       string       // メッセージの内容（暗号化されている場合は暗号文）
+	IsEncrypted   bool         // エンドツーエンド暗号化されているかどうか
+	MediaURL      string       // メッセージのメディアのURL
+	Attachments   []Attachment // メッセージの添付（リンク）
+	CreatedAt     time.Time    // メッセージの作成日時
+	EditedAt      time.Time    // メッセージの編集日時（編集されていない場合はゼロ値）
+	IsRead        bool         // メッセージが読まれたかどうか
+	ReadBy        []string     // メッセージを読んだユーザーのID
+	ReplyTo       string       // メッセージの返信先のID
 }
 
 // ビジネスロジックの為のチャットのユースケース
@@ -52,6 +61,7 @@ type ChatRepository interface {
 	AddChat(user, message string) error
 	GetChats(userID string) ([]Chat, error)
 	GetMessages(chatID string) ([]Message, error)
+	GetContacts(userID string) ([]Contact, error)
 }
 
 // チャットのコントローラー
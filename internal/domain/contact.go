@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// 連絡先の申請の構造体
+// 承認・拒否・取り消しのいずれかで削除する
+type ContactRequest struct {
+	ID        string    // 申請のID（申請者と相手のIDから決まる）
+	FromID    string    // 申請したユーザーのID
+	ToID      string    // 申請されたユーザーのID
+	CreatedAt time.Time // 申請日時
+}
+
+// 連絡先の関係の構造体（2人のユーザーにつき1件）
+type ContactLink struct {
+	ID        string    // 関係のID（2人のユーザーのIDから決まる）
+	UserIDs   []string  // 連絡先になった2人のユーザーのID
+	CreatedAt time.Time // 連絡先になった日時
+}
+
+// ContactRelation ユーザーとの関係
+type ContactRelation string
+
+const (
+	RelationNone            ContactRelation = ""                 // 関係なし
+	RelationContact         ContactRelation = "contact"          // 連絡先
+	RelationRequestSent     ContactRelation = "request_sent"     // 自分が申請中
+	RelationRequestReceived ContactRelation = "request_received" // 相手から申請が届いている
+)
+
+// ContactRequestID 申請のID（同じ相手への申請が重複しないよう、申請者と相手のIDから決める）
+func ContactRequestID(fromID, toID string) string {
+	return fromID + "_" + toID
+}
+
+// ContactLinkID 連絡先の関係のID（どちらのユーザーから見ても同じになるよう、IDを並べ替えて決める）
+func ContactLinkID(userID, otherID string) string {
+	ids := []string{userID, otherID}
+	sort.Strings(ids)
+	return strings.Join(ids, "_")
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// エンドツーエンド暗号化されたメッセージ本文の接頭辞
+const EncryptedContentPrefix = "e2e.v1."
+
+// 端末の公開鍵の構造体（エンドツーエンド暗号化用）
+type DeviceKey struct {
+	ID          string    // 端末のID
+	UserID      string    // ユーザーのID
+	DeviceName  string    // 端末の名前
+	PublicKey   string    // 公開鍵（ECDH P-256 の SPKI を Base64 エンコードしたもの）
+	Fingerprint string    // 公開鍵のフィンガープリント
+	CreatedAt   time.Time // 登録日時
+}
+
+// 端末の公開鍵で暗号化されたチャット鍵の構造体
+type WrappedChatKey struct {
+	DeviceID           string    // 復号できる端末のID
+	UserID             string    // 端末の所有者のID
+	EphemeralPublicKey string    // 鍵交換に使用した一時公開鍵（SPKI, Base64）
+	IV                 string    // 暗号化に使用したIV（Base64）
+	WrappedKey         string    // 暗号化されたチャット鍵（Base64）
+	CreatedAt          time.Time // 作成日時
+}
+
+// メッセージ本文がエンドツーエンド暗号化された形式かどうか
+func IsEncryptedContent(content string) bool {
+	parts := strings.Split(strings.TrimPrefix(content, EncryptedContentPrefix), ".")
+	if !strings.HasPrefix(content, EncryptedContentPrefix) || len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := base64.StdEncoding.DecodeString(part); err != nil || part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// 端末の公開鍵を検証し、フィンガープリントを返す
+func ParseDevicePublicKey(publicKey string) (string, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("公開鍵の形式が不正です: %v", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return "", fmt.Errorf("公開鍵の解析に失敗しました: %v", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return "", fmt.Errorf("P-256 の公開鍵のみ登録できます")
+	}
+	return KeyFingerprint(der), nil
+}
+
+// 公開鍵のフィンガープリント（SHA-256 を4桁ずつ区切った16進数）
+func KeyFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	encoded := strings.ToUpper(hex.EncodeToString(sum[:]))
+	var groups []string
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// 2人のユーザーの端末の公開鍵から安全番号を算出する
+// どちらのユーザーから見ても同じ値になり、両者が一致を確認することで鍵のすり替えを検出できる
+func SafetyNumber(userA string, keysA []DeviceKey, userB string, keysB []DeviceKey) string {
+	parts := []string{identityDigest(userA, keysA), identityDigest(userB, keysB)}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "")))
+
+	// 5桁 × 12 グループの数字に変換する
+	var groups []string
+	for i := 0; i < 12; i++ {
+		chunk := uint64(sum[i*2])<<16 | uint64(sum[i*2+1])<<8 | uint64(sum[24+i%8])
+		groups = append(groups, fmt.Sprintf("%05d", chunk%100000))
+	}
+	return strings.Join(groups, " ")
+}
+
+// ユーザーと端末の公開鍵の組を表すダイジェスト
+func identityDigest(userID string, keys []DeviceKey) string {
+	var fingerprints []string
+	for _, key := range keys {
+		fingerprints = append(fingerprints, key.Fingerprint)
+	}
+	sort.Strings(fingerprints)
+	sum := sha256.Sum256([]byte(userID + "|" + strings.Join(fingerprints, "|")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind アプリケーションエラーの種類
+type ErrorKind int
+
+const (
+	ErrorKindInternal         ErrorKind = iota // サーバー内部のエラー
+	ErrorKindNotFound                          // 対象が存在しない
+	ErrorKindForbidden                         // 操作が許可されていない
+	ErrorKindUnauthorized                      // 認証されていない
+	ErrorKindValidation                        // 入力値が不正
+	ErrorKindMethodNotAllowed                  // HTTPメソッドが許可されていない
+	ErrorKindConflict                          // 既存のデータと競合する
+	ErrorKindTooManyRequests                   // 短時間にリクエストが多すぎる
+)
+
+// AppError アプリケーションエラー
+type AppError struct {
+	Kind    ErrorKind // エラーの種類
+	Message string    // 利用者に表示するメッセージ
+	Err     error     // 原因となったエラー（ログにのみ出力する）
+}
+
+// Error エラーメッセージを返す
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap 原因となったエラーを返す
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// NewNotFoundError 対象が存在しないエラーを作成する
+func NewNotFoundError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindNotFound, Message: message, Err: err}
+}
+
+// NewForbiddenError 操作が許可されていないエラーを作成する
+func NewForbiddenError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindForbidden, Message: message, Err: err}
+}
+
+// NewUnauthorizedError 認証されていないエラーを作成する
+func NewUnauthorizedError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindUnauthorized, Message: message, Err: err}
+}
+
+// NewValidationError 入力値が不正なエラーを作成する
+func NewValidationError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindValidation, Message: message, Err: err}
+}
+
+// NewMethodNotAllowedError HTTPメソッドが許可されていないエラーを作成する
+func NewMethodNotAllowedError() *AppError {
+	return &AppError{Kind: ErrorKindMethodNotAllowed, Message: "メソッドが許可されていません"}
+}
+
+// NewConflictError 既存のデータと競合するエラーを作成する
+func NewConflictError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindConflict, Message: message, Err: err}
+}
+
+// NewTooManyRequestsError 短時間にリクエストが多すぎるエラーを作成する
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{Kind: ErrorKindTooManyRequests, Message: message}
+}
+
+// NewInternalError サーバー内部のエラーを作成する
+func NewInternalError(message string, err error) *AppError {
+	return &AppError{Kind: ErrorKindInternal, Message: message, Err: err}
+}
+
+// AsAppError エラーをアプリケーションエラーに変換する（該当しない場合は内部エラーとして扱う）
+func AsAppError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return NewInternalError("サーバーでエラーが発生しました", err)
+}
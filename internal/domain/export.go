@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// データのエクスポートの状態
+const (
+	ExportPending = "pending" // 作成中
+	ExportReady   = "ready"   // ダウンロードできる
+	ExportFailed  = "failed"  // 作成に失敗した
+)
+
+// ユーザーのデータのエクスポート（ZIPファイルは Storage に非公開で保存する）
+type DataExport struct {
+	ID          string    // エクスポートのID
+	UserID      string    // エクスポートしたユーザーのID
+	Status      string    // 状態（ExportPending / ExportReady / ExportFailed）
+	ObjectPath  string    // Storage に保存したZIPファイルのパス（作成前・失敗時は空）
+	Size        int64     // ZIPファイルのサイズ（バイト）
+	Error       string    // 作成に失敗した理由
+	CreatedAt   time.Time // 申請日時
+	CompletedAt time.Time // 作成が終わった日時（作成中はゼロ値）
+	ExpiresAt   time.Time // ダウンロードの期限（過ぎるとZIPファイルごと削除する）
+}
+
+// IsDownloadable ダウンロードできるかどうか（作成済みで期限内）
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e != nil && e.Status == ExportReady && now.Before(e.ExpiresAt)
+}
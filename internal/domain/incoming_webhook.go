@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// 受信用WebhookのURLに含めるトークンの接頭辞
+const IncomingWebhookPrefix = "ihk_"
+
+// 受信用Webhookから投稿されたメッセージの送信者の種類
+const SenderTypeIntegration = "integration"
+
+// 受信用Webhookの構造体
+// URLに含めるトークンは作成時に一度だけ表示し、保存するのはハッシュのみ
+type IncomingWebhook struct {
+	ID         string    // 受信用WebhookのID
+	ChatID     string    // 投稿先のチャットのID
+	CreatorID  string    // 作成したユーザーのID
+	Name       string    // 投稿したメッセージに表示する送信者の名前
+	TokenHash  string    // トークンのSHA-256ハッシュ
+	Hint       string    // 一覧で識別するためのトークンの先頭部分
+	CreatedAt  time.Time // 作成日時
+	LastUsedAt time.Time // 最後に投稿された日時
+}
+
+// メッセージの添付（リンク）
+type Attachment struct {
+	Title string // 表示名（空の場合はURLを表示する）
+	URL   string // リンク先のURL
+}
+
+// IsIncomingWebhookToken 文字列が受信用Webhookのトークンの形式かどうか
+func IsIncomingWebhookToken(value string) bool {
+	return strings.HasPrefix(value, IncomingWebhookPrefix)
+}
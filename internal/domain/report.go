@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// 通報の対象の種類
+const (
+	ReportTargetMessage = "message" // メッセージ
+	ReportTargetUser    = "user"    // ユーザー（プロフィール）
+)
+
+// 通報の状態
+const (
+	ReportStatusOpen      = "open"      // 未対応
+	ReportStatusReviewing = "reviewing" // 確認中
+	ReportStatusResolved  = "resolved"  // 対応済み（メッセージの削除・アカウントの停止）
+	ReportStatusDismissed = "dismissed" // 却下（問題なし）
+)
+
+// ReportStatusLabel 通報の状態の表示名
+func ReportStatusLabel(status string) string {
+	switch status {
+	case ReportStatusOpen:
+		return "未対応"
+	case ReportStatusReviewing:
+		return "確認中"
+	case ReportStatusResolved:
+		return "対応済み"
+	case ReportStatusDismissed:
+		return "却下"
+	}
+	return status
+}
+
+// 通報の理由
+type ReportReason struct {
+	Name  string // 理由の識別子
+	Label string // 画面に表示する名前
+}
+
+// 通報の理由の識別子
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonImpersonation = "impersonation"
+	ReportReasonOther         = "other"
+)
+
+// ReportReasons 選択できる通報の理由の一覧
+var ReportReasons = []ReportReason{
+	{Name: ReportReasonSpam, Label: "スパム・宣伝"},
+	{Name: ReportReasonHarassment, Label: "嫌がらせ・脅迫"},
+	{Name: ReportReasonInappropriate, Label: "不適切な内容"},
+	{Name: ReportReasonImpersonation, Label: "なりすまし"},
+	{Name: ReportReasonOther, Label: "その他"},
+}
+
+// IsReportReason 選択できる通報の理由かどうか
+func IsReportReason(name string) bool {
+	return slices.ContainsFunc(ReportReasons, func(r ReportReason) bool { return r.Name == name })
+}
+
+// ReportReasonLabel 通報の理由の表示名（不明な場合は識別子をそのまま返す）
+func ReportReasonLabel(name string) string {
+	for _, reason := range ReportReasons {
+		if reason.Name == name {
+			return reason.Label
+		}
+	}
+	return name
+}
+
+// 通報の構造体
+// メッセージの通報では、前後のメッセージを含めた通報時点の内容を別に保存する
+type Report struct {
+	ID           string    // 通報のID
+	ReporterID   string    // 通報したユーザーのID
+	TargetType   string    // 通報の対象の種類（ReportTargetMessage / ReportTargetUser）
+	TargetUserID string    // 通報されたユーザーのID（メッセージの場合は送信者）
+	ChatID       string    // 通報されたメッセージのチャットのID（メッセージの場合のみ）
+	MessageID    string    // 通報されたメッセージのID（メッセージの場合のみ）
+	Reason       string    // 通報の理由
+	Details      string    // 通報したユーザーが入力した詳細
+	Status       string    // 通報の状態
+	CreatedAt    time.Time // 通報日時
+	UpdatedAt    time.Time // 最後に対応した日時
+}
+
+// IsOpen 対応が終わっていない通報かどうか
+func (r Report) IsOpen() bool {
+	return r.Status == ReportStatusOpen || r.Status == ReportStatusReviewing
+}
+
+// モデレーションの操作
+const (
+	ModerationReview        = "review"         // 確認中にする
+	ModerationDismiss       = "dismiss"        // 問題なしとして却下する
+	ModerationDeleteMessage = "delete_message" // 通報されたメッセージを削除する
+	ModerationSuspend       = "suspend"        // 通報されたユーザーのアカウントを停止する
+)
+
+// ModerationActionLabel モデレーションの操作の表示名
+func ModerationActionLabel(action string) string {
+	switch action {
+	case ModerationReview:
+		return "確認中にする"
+	case ModerationDismiss:
+		return "却下"
+	case ModerationDeleteMessage:
+		return "メッセージを削除"
+	case ModerationSuspend:
+		return "アカウントを停止"
+	}
+	return action
+}
+
+// モデレーションの操作の記録（変更・削除はしない）
+type ModerationAction struct {
+	ID           string    // 記録のID
+	ReportID     string    // 対応した通報のID
+	ModeratorID  string    // 操作した管理者のID
+	Action       string    // 操作の種類
+	TargetUserID string    // 対象のユーザーのID
+	ChatID       string    // 対象のメッセージのチャットのID（メッセージの場合のみ）
+	MessageID    string    // 対象のメッセージのID（メッセージの場合のみ）
+	Note         string    // 管理者のメモ
+	CreatedAt    time.Time // 操作日時
+}
@@ -1,38 +1,73 @@
 package domain
 
 import (
-	"log"
+	"log/slog"
 	"time"
 )
 
 // セッション情報を管理する構造体
 type Session struct {
-	ID        string    // セッションのID
-	User      *User     // ユーザー
-	Token     string    // セッションのトークン
-	CreatedAt time.Time // セッションの作成日時
-	UpdatedAt time.Time // セッションの更新日時
-	ExpiredAt time.Time // セッションの有効期限
-	IsValid   bool      // セッションが有効かどうか
+	ID                string    // セッションのID
+	UserID            string    // ユーザーのID
+	CredentialVersion int       // セッション作成時の認証情報のバージョン
+	User              *User     `firestore:"-"` // ユーザー（リクエスト毎に読み込み、保存しない）
+	Token             string    // セッションのトークン
+	CreatedAt         time.Time // セッションの作成日時
+	UpdatedAt         time.Time // セッションの更新日時
+	ExpiredAt         time.Time // セッションの有効期限（ログインからの絶対期限）
+	IdleExpiredAt     time.Time // 無操作による有効期限（操作のたびに延長される）
+	IsValid           bool      // セッションが有効かどうか
 }
 
 // CheckSession セッションの有効性をチェックする
 func (s *Session) CheckSession() bool {
 	if s == nil {
-		log.Printf("セッションがnilです")
+		slog.Debug("セッションがnilです")
 		return false
 	}
 
 	// セッションの有効期限をチェック
 	if time.Now().After(s.ExpiredAt) {
-		log.Printf("セッションの有効期限が切れています: sessionID=%s, expiredAt=%v", s.ID, s.ExpiredAt)
+		slog.Debug("セッションの有効期限が切れています", "user_id", s.UserID, "expired_at", s.ExpiredAt)
+		return false
+	}
+
+	// 無操作による有効期限をチェック
+	if time.Now().After(s.IdleExpiredAt) {
+		slog.Debug("セッションが無操作により失効しています", "user_id", s.UserID, "idle_expired_at", s.IdleExpiredAt)
 		return false
 	}
 
 	// セッションが無効に設定されている場合
 	if !s.IsValid {
-		log.Printf("セッションが無効に設定されています: sessionID=%s", s.ID)
+		slog.Debug("セッションが無効に設定されています", "user_id", s.UserID)
 		return false
 	}
 	return true
 }
+
+// CheckCredential セッションがユーザーの現在の認証情報に対して発行されたものかをチェックする
+func (s *Session) CheckCredential(user *User) bool {
+	if s == nil || user == nil {
+		return false
+	}
+	if s.UserID != user.ID {
+		slog.Info("セッションのユーザーが一致しません", "user_id", s.UserID)
+		return false
+	}
+	if s.CredentialVersion != user.CredentialVersion {
+		slog.Info("認証情報が変更されたためセッションは無効です", "user_id", s.UserID)
+		return false
+	}
+	return true
+}
+
+// Touch 操作があったものとして無操作による有効期限を延長する（絶対期限は超えない）
+func (s *Session) Touch(idleTimeout time.Duration) {
+	now := time.Now()
+	s.UpdatedAt = now
+	s.IdleExpiredAt = now.Add(idleTimeout)
+	if s.IdleExpiredAt.After(s.ExpiredAt) {
+		s.IdleExpiredAt = s.ExpiredAt
+	}
+}
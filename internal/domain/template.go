@@ -15,6 +15,7 @@ type TemplateData struct {
 	ValidationErrors []string   // バリデーションエラー
 	Error            string     // エラー
 	ChatID           string     // チャットID
+	OwnBots          []User     // チャットに追加できる自分のボット
 }
 
 // DefaultIcon デフォルトアイコンの情報
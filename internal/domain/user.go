@@ -1,18 +1,84 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // ユーザーの構造体
 type User struct {
-	ID            string    // ユーザーのID
-	Name          string    // ユーザーの名前
-	Email         string    // ユーザーのメールアドレス
-	Password      string    // ユーザーのパスワード
-	CreatedAt     time.Time // ユーザーの作成日時
-	UpdatedAt     time.Time // ユーザーの更新日時
-	IsOnline      bool      // ユーザーがオンラインかどうか
-	Icon          string    // ユーザーのアイコン
-	Contacts      []Contact // ユーザーの連絡先
+	ID                    string    // ユーザーのID
+	Name                  string    // ユーザーの名前
+	Email                 string    // ユーザーのメールアドレス
+	Password              string    // ユーザーのパスワード
+	CredentialVersion     int       // 認証情報のバージョン（パスワード・メールアドレス・権限の変更時に更新）
+	CreatedAt             time.Time // ユーザーの作成日時
+	UpdatedAt             time.Time // ユーザーの更新日時
+	IsOnline              bool      // ユーザーがオンラインかどうか
+	LastSeenAt            time.Time // ユーザーの最終接続日時（数分単位で更新する）
+	Icon                  string    // ユーザーのアイコン
+	Contacts              []Contact // ユーザーの連絡先
+	ContactsOnly          bool      // 連絡先のユーザーのみチャットを開始できるようにするかどうか
+	MutedChatIDs          []string  // ミュートしたチャットのID（通知・未読数を表示しない）
+	Role                  string    // ユーザーの権限（空の場合は通常のユーザー、RoleAdmin の場合は管理者）
+	Suspended             bool      // アカウントが停止されているかどうか（ログイン・APIの利用ができない）
+	SuspendedAt           time.Time // アカウントを停止した日時
+	PasswordResetRequired bool      // 管理者によりパスワードの再設定が求められているかどうか（再設定するまでログインできない）
+	DeletionScheduledAt   time.Time // アカウントの削除を予定している日時（予定が無い場合はゼロ値）
+	DeleteMessages        bool      // アカウントの削除時に送信したメッセージを削除するかどうか（false の場合は送信者を削除されたユーザーに置き換える）
+	Type                  string    // ユーザーの種類（空の場合は通常のユーザー、UserTypeBot の場合はボット）
+	OwnerID               string    // ボットを作成したユーザーのID（ボットのみ）
+	WebhookURL            string    // 参加しているチャットのメッセージを送信するURL（ボットのみ、空の場合はロングポーリングで受信）
+	WebhookSecret         string    // Webhookの署名に使う秘密鍵（ボットのみ）
+}
+
+// ボットのユーザーの種類
+const UserTypeBot = "bot"
+
+// ユーザーの権限
+const (
+	RoleUser  = ""      // 通常のユーザー
+	RoleAdmin = "admin" // 管理者
+)
+
+// 削除されたユーザー（削除したユーザーの代わりにチャットの参加者・メッセージの送信者に設定する）
+const (
+	DeletedUserID   = "deleted-user"
+	DeletedUserName = "削除されたユーザー"
+)
+
+// NormalizeEmail メールアドレスを保存・検索する形式にする（前後の空白を除き、小文字にする）
+// 大文字・小文字の違いで同じメールアドレスのアカウントを重複して作成できないようにする
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsDeletionScheduled アカウントの削除が予定されているかどうか
+func (u *User) IsDeletionScheduled() bool {
+	return u != nil && !u.DeletionScheduledAt.IsZero()
+}
+
+// IsAdmin 権限が管理者のユーザーかどうか（ボットは管理者にならない）
+func (u *User) IsAdmin() bool {
+	return u != nil && !u.IsBot() && u.Role == RoleAdmin
+}
+
+// IsBot ボットのユーザーかどうか
+func (u *User) IsBot() bool {
+	return u != nil && u.Type == UserTypeBot
+}
+
+// IsChatMuted チャットをミュートしているかどうか
+func (u *User) IsChatMuted(chatID string) bool {
+	if u == nil {
+		return false
+	}
+	for _, id := range u.MutedChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
 }
 
 // 連絡先を交換したユーザーの構造体
@@ -22,4 +88,5 @@ type Contact struct {
 	Icon     string    // 連絡先のアイコンのURL
 	LastSeen time.Time // 連絡先の最終接続日時
 	IsOnline bool      // 連絡先がオンラインかどうか
+	IsBot    bool      // 連絡先がボットかどうか
 }
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Webhookで送信するチャットのイベント
+const (
+	EventMessageCreated = "message.created" // メッセージの送信
+	EventMessageEdited  = "message.edited"  // メッセージの編集
+	EventMemberJoined   = "member.joined"   // チャットへの参加（チャットの開始・ボットの追加）
+)
+
+// WebhookEvent イベントと画面での表示名
+type WebhookEvent struct {
+	Name  string // イベントの種類
+	Label string // 表示名
+}
+
+// WebhookEvents 登録できるイベントの一覧
+var WebhookEvents = []WebhookEvent{
+	{Name: EventMessageCreated, Label: "メッセージの送信"},
+	{Name: EventMessageEdited, Label: "メッセージの編集"},
+	{Name: EventMemberJoined, Label: "メンバーの参加"},
+}
+
+// WebhookFailureLimit 連続して配信に失敗した場合にWebhookを停止する回数
+const WebhookFailureLimit = 10
+
+// 送信用Webhookの構造体
+// ChatID が空の場合は、登録したユーザーが参加しているすべてのチャットのイベントを送信する
+type Webhook struct {
+	ID             string    // WebhookのID
+	OwnerID        string    // 登録したユーザーのID
+	ChatID         string    // 対象のチャットのID（空の場合はすべてのチャット）
+	URL            string    // 送信先のURL
+	Secret         string    // 署名の秘密鍵
+	Events         []string  // 送信するイベントの種類
+	Disabled       bool      // 停止されているかどうか
+	DisabledReason string    // 停止された理由
+	FailureCount   int       // 連続して配信に失敗した回数
+	LastStatus     int       // 最後の配信のステータスコード（接続できなかった場合は0）
+	LastError      string    // 最後の配信のエラー（成功した場合は空）
+	LastDeliveryAt time.Time // 最後の配信日時
+	CreatedAt      time.Time // 登録日時
+}
+
+// Webhookの配信履歴（配信の試行ごとに記録する）
+type WebhookDelivery struct {
+	ID         string        // 配信履歴のID
+	WebhookID  string        // WebhookのID
+	EventID    string        // イベントのID
+	Event      string        // イベントの種類
+	Attempt    int           // 試行回数（1から数える）
+	StatusCode int           // レスポンスのステータスコード（接続できなかった場合は0）
+	Error      string        // エラー（成功した場合は空）
+	Duration   time.Duration // 送信にかかった時間
+	CreatedAt  time.Time     // 送信日時
+}
+
+// IsValidWebhookEvent 登録できるイベントかどうか
+func IsValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e.Name == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribes イベントを送信する対象かどうか
+func (w *Webhook) Subscribes(chatID, event string) bool {
+	if w.Disabled || !slices.Contains(w.Events, event) {
+		return false
+	}
+	return w.ChatID == "" || w.ChatID == chatID
+}
+
+// Succeeded 配信履歴が成功した配信かどうか
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.Error == ""
+}
+
+// RecordDelivery 配信の結果を最後の状態と連続した失敗回数に反映する
+// 連続して WebhookFailureLimit 回失敗した場合はWebhookを停止し、この配信で停止した場合は true を返す
+func (w *Webhook) RecordDelivery(delivery *WebhookDelivery) (disabledNow bool) {
+	w.LastStatus = delivery.StatusCode
+	w.LastError = delivery.Error
+	w.LastDeliveryAt = delivery.CreatedAt
+	if delivery.Succeeded() {
+		w.FailureCount = 0
+		return false
+	}
+	w.FailureCount++
+	if w.Disabled || w.FailureCount < WebhookFailureLimit {
+		return false
+	}
+	w.Disabled = true
+	w.DisabledReason = fmt.Sprintf("%d回連続で配信に失敗しました", w.FailureCount)
+	return true
+}
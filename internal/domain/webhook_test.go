@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookRecordDeliveryDisablesAtFailureLimit(t *testing.T) {
+	hook := &Webhook{}
+	failed := &WebhookDelivery{StatusCode: 500, Error: "送信先が 500 を返しました", CreatedAt: time.Now()}
+
+	for i := 1; i < WebhookFailureLimit; i++ {
+		if hook.RecordDelivery(failed) {
+			t.Fatalf("disabled after %d failures, want %d", i, WebhookFailureLimit)
+		}
+	}
+	if hook.Disabled {
+		t.Fatalf("Disabled before reaching the limit")
+	}
+	if !hook.RecordDelivery(failed) {
+		t.Fatalf("not disabled after %d failures", WebhookFailureLimit)
+	}
+	if !hook.Disabled || hook.DisabledReason == "" || hook.FailureCount != WebhookFailureLimit {
+		t.Fatalf("hook = %+v, want disabled with a reason after %d failures", hook, WebhookFailureLimit)
+	}
+	if hook.LastStatus != 500 || hook.LastError != failed.Error {
+		t.Errorf("last status = %d %q, want the failed delivery", hook.LastStatus, hook.LastError)
+	}
+
+	// 停止済みのWebhookは停止し直さない
+	if hook.RecordDelivery(failed) {
+		t.Error("disabled again after it was already disabled")
+	}
+}
+
+func TestWebhookRecordDeliveryResetsFailuresOnSuccess(t *testing.T) {
+	hook := &Webhook{FailureCount: WebhookFailureLimit - 1}
+	if hook.RecordDelivery(&WebhookDelivery{StatusCode: 200}) {
+		t.Fatal("disabled on a successful delivery")
+	}
+	if hook.FailureCount != 0 || hook.Disabled {
+		t.Fatalf("hook = %+v, want the failure count reset", hook)
+	}
+
+	// 成功を挟んだ失敗は連続した失敗として数えない
+	if hook.RecordDelivery(&WebhookDelivery{StatusCode: 0, Error: "timeout"}) || hook.FailureCount != 1 {
+		t.Fatalf("FailureCount = %d after one failure, want 1", hook.FailureCount)
+	}
+}
@@ -0,0 +1,330 @@
+package firebase
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/utils/envelope"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 保存時暗号化されたメッセージであることを示すフィールド
+const encryptedAtRestField = "encrypted_at_rest"
+
+// 保存時暗号化の形式を示すフィールドと現在の形式（チャット・メッセージのID・フィールド名を関連データとして暗号化）
+// 形式が一致しないメッセージは、関連データなしで復号できてしまわないよう復号しない
+const (
+	encryptedAtRestVersionField = "encrypted_at_rest_version"
+	encryptedAtRestVersion      = 2
+)
+
+// 復号できなかったメッセージの表示内容
+const undecryptableContent = "（復号できないメッセージ）"
+
+// 保存時暗号化の対象となるメッセージのフィールド
+var encryptedMessageFields = []string{"content", "media_url", "attachments"}
+
+// チャットごとのデータ鍵（マスター鍵で暗号化して保存する）
+type dataKeyRecord struct {
+	ChatID      string    // チャットのID
+	MasterKeyID string    // 暗号化に使用したマスター鍵のID
+	WrappedKey  []byte    // 暗号化されたデータ鍵
+	CreatedAt   time.Time // 作成日時
+	RotatedAt   time.Time // 再暗号化日時
+}
+
+var (
+	// マスター鍵（InitEncryption で読み込む）
+	keyring *envelope.Keyring
+
+	// 復号済みデータ鍵のキャッシュ（再暗号化してもデータ鍵自体は変わらない）
+	dataKeyCache = struct {
+		sync.Mutex
+		items map[string][]byte
+	}{items: make(map[string][]byte)}
+)
+
+// 保存時暗号化のマスター鍵を読み込む
+// マスター鍵が設定されていない場合、メッセージは暗号化せずに保存する
+func InitEncryption() error {
+	k := envelope.NewKeyring()
+	if config.Config.MasterKeyFile != "" {
+		if err := k.LoadFile(config.Config.MasterKeyFile); err != nil {
+			return err
+		}
+	}
+	if config.Config.MasterKey != "" {
+		if err := k.Add("default", config.Config.MasterKey); err != nil {
+			return err
+		}
+	}
+	if config.Config.MasterKeyID != "" {
+		if err := k.SetActive(config.Config.MasterKeyID); err != nil {
+			return err
+		}
+	}
+
+	if !k.Enabled() {
+		slog.Warn("マスター鍵が設定されていないため、メッセージの保存時暗号化は無効です")
+		keyring = nil
+		return nil
+	}
+	slog.Info("メッセージの保存時暗号化を有効にしました", "master_key_id", k.ActiveID())
+	keyring = k
+	return nil
+}
+
+// チャットのデータ鍵を取得する（create が true の場合、存在しなければ作成する）
+func getDataKey(ctx context.Context, client *firestore.Client, chatID string, create bool) ([]byte, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("マスター鍵が設定されていません")
+	}
+
+	dataKeyCache.Lock()
+	key, ok := dataKeyCache.items[chatID]
+	dataKeyCache.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	ref := client.Collection("dataKeys").Doc(chatID)
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound && create {
+		if err := createDataKey(ctx, ref, chatID); err != nil && status.Code(err) != codes.AlreadyExists {
+			return nil, err
+		}
+		// 同時に作成された場合も含め、保存された鍵を読み直す
+		doc, err = ref.Get(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("データ鍵の取得に失敗: %v", err)
+	}
+
+	var record dataKeyRecord
+	if err := doc.DataTo(&record); err != nil {
+		return nil, fmt.Errorf("データ鍵の変換に失敗: %v", err)
+	}
+	key, err = keyring.Unwrap(record.MasterKeyID, record.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("データ鍵の復号に失敗: %v", err)
+	}
+
+	dataKeyCache.Lock()
+	dataKeyCache.items[chatID] = key
+	dataKeyCache.Unlock()
+	return key, nil
+}
+
+// 新しいデータ鍵を作成して保存する
+func createDataKey(ctx context.Context, ref *firestore.DocumentRef, chatID string) error {
+	dataKey, err := envelope.GenerateKey()
+	if err != nil {
+		return err
+	}
+	masterKeyID, wrapped, err := keyring.Wrap(dataKey)
+	if err != nil {
+		return err
+	}
+	_, err = ref.Create(ctx, dataKeyRecord{
+		ChatID:      chatID,
+		MasterKeyID: masterKeyID,
+		WrappedKey:  wrapped,
+		CreatedAt:   time.Now(),
+		RotatedAt:   time.Now(),
+	})
+	return err
+}
+
+// 暗号化するフィールドの関連データ
+// 暗号文を別のメッセージ・チャット・フィールドに移し替えても復号できないようにする
+func messageAdditionalData(chatID, messageID, field string) []byte {
+	return []byte(chatID + "/" + messageID + "/" + field)
+}
+
+// 保存するメッセージの対象フィールドを暗号化したコピーを返す
+func encryptMessage(ctx context.Context, client *firestore.Client, chatID string, messageID string, message map[string]interface{}) (map[string]interface{}, error) {
+	if keyring == nil {
+		return message, nil
+	}
+
+	key, err := getDataKey(ctx, client, chatID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]interface{}, len(message)+2)
+	for k, v := range message {
+		stored[k] = v
+	}
+	for _, field := range encryptedMessageFields {
+		value, ok := stored[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		sealed, err := envelope.Seal(key, []byte(value), messageAdditionalData(chatID, messageID, field))
+		if err != nil {
+			return nil, err
+		}
+		stored[field] = base64.StdEncoding.EncodeToString(sealed)
+	}
+	stored[encryptedAtRestField] = true
+	stored[encryptedAtRestVersionField] = encryptedAtRestVersion
+	return stored, nil
+}
+
+// DecryptMessage 保存時暗号化されたメッセージの対象フィールドを復号する
+// 暗号化されていないメッセージはそのまま返す
+// 復号できない場合は、暗号文を表示・出力しないよう対象フィールドをすべて置き換えてエラーを返す
+func DecryptMessage(ctx context.Context, client *firestore.Client, chatID string, messageID string, data map[string]interface{}) error {
+	if encrypted, _ := data[encryptedAtRestField].(bool); !encrypted {
+		return nil
+	}
+	version, _ := data[encryptedAtRestVersionField].(int64)
+	delete(data, encryptedAtRestField)
+	delete(data, encryptedAtRestVersionField)
+
+	if err := decryptMessageFields(ctx, client, chatID, messageID, version, data); err != nil {
+		for _, field := range encryptedMessageFields {
+			if _, ok := data[field]; ok {
+				data[field] = ""
+			}
+		}
+		data["content"] = undecryptableContent
+		return err
+	}
+	return nil
+}
+
+// メッセージの対象フィールドを復号する（失敗した場合、一部のフィールドは暗号文のまま残る）
+func decryptMessageFields(ctx context.Context, client *firestore.Client, chatID, messageID string, version int64, data map[string]interface{}) error {
+	if version != encryptedAtRestVersion {
+		return fmt.Errorf("保存時暗号化の形式が不正です: %d", version)
+	}
+	key, err := getDataKey(ctx, client, chatID, false)
+	if err != nil {
+		return err
+	}
+	for _, field := range encryptedMessageFields {
+		value, ok := data[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("メッセージの %s の形式が不正です: %w", field, err)
+		}
+		plaintext, err := envelope.Open(key, sealed, messageAdditionalData(chatID, messageID, field))
+		if err != nil {
+			return fmt.Errorf("メッセージの %s の復号に失敗: %w", field, err)
+		}
+		data[field] = string(plaintext)
+	}
+	return nil
+}
+
+// RewrapDataKeys 全てのデータ鍵を有効なマスター鍵で再暗号化する
+// マスター鍵のローテーション後に実行し、メッセージ本体は再暗号化しない
+func RewrapDataKeys(ctx context.Context, client *firestore.Client) (int, error) {
+	if keyring == nil {
+		return 0, fmt.Errorf("マスター鍵が設定されていません")
+	}
+
+	iter := client.Collection("dataKeys").Documents(ctx)
+	defer iter.Stop()
+
+	rewrapped := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return rewrapped, fmt.Errorf("データ鍵の列挙に失敗: %v", err)
+		}
+
+		var record dataKeyRecord
+		if err := doc.DataTo(&record); err != nil {
+			slog.Error("データ鍵の変換に失敗", "error", err, "chat_id", doc.Ref.ID)
+			continue
+		}
+		if record.MasterKeyID == keyring.ActiveID() {
+			continue
+		}
+
+		masterKeyID, wrapped, err := rewrapDataKey(record.MasterKeyID, record.WrappedKey)
+		if err != nil {
+			slog.Error("データ鍵の再暗号化に失敗", "error", err, "chat_id", doc.Ref.ID)
+			continue
+		}
+		_, err = doc.Ref.Update(ctx, []firestore.Update{
+			{Path: "MasterKeyID", Value: masterKeyID},
+			{Path: "WrappedKey", Value: wrapped},
+			{Path: "RotatedAt", Value: time.Now()},
+		})
+		if err != nil {
+			return rewrapped, fmt.Errorf("データ鍵の更新に失敗: %v", err)
+		}
+		rewrapped++
+	}
+	return rewrapped, nil
+}
+
+// データ鍵を有効なマスター鍵で暗号化し直す
+// データ鍵自体は変わらないため、メッセージは再暗号化しなくてもそのまま復号できる
+func rewrapDataKey(masterKeyID string, wrappedKey []byte) (string, []byte, error) {
+	dataKey, err := keyring.Unwrap(masterKeyID, wrappedKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("データ鍵の復号に失敗: %w", err)
+	}
+	return keyring.Wrap(dataKey)
+}
+
+// EncryptPlaintextMessages 保存時暗号化を有効にする前に保存された平文のメッセージを暗号化し、暗号化した数を返す
+func EncryptPlaintextMessages(ctx context.Context, client *firestore.Client) (int, error) {
+	if keyring == nil {
+		return 0, fmt.Errorf("マスター鍵が設定されていません")
+	}
+
+	iter := client.Collection("chats").Documents(ctx)
+	defer iter.Stop()
+
+	encrypted := 0
+	for {
+		chatDoc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return encrypted, fmt.Errorf("チャットの列挙に失敗: %v", err)
+		}
+
+		docs, err := chatDoc.Ref.Collection("messages").Documents(ctx).GetAll()
+		if err != nil {
+			return encrypted, fmt.Errorf("メッセージの取得に失敗: %v", err)
+		}
+		for _, doc := range docs {
+			data := doc.Data()
+			if encrypted, _ := data[encryptedAtRestField].(bool); encrypted {
+				continue
+			}
+			stored, err := encryptMessage(ctx, client, chatDoc.Ref.ID, doc.Ref.ID, data)
+			if err != nil {
+				return encrypted, err
+			}
+			if _, err := doc.Ref.Set(ctx, stored); err != nil {
+				return encrypted, fmt.Errorf("メッセージの更新に失敗: %v", err)
+			}
+			encrypted++
+		}
+	}
+	return encrypted, nil
+}
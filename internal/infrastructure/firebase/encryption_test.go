@@ -0,0 +1,268 @@
+package firebase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"security_chat_app/internal/utils/envelope"
+)
+
+// テスト用のマスター鍵を設定し、チャットのデータ鍵を Firestore から読まずに使えるようキャッシュに入れる
+// 同じデータ鍵を複数のチャットに設定し、関連データだけで別のチャットへの移し替えを検出できることを確かめられるようにする
+func setupEncryption(t *testing.T, chatIDs ...string) *envelope.Keyring {
+	t.Helper()
+	savedKeyring := keyring
+	dataKeyCache.Lock()
+	savedCache := dataKeyCache.items
+	dataKeyCache.items = make(map[string][]byte)
+	dataKeyCache.Unlock()
+	t.Cleanup(func() {
+		keyring = savedKeyring
+		dataKeyCache.Lock()
+		dataKeyCache.items = savedCache
+		dataKeyCache.Unlock()
+	})
+
+	keyring = envelope.NewKeyring()
+	if err := keyring.Add("test", newEncodedKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := envelope.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chatID := range chatIDs {
+		cacheDataKey(chatID, dataKey)
+	}
+	return keyring
+}
+
+func cacheDataKey(chatID string, dataKey []byte) {
+	dataKeyCache.Lock()
+	dataKeyCache.items[chatID] = dataKey
+	dataKeyCache.Unlock()
+}
+
+func newEncodedKey(t *testing.T) string {
+	t.Helper()
+	key, err := envelope.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// テスト用のメッセージ
+func newTestMessage() map[string]interface{} {
+	return map[string]interface{}{
+		"content":     "こんにちは",
+		"media_url":   "https://example.com/image.png",
+		"attachments": `[{"name":"a.txt"}]`,
+		"sender_id":   "user-1",
+	}
+}
+
+// メッセージを暗号化し、Firestore から読み込んだ時と同じ形（整数は int64）にして返す
+func sealTestMessage(t *testing.T, chatID, messageID string) map[string]interface{} {
+	t.Helper()
+	stored, err := encryptMessage(context.Background(), nil, chatID, messageID, newTestMessage())
+	if err != nil {
+		t.Fatalf("encryptMessage() error = %v", err)
+	}
+	stored[encryptedAtRestVersionField] = int64(encryptedAtRestVersion)
+	return stored
+}
+
+// 復号に失敗したメッセージの対象フィールドに暗号文が残っていないことを確かめる
+func assertUndecryptable(t *testing.T, data map[string]interface{}) {
+	t.Helper()
+	if data["content"] != undecryptableContent {
+		t.Errorf("content = %q, want %q", data["content"], undecryptableContent)
+	}
+	for _, field := range encryptedMessageFields[1:] {
+		if data[field] != "" {
+			t.Errorf("%s = %q, want it blanked", field, data[field])
+		}
+	}
+}
+
+func TestEncryptDecryptMessageRoundTrip(t *testing.T) {
+	setupEncryption(t, "chat-1")
+	original := newTestMessage()
+
+	stored := sealTestMessage(t, "chat-1", "msg-1")
+	for _, field := range encryptedMessageFields {
+		if stored[field] == original[field] {
+			t.Errorf("%s is stored in plaintext", field)
+		}
+	}
+	if stored["sender_id"] != original["sender_id"] {
+		t.Errorf("sender_id = %q, want it unencrypted", stored["sender_id"])
+	}
+
+	if err := DecryptMessage(context.Background(), nil, "chat-1", "msg-1", stored); err != nil {
+		t.Fatalf("DecryptMessage() error = %v", err)
+	}
+	for field, want := range original {
+		if stored[field] != want {
+			t.Errorf("%s = %q, want %q", field, stored[field], want)
+		}
+	}
+	if _, ok := stored[encryptedAtRestField]; ok {
+		t.Error("encryption marker is left in the decrypted message")
+	}
+}
+
+func TestDecryptMessageRejectsMovedCiphertext(t *testing.T) {
+	tests := []struct {
+		name      string
+		chatID    string
+		messageID string
+		move      func(data map[string]interface{})
+	}{
+		{"other chat", "chat-2", "msg-1", nil},
+		{"other message", "chat-1", "msg-2", nil},
+		{"other field", "chat-1", "msg-1", func(data map[string]interface{}) {
+			data["content"], data["media_url"] = data["media_url"], data["content"]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEncryption(t, "chat-1", "chat-2")
+			stored := sealTestMessage(t, "chat-1", "msg-1")
+			if tt.move != nil {
+				tt.move(stored)
+			}
+			if err := DecryptMessage(context.Background(), nil, tt.chatID, tt.messageID, stored); err == nil {
+				t.Fatal("DecryptMessage() succeeded, want an error")
+			}
+			assertUndecryptable(t, stored)
+		})
+	}
+}
+
+func TestDecryptMessageRequiresCurrentVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version interface{}
+	}{
+		{"missing", nil},
+		{"first format", int64(1)},
+		{"future format", int64(encryptedAtRestVersion + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEncryption(t, "chat-1")
+			stored := sealTestMessage(t, "chat-1", "msg-1")
+			if tt.version == nil {
+				delete(stored, encryptedAtRestVersionField)
+			} else {
+				stored[encryptedAtRestVersionField] = tt.version
+			}
+			if err := DecryptMessage(context.Background(), nil, "chat-1", "msg-1", stored); err == nil {
+				t.Fatal("DecryptMessage() succeeded, want an error")
+			}
+			assertUndecryptable(t, stored)
+		})
+	}
+}
+
+func TestDecryptMessageBlanksEveryFieldOnFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(data map[string]interface{})
+	}{
+		{"tampered first field", func(data map[string]interface{}) {
+			sealed, _ := base64.StdEncoding.DecodeString(data["content"].(string))
+			sealed[len(sealed)-1] ^= 0x01
+			data["content"] = base64.StdEncoding.EncodeToString(sealed)
+		}},
+		{"tampered middle field", func(data map[string]interface{}) {
+			sealed, _ := base64.StdEncoding.DecodeString(data["media_url"].(string))
+			sealed[len(sealed)-1] ^= 0x01
+			data["media_url"] = base64.StdEncoding.EncodeToString(sealed)
+		}},
+		{"invalid base64", func(data map[string]interface{}) {
+			data["media_url"] = "not base64!"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEncryption(t, "chat-1")
+			stored := sealTestMessage(t, "chat-1", "msg-1")
+			tt.tamper(stored)
+			if err := DecryptMessage(context.Background(), nil, "chat-1", "msg-1", stored); err == nil {
+				t.Fatal("DecryptMessage() succeeded, want an error")
+			}
+			assertUndecryptable(t, stored)
+		})
+	}
+}
+
+func TestDecryptMessageIgnoresPlaintext(t *testing.T) {
+	setupEncryption(t, "chat-1")
+	data := newTestMessage()
+	if err := DecryptMessage(context.Background(), nil, "chat-1", "msg-1", data); err != nil {
+		t.Fatalf("DecryptMessage() error = %v", err)
+	}
+	if data["content"] != newTestMessage()["content"] {
+		t.Fatalf("content = %q, want the plaintext unchanged", data["content"])
+	}
+}
+
+func TestRewrapDataKeyKeepsMessagesReadable(t *testing.T) {
+	k := setupEncryption(t)
+
+	// 古いマスター鍵でデータ鍵を暗号化し、そのデータ鍵でメッセージを暗号化する
+	dataKey, err := envelope.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldID, oldWrapped, err := k.Wrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheDataKey("chat-1", dataKey)
+	stored := sealTestMessage(t, "chat-1", "msg-1")
+
+	// マスター鍵をローテーションしてデータ鍵を再暗号化する
+	newKey := newEncodedKey(t)
+	if err := k.Add("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+	newID, newWrapped, err := rewrapDataKey(oldID, oldWrapped)
+	if err != nil {
+		t.Fatalf("rewrapDataKey() error = %v", err)
+	}
+	if newID != "new" || bytes.Equal(newWrapped, oldWrapped) {
+		t.Fatalf("rewrapDataKey() = %q, want the data key wrapped with the new master key", newID)
+	}
+
+	// 古いマスター鍵を廃止しても、再暗号化したデータ鍵で既存のメッセージを復号できる
+	keyring = envelope.NewKeyring()
+	if err := keyring.Add("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Unwrap(oldID, oldWrapped); err == nil {
+		t.Fatal("Unwrap() with the retired master key succeeded")
+	}
+	unwrapped, err := keyring.Unwrap(newID, newWrapped)
+	if err != nil {
+		t.Fatalf("Unwrap() error = %v", err)
+	}
+	cacheDataKey("chat-1", unwrapped)
+
+	if err := DecryptMessage(context.Background(), nil, "chat-1", "msg-1", stored); err != nil {
+		t.Fatalf("DecryptMessage() after rotation error = %v", err)
+	}
+	if stored["content"] != newTestMessage()["content"] {
+		t.Fatalf("content = %q after rotation, want the original message", stored["content"])
+	}
+
+	// 廃止したマスター鍵で暗号化されたままのデータ鍵は再暗号化できない
+	if _, _, err := rewrapDataKey(oldID, oldWrapped); err == nil {
+		t.Fatal("rewrapDataKey() with a retired master key succeeded")
+	}
+}
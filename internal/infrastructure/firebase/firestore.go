@@ -3,18 +3,31 @@ package firebase
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/metrics"
+
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// データストアの呼び出しを計測する（defer で呼び出す）
+func observeDatastore(operation string, start time.Time, err *error) {
+	metrics.ObserveDatastore(operation, time.Since(start), *err)
+}
+
 // コレクションにデータを追加する
-func AddData(collection string, data interface{}, docID string) error {
+func AddData(collection string, data interface{}, docID string) (err error) {
+	defer observeDatastore("add_data", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
-		log.Printf("Firebase初期化エラー: %v", err)
+		slog.Error("Firebase初期化エラー", "error", err)
 		return err
 	}
 	defer client.Close()
@@ -26,17 +39,19 @@ func AddData(collection string, data interface{}, docID string) error {
 		_, _, err = client.Collection(collection).Add(ctx, data)
 	}
 	if err != nil {
-		log.Printf("データ追加エラー: %v", err)
+		slog.Error("データ追加エラー", "error", err, "collection", collection)
 		return err
 	}
 	return nil
 }
 
 // コレクションとドキュメントIDから特定フィールドを更新する
-func UpdateField(collection string, documentID string, field string, value interface{}) error {
+func UpdateField(collection string, documentID string, field string, value interface{}) (err error) {
+	defer observeDatastore("update_field", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
-		log.Printf("Firebase初期化エラー: %v", err)
+		slog.Error("Firebase初期化エラー", "error", err)
 		return err
 	}
 	defer client.Close()
@@ -49,14 +64,57 @@ func UpdateField(collection string, documentID string, field string, value inter
 		},
 	})
 	if err != nil {
-		log.Printf("フィールド更新エラー: %v, collection=%s, documentID=%s, field=%s", err, collection, documentID, field)
+		slog.Error("フィールド更新エラー", "error", err, "collection", collection, "document_id", documentID, "field", field)
 		return err
 	}
 	return nil
 }
 
+// 複数のドキュメントの同じフィールドをまとめて更新する
+func UpdateFieldBatch(ctx context.Context, collection string, documentIDs []string, field string, value interface{}) (err error) {
+	defer observeDatastore("update_field_batch", time.Now(), &err)
+
+	if len(documentIDs) == 0 {
+		return nil
+	}
+
+	client, err := InitFirebase()
+	if err != nil {
+		slog.Error("Firebase初期化エラー", "error", err)
+		return err
+	}
+	defer client.Close()
+
+	writer := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, id := range documentIDs {
+		job, err := writer.Update(client.Collection(collection).Doc(id), []firestore.Update{
+			{Path: field, Value: value},
+		})
+		if err != nil {
+			writer.End()
+			return fmt.Errorf("一括更新の登録に失敗: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
+
+	failed := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d件のドキュメントの更新に失敗しました: collection=%s, field=%s", failed, collection, field)
+	}
+	return nil
+}
+
 // コレクションからデータを取得する
-func GetData(collection string, documentID string) (map[string]interface{}, error) {
+func GetData(collection string, documentID string) (_ map[string]interface{}, err error) {
+	defer observeDatastore("get_data", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
@@ -73,7 +131,9 @@ func GetData(collection string, documentID string) (map[string]interface{}, erro
 }
 
 // コレクションから条件に合うデータを取得する
-func GetDataByQuery(collection string, field string, operator string, value interface{}) ([]map[string]interface{}, error) {
+func GetDataByQuery(collection string, field string, operator string, value interface{}) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_data_by_query", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
@@ -96,7 +156,9 @@ func GetDataByQuery(collection string, field string, operator string, value inte
 }
 
 // コレクションからデータを削除する
-func DeleteData(collection string, documentID string) error {
+func DeleteData(collection string, documentID string) (err error) {
+	defer observeDatastore("delete_data", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return err
@@ -112,7 +174,9 @@ func DeleteData(collection string, documentID string) error {
 }
 
 // コレクションの全データを取得する
-func GetAllData(collection string, userID string) ([]map[string]interface{}, error) {
+func GetAllData(collection string, userID string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_all_data", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
@@ -146,7 +210,9 @@ func GetAllData(collection string, userID string) ([]map[string]interface{}, err
 }
 
 // ユーザーを検索する
-func SearchUser(searchQuery string) ([]map[string]interface{}, error) {
+func SearchUser(searchQuery string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("search_user", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
@@ -159,7 +225,7 @@ func SearchUser(searchQuery string) ([]map[string]interface{}, error) {
 	usersRef := client.Collection("users")
 	docs, err := usersRef.Documents(ctx).GetAll()
 	if err != nil {
-		log.Printf("ユーザー検索エラー: %v", err)
+		slog.Error("ユーザー検索エラー", "error", err)
 		return nil, err
 	}
 
@@ -186,8 +252,44 @@ func SearchUser(searchQuery string) ([]map[string]interface{}, error) {
 	return results, nil
 }
 
+// ユーザーを名前とIDの順に読み込み、fn に渡す（fn が false を返すと終了する）
+// afterID を指定した場合は、名前が afterName でIDが afterID のユーザーより後から読み込む
+func ScanUsersByName(ctx context.Context, afterName string, afterID string, fn func(data map[string]interface{}) bool) (err error) {
+	defer observeDatastore("scan_users_by_name", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	query := client.Collection("users").OrderBy("Name", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc)
+	if afterID != "" {
+		query = query.StartAfter(afterName, afterID)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data := doc.Data()
+		data["ID"] = doc.Ref.ID
+		if !fn(data) {
+			return nil
+		}
+	}
+}
+
 // チャットを開始する
-func StartChat(userID string, targetUserID string) (string, error) {
+func StartChat(userID string, targetUserID string) (_ string, err error) {
+	defer observeDatastore("start_chat", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return "", err
@@ -216,10 +318,12 @@ func StartChat(userID string, targetUserID string) (string, error) {
 }
 
 // チャットメッセージを追加する
-func AddChatMessage(chatID string, message map[string]interface{}) error {
+func AddChatMessage(chatID string, message map[string]interface{}) (err error) {
+	defer observeDatastore("add_chat_message", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
-		log.Printf("Firebase初期化エラー: %v", err)
+		slog.Error("Firebase初期化エラー", "error", err)
 		return err
 	}
 	defer client.Close()
@@ -230,12 +334,20 @@ func AddChatMessage(chatID string, message map[string]interface{}) error {
 	messageID := fmt.Sprintf("msg_%d", time.Now().UnixNano())
 	message["id"] = messageID
 
+	// 本文などを保存時暗号化する
+	stored, err := encryptMessage(ctx, client, chatID, messageID, message)
+	if err != nil {
+		slog.Error("メッセージの暗号化エラー", "error", err, "chat_id", chatID)
+		return err
+	}
+
 	// メッセージを保存
-	_, err = client.Collection("chats").Doc(chatID).Collection("messages").Doc(messageID).Set(ctx, message)
+	_, err = client.Collection("chats").Doc(chatID).Collection("messages").Doc(messageID).Set(ctx, stored)
 	if err != nil {
-		log.Printf("メッセージ保存エラー: %v", err)
+		slog.Error("メッセージ保存エラー", "error", err, "chat_id", chatID)
 		return err
 	}
+	metrics.MessageSent()
 
 	// チャットの更新時刻を更新
 	_, err = client.Collection("chats").Doc(chatID).Update(ctx, []firestore.Update{
@@ -245,7 +357,7 @@ func AddChatMessage(chatID string, message map[string]interface{}) error {
 		},
 	})
 	if err != nil {
-		log.Printf("チャット更新時刻の更新エラー: %v", err)
+		slog.Error("チャット更新時刻の更新エラー", "error", err, "chat_id", chatID)
 		return err
 	}
 
@@ -253,14 +365,15 @@ func AddChatMessage(chatID string, message map[string]interface{}) error {
 }
 
 // チャットのメッセージを取得する
-func GetChatMessages(chatID string) ([]map[string]interface{}, error) {
+func GetChatMessages(ctx context.Context, chatID string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_chat_messages", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 
-	ctx := context.Background()
 	docs, err := client.Collection("chats").Doc(chatID).Collection("messages").OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
@@ -270,6 +383,9 @@ func GetChatMessages(chatID string) ([]map[string]interface{}, error) {
 	for _, doc := range docs {
 		data := doc.Data()
 		data["id"] = doc.Ref.ID
+		if err := DecryptMessage(ctx, client, chatID, doc.Ref.ID, data); err != nil {
+			slog.Error("メッセージの復号エラー", "error", err, "chat_id", chatID, "message_id", doc.Ref.ID)
+		}
 		messages = append(messages, data)
 	}
 
@@ -277,7 +393,9 @@ func GetChatMessages(chatID string) ([]map[string]interface{}, error) {
 }
 
 // チャットの存在確認
-func CheckChatExists(chatID string) (bool, error) {
+func CheckChatExists(chatID string) (_ bool, err error) {
+	defer observeDatastore("check_chat_exists", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return false, err
@@ -294,7 +412,9 @@ func CheckChatExists(chatID string) (bool, error) {
 }
 
 // チャットの参加者を取得
-func GetChatParticipants(chatID string) ([]string, error) {
+func GetChatParticipants(chatID string) (_ []string, err error) {
+	defer observeDatastore("get_chat_participants", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
@@ -324,15 +444,15 @@ func GetChatParticipants(chatID string) ([]string, error) {
 }
 
 // 指定されたユーザーIDが参加者として含まれるチャットを全て取得します
-func GetAllChats(userID string) ([]map[string]interface{}, error) {
+func GetAllChats(ctx context.Context, userID string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_all_chats", time.Now(), &err)
+
 	client, err := InitFirebase()
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 
-	ctx := context.Background()
-
 	// チャットコレクションを参照
 	chatsRef := client.Collection("chats")
 
@@ -358,3 +478,468 @@ func GetAllChats(userID string) ([]map[string]interface{}, error) {
 
 	return chats, nil
 }
+
+// チャットの相手から届いた未読のメッセージを既読にする
+// 既読にしたメッセージの件数を返す
+func MarkChatMessagesRead(ctx context.Context, chatID string, userID string) (_ int, err error) {
+	defer observeDatastore("mark_chat_messages_read", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("chats").Doc(chatID).Collection("messages").Where("is_read", "==", false).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	writer := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range docs {
+		// 自分が送信したメッセージは対象外
+		if senderID, _ := doc.Data()["sender_id"].(string); senderID == userID {
+			continue
+		}
+		job, err := writer.Update(doc.Ref, []firestore.Update{
+			{Path: "is_read", Value: true},
+			{Path: "read_by", Value: firestore.ArrayUnion(userID)},
+		})
+		if err != nil {
+			writer.End()
+			return 0, fmt.Errorf("既読の登録に失敗: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
+
+	count := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// チャットにボットを追加する
+// ボットは1対1のチャットの participants には含めず、bots に保存する
+func AddChatBot(chatID string, botID string) (err error) {
+	defer observeDatastore("add_chat_bot", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	_, err = client.Collection("chats").Doc(chatID).Update(ctx, []firestore.Update{
+		{Path: "bots", Value: firestore.ArrayUnion(botID)},
+	})
+	return err
+}
+
+// チャットからボットを外す
+func RemoveChatBot(chatID string, botID string) (err error) {
+	defer observeDatastore("remove_chat_bot", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	_, err = client.Collection("chats").Doc(chatID).Update(ctx, []firestore.Update{
+		{Path: "bots", Value: firestore.ArrayRemove(botID)},
+	})
+	return err
+}
+
+// チャットに追加されたボットのIDを取得する
+func GetChatBots(chatID string) (_ []string, err error) {
+	defer observeDatastore("get_chat_bots", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	doc, err := client.Collection("chats").Doc(chatID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	bots, _ := doc.Data()["bots"].([]interface{})
+	for _, b := range bots {
+		if str, ok := b.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result, nil
+}
+
+// ボットが追加されているチャットを全て取得する
+func GetBotChats(botID string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_bot_chats", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("chats").Where("bots", "array-contains", botID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("チャットデータの取得に失敗: %v", err)
+	}
+
+	var chats []map[string]interface{}
+	for _, doc := range docs {
+		data := doc.Data()
+		data["id"] = doc.Ref.ID
+		chats = append(chats, data)
+	}
+	return chats, nil
+}
+
+// 指定した日時より後に送信されたチャットのメッセージを古い順に取得する
+func GetChatMessagesSince(ctx context.Context, chatID string, since time.Time) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_chat_messages_since", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("chats").Doc(chatID).Collection("messages").
+		Where("created_at", ">", since).OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []map[string]interface{}
+	for _, doc := range docs {
+		data := doc.Data()
+		data["id"] = doc.Ref.ID
+		if err := DecryptMessage(ctx, client, chatID, doc.Ref.ID, data); err != nil {
+			slog.Error("メッセージの復号エラー", "error", err, "chat_id", chatID, "message_id", doc.Ref.ID)
+		}
+		messages = append(messages, data)
+	}
+	return messages, nil
+}
+
+// チャットのメッセージを新しい順に最大 limit 件取得する
+// beforeID を指定した場合は、送信日時が before でIDが beforeID のメッセージより前（古い）のものを取得する
+func GetChatMessagesBefore(ctx context.Context, chatID string, before time.Time, beforeID string, limit int) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_chat_messages_before", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	query := client.Collection("chats").Doc(chatID).Collection("messages").
+		OrderBy("created_at", firestore.Desc).OrderBy(firestore.DocumentID, firestore.Desc)
+	if beforeID != "" {
+		query = query.StartAfter(before, beforeID)
+	}
+	docs, err := query.Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []map[string]interface{}
+	for _, doc := range docs {
+		data := doc.Data()
+		data["id"] = doc.Ref.ID
+		if err := DecryptMessage(ctx, client, chatID, doc.Ref.ID, data); err != nil {
+			slog.ErrorContext(ctx, "メッセージの復号エラー", "error", err, "chat_id", chatID, "message_id", doc.Ref.ID)
+		}
+		messages = append(messages, data)
+	}
+	return messages, nil
+}
+
+// チャットのメッセージを1件取得する（見つからない場合は nil を返す）
+func GetChatMessage(ctx context.Context, chatID string, messageID string) (_ map[string]interface{}, err error) {
+	defer observeDatastore("get_chat_message", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("chats").Doc(chatID).Collection("messages").Doc(messageID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := doc.Data()
+	data["id"] = doc.Ref.ID
+	if err := DecryptMessage(ctx, client, chatID, messageID, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// メッセージの本文を変更し、変更後のメッセージを返す
+// 保存時暗号化されている場合は、他のフィールドとあわせて暗号化し直して保存する
+func EditChatMessage(ctx context.Context, chatID string, messageID string, content string, editedAt time.Time) (_ map[string]interface{}, err error) {
+	defer observeDatastore("edit_chat_message", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ref := client.Collection("chats").Doc(chatID).Collection("messages").Doc(messageID)
+	var edited map[string]interface{}
+	// 編集中に既読が付いても上書きしないよう、トランザクションで読み書きする
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		data := doc.Data()
+		if err := DecryptMessage(ctx, client, chatID, messageID, data); err != nil {
+			return err
+		}
+		data["content"] = content
+		data["edited_at"] = editedAt
+
+		stored, err := encryptMessage(ctx, client, chatID, messageID, data)
+		if err != nil {
+			return err
+		}
+		edited = data
+		return tx.Set(ref, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	edited["id"] = messageID
+	return edited, nil
+}
+
+// チャットのメッセージを削除する
+func DeleteChatMessage(ctx context.Context, chatID string, messageID string) (err error) {
+	defer observeDatastore("delete_chat_message", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Collection("chats").Doc(chatID).Collection("messages").Doc(messageID).Delete(ctx)
+	return err
+}
+
+// 通報されたメッセージと前後のメッセージを、通報時点の内容として保存する
+// 元のメッセージと同じく、チャットのデータ鍵で保存時暗号化する
+// メッセージの集計（messages のコレクショングループ）に含まれないよう、別の名前のサブコレクションに保存する
+func SaveReportContext(ctx context.Context, reportID string, chatID string, messages []map[string]interface{}) (err error) {
+	defer observeDatastore("save_report_context", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	contextRef := client.Collection("reports").Doc(reportID).Collection("contextMessages")
+	for _, message := range messages {
+		messageID, _ := message["id"].(string)
+		stored, err := encryptMessage(ctx, client, chatID, messageID, message)
+		if err != nil {
+			return err
+		}
+		if _, err := contextRef.Doc(messageID).Set(ctx, stored); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 通報時点で保存したメッセージを古い順に取得する
+func GetReportContext(ctx context.Context, reportID string, chatID string) (_ []map[string]interface{}, err error) {
+	defer observeDatastore("get_report_context", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("reports").Doc(reportID).Collection("contextMessages").OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var messages []map[string]interface{}
+	for _, doc := range docs {
+		data := doc.Data()
+		data["id"] = doc.Ref.ID
+		if err := DecryptMessage(ctx, client, chatID, doc.Ref.ID, data); err != nil {
+			slog.ErrorContext(ctx, "メッセージの復号エラー", "error", err, "report_id", reportID, "message_id", doc.Ref.ID)
+		}
+		messages = append(messages, data)
+	}
+	return messages, nil
+}
+
+// 削除したユーザーをチャットから取り除き、処理したメッセージの件数を返す
+// deleteMessages が true の場合はユーザーが送信したメッセージを削除し、false の場合は送信者を削除されたユーザーに置き換えて残す
+// 相手が履歴を読めるよう、チャット自体と参加者の枠は残し、ユーザーのIDを削除されたユーザーのIDに置き換える
+func EraseUserFromChat(ctx context.Context, chatID string, userID string, deleteMessages bool) (_ int, err error) {
+	defer observeDatastore("erase_user_from_chat", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	chatRef := client.Collection("chats").Doc(chatID)
+	docs, err := chatRef.Collection("messages").Where("sender_id", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	writer := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range docs {
+		var job *firestore.BulkWriterJob
+		if deleteMessages {
+			job, err = writer.Delete(doc.Ref)
+		} else {
+			job, err = writer.Update(doc.Ref, []firestore.Update{
+				{Path: "sender_id", Value: domain.DeletedUserID},
+				{Path: "sender_name", Value: domain.DeletedUserName},
+			})
+		}
+		if err != nil {
+			writer.End()
+			return 0, fmt.Errorf("メッセージの処理に失敗: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
+
+	count := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	// ユーザーの端末向けに包んだチャット鍵は不要になるため削除する
+	keyDocs, err := chatRef.Collection("keys").Where("UserID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return count, err
+	}
+	if _, err := deleteDocuments(ctx, client, keyDocs); err != nil {
+		return count, err
+	}
+
+	// 参加者を置き換える（ArrayRemove・ArrayUnion を同時に使えないため、読み込んでから書き込む）
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(chatRef)
+		if err != nil {
+			return err
+		}
+		participants, _ := doc.Data()["participants"].([]interface{})
+		replaced := make([]interface{}, 0, len(participants))
+		for _, p := range participants {
+			if p == userID {
+				p = domain.DeletedUserID
+			}
+			replaced = append(replaced, p)
+		}
+		return tx.Update(chatRef, []firestore.Update{{Path: "participants", Value: replaced}})
+	})
+	return count, err
+}
+
+// チャットをメッセージ・端末ごとのチャット鍵を含めて削除する
+// 通報の記録（通報時点のメッセージ）が復号できなくならないよう、通報があるチャットのデータ鍵は残す
+func DeleteChat(ctx context.Context, chatID string) (err error) {
+	defer observeDatastore("delete_chat", time.Now(), &err)
+
+	client, err := InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	chatRef := client.Collection("chats").Doc(chatID)
+	for _, name := range []string{"messages", "keys"} {
+		docs, err := chatRef.Collection(name).Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		if _, err := deleteDocuments(ctx, client, docs); err != nil {
+			return err
+		}
+	}
+	if _, err := chatRef.Delete(ctx); err != nil {
+		return err
+	}
+
+	reports, err := client.Collection("reports").Where("ChatID", "==", chatID).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	if len(reports) > 0 {
+		return nil
+	}
+	if _, err := client.Collection("dataKeys").Doc(chatID).Delete(ctx); err != nil {
+		return err
+	}
+	dataKeyCache.Lock()
+	delete(dataKeyCache.items, chatID)
+	dataKeyCache.Unlock()
+	return nil
+}
+
+// ドキュメントをまとめて削除し、削除した件数を返す
+func deleteDocuments(ctx context.Context, client *firestore.Client, docs []*firestore.DocumentSnapshot) (int, error) {
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	writer := client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+	for _, doc := range docs {
+		job, err := writer.Delete(doc.Ref)
+		if err != nil {
+			writer.End()
+			return 0, fmt.Errorf("ドキュメントの削除に失敗: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
+
+	count := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,49 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/iterator"
+)
+
+// CheckFirestore Firestoreに接続できるかを確認する
+func CheckFirestore(ctx context.Context) error {
+	app, err := newApp(ctx)
+	if err != nil {
+		return fmt.Errorf("Firebaseアプリの初期化に失敗: %v", err)
+	}
+	client, err := app.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("Firestoreクライアント作成に失敗: %v", err)
+	}
+	defer client.Close()
+
+	// 1件だけ読み込んで疎通を確認する（ドキュメントが無くても成功とする）
+	iter := client.Collection("users").Limit(1).Documents(ctx)
+	defer iter.Stop()
+	if _, err := iter.Next(); err != nil && err != iterator.Done {
+		return fmt.Errorf("Firestoreへの問い合わせに失敗: %v", err)
+	}
+	return nil
+}
+
+// CheckStorage ストレージのバケットにアクセスできるかを確認する
+func CheckStorage(ctx context.Context) error {
+	app, err := newApp(ctx)
+	if err != nil {
+		return fmt.Errorf("Firebaseアプリの初期化に失敗: %v", err)
+	}
+	client, err := app.Storage(ctx)
+	if err != nil {
+		return fmt.Errorf("Storageクライアントの作成に失敗: %v", err)
+	}
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return fmt.Errorf("デフォルトバケットの取得に失敗: %v", err)
+	}
+	if _, err := bucket.Attrs(ctx); err != nil {
+		return fmt.Errorf("バケットの情報の取得に失敗: %v", err)
+	}
+	return nil
+}
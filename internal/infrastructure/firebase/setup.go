@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
@@ -19,29 +19,9 @@ import (
 )
 
 func InitFirebase() (*firestore.Client, error) {
-	var opts []option.ClientOption
-
-	// ServiceKeyPathが設定されている場合はファイルから読み込む
-	// そうでない場合はCloud Runのデフォルト認証情報を使用
-	if config.Config.ServiceKeyPath != "" {
-		opts = append(opts, option.WithCredentialsFile(config.Config.ServiceKeyPath))
-	}
-	// ServiceKeyPathが空の場合は、デフォルト認証情報（Cloud Runのサービスアカウントなど）を使用
-
-	firebaseConfig := &firebase.Config{
-		ProjectID:     config.Config.ProjectId,
-		StorageBucket: config.Config.StorageBucket,
-	}
-
-	var app *firebase.App
-	var err error
-	if len(opts) > 0 {
-		app, err = firebase.NewApp(context.Background(), firebaseConfig, opts...)
-	} else {
-		app, err = firebase.NewApp(context.Background(), firebaseConfig)
-	}
+	app, err := newApp(context.Background())
 	if err != nil {
-		log.Printf("Firebaseアプリの初期化に失敗: %v", err)
+		slog.Error("Firebaseアプリの初期化に失敗", "error", err)
 		return nil, err
 	}
 
@@ -50,99 +30,128 @@ func InitFirebase() (*firestore.Client, error) {
 
 	client, err := app.Firestore(ctx)
 	if err != nil {
-		log.Printf("Firestoreクライアント作成に失敗: %v", err)
+		slog.Error("Firestoreクライアント作成に失敗", "error", err)
 		return nil, err
 	}
 
 	// デフォルトアイコンの初期化
 	if err := initDefaultIcons(app); err != nil {
-		log.Printf("デフォルトアイコンの初期化に失敗: %v", err)
+		slog.Error("デフォルトアイコンの初期化に失敗", "error", err)
 	}
 
 	return client, nil
 }
 
-// デフォルトアイコンを初期化する
+// 設定に従ってFirebaseアプリを作成する
+func newApp(ctx context.Context) (*firebase.App, error) {
+	var opts []option.ClientOption
+
+	// ServiceKeyPathが設定されている場合はファイルから読み込む
+	// そうでない場合はCloud Runのデフォルト認証情報を使用
+	if config.Config.ServiceKeyPath != "" {
+		opts = append(opts, option.WithCredentialsFile(config.Config.ServiceKeyPath))
+	}
+
+	firebaseConfig := &firebase.Config{
+		ProjectID:     config.Config.ProjectId,
+		StorageBucket: config.Config.StorageBucket,
+	}
+	return firebase.NewApp(ctx, firebaseConfig, opts...)
+}
+
+// デフォルトアイコンを初期化する（ストレージにデフォルトアイコンが無い場合のみアップロードする）
 func initDefaultIcons(app *firebase.App) error {
-	ctx := context.Background()
+	_, err := uploadDefaultIcons(context.Background(), app, false)
+	return err
+}
+
+// SeedDefaultIcons デフォルトアイコンをアップロードし直し、アップロードした数を返す
+// ストレージに既にある同じ名前のアイコンは上書きする
+func SeedDefaultIcons(ctx context.Context) (int, error) {
+	app, err := newApp(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Firebaseアプリの初期化に失敗: %v", err)
+	}
+	return uploadDefaultIcons(ctx, app, true)
+}
+
+// ローカルのデフォルトアイコンをストレージにアップロードする
+// force が false の場合、ストレージにデフォルトアイコンが1つでもあれば何もしない
+func uploadDefaultIcons(ctx context.Context, app *firebase.App, force bool) (int, error) {
 	storageClient, err := app.Storage(ctx)
 	if err != nil {
-		return fmt.Errorf("Storageクライアントの作成に失敗: %v", err)
+		return 0, fmt.Errorf("Storageクライアントの作成に失敗: %v", err)
 	}
 	bucket, err := storageClient.DefaultBucket()
 	if err != nil {
-		return fmt.Errorf("デフォルトバケットの取得に失敗: %v", err)
+		return 0, fmt.Errorf("デフォルトバケットの取得に失敗: %v", err)
 	}
 
 	// デフォルトアイコンディレクトリの存在確認
 	storagePrefix := "icons/default/"
-	it := bucket.Objects(ctx, &storage.Query{Prefix: storagePrefix})
-
-	hasObjects := false
-	for {
+	if !force {
+		it := bucket.Objects(ctx, &storage.Query{Prefix: storagePrefix})
 		_, err := it.Next()
-		if err == iterator.Done {
-			break
+		if err == nil {
+			return 0, nil
 		}
-		if err != nil {
-			return fmt.Errorf("オブジェクトの列挙に失敗: %v", err)
+		if err != iterator.Done {
+			return 0, fmt.Errorf("オブジェクトの列挙に失敗: %v", err)
 		}
-		hasObjects = true
-		break
 	}
 
-	// デフォルトアイコンが存在しない場合、アップロードする
-	if !hasObjects {
-		localIconDir := config.Config.DefaultIconDir
-		if localIconDir == "" {
-			return fmt.Errorf("デフォルトアイコンディレクトリのパスが設定されていません")
-		}
+	localIconDir := config.Config.DefaultIconDir
+	if localIconDir == "" {
+		return 0, fmt.Errorf("デフォルトアイコンディレクトリのパスが設定されていません")
+	}
 
-		// デフォルトアイコンディレクトリが存在しない場合はエラー
-		if _, err := os.Stat(localIconDir); os.IsNotExist(err) {
-			log.Printf("デフォルトアイコンディレクトリが存在しません: %s", localIconDir)
-			return fmt.Errorf("デフォルトアイコンディレクトリが存在しません: %s", localIconDir)
-		}
+	// デフォルトアイコンディレクトリが存在しない場合はエラー
+	if _, err := os.Stat(localIconDir); os.IsNotExist(err) {
+		slog.Warn("デフォルトアイコンディレクトリが存在しません", "dir", localIconDir)
+		return 0, fmt.Errorf("デフォルトアイコンディレクトリが存在しません: %s", localIconDir)
+	}
+
+	files, err := os.ReadDir(localIconDir)
+	if err != nil {
+		return 0, fmt.Errorf("デフォルトアイコンディレクトリの読み込みに失敗: %v", err)
+	}
 
-		files, err := os.ReadDir(localIconDir)
-		if err != nil {
-			return fmt.Errorf("デフォルトアイコンディレクトリの読み込みに失敗: %v", err)
+	uploaded := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
 		}
 
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-
-			filePath := filepath.Join(localIconDir, file.Name())
-			fileContent, err := os.Open(filePath)
-			if err != nil {
-				log.Printf("ファイル %s のオープンに失敗: %v", filePath, err)
-				continue
-			}
-			defer fileContent.Close()
-
-			objectPath := storagePrefix + file.Name()
-			obj := bucket.Object(objectPath)
-			writer := obj.NewWriter(ctx)
-			writer.ObjectAttrs = storage.ObjectAttrs{
-				Name:        objectPath,
-				ContentType: "image/png",
-				ACL:         []storage.ACLRule{{Entity: storage.AllUsers, Role: storage.RoleReader}},
-			}
-
-			if _, err := io.Copy(writer, fileContent); err != nil {
-				log.Printf("ファイル %s のアップロードに失敗: %v", filePath, err)
-				writer.Close()
-				continue
-			}
-
-			if err := writer.Close(); err != nil {
-				log.Printf("ファイル %s のアップロード完了に失敗: %v", filePath, err)
-				continue
-			}
+		filePath := filepath.Join(localIconDir, file.Name())
+		objectPath := storagePrefix + file.Name()
+		if err := uploadDefaultIcon(ctx, bucket.Object(objectPath), filePath); err != nil {
+			slog.Error("ファイルのアップロードに失敗", "error", err, "file", filePath)
+			continue
 		}
+		uploaded++
+	}
+	return uploaded, nil
+}
+
+// デフォルトアイコンを1つアップロードする
+func uploadDefaultIcon(ctx context.Context, obj *storage.ObjectHandle, filePath string) error {
+	fileContent, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer fileContent.Close()
+
+	writer := obj.NewWriter(ctx)
+	writer.ObjectAttrs = storage.ObjectAttrs{
+		Name:        obj.ObjectName(),
+		ContentType: "image/png",
+		ACL:         []storage.ACLRule{{Entity: storage.AllUsers, Role: storage.RoleReader}},
+	}
+
+	if _, err := io.Copy(writer, fileContent); err != nil {
+		writer.Close()
+		return err
 	}
-	return nil
+	return writer.Close()
 }
 
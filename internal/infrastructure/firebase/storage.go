@@ -6,12 +6,17 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"security_chat_app/internal/config"
+	"security_chat_app/internal/domain"
 
 	"cloud.google.com/go/storage"
 	firebase "firebase.google.com/go"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -83,3 +88,151 @@ func GetDefaultIconURL(objectPath string) (string, error) {
 	url := fmt.Sprintf("https://firebasestorage.googleapis.com/v0/b/%s/o/%s?alt=media", config.Config.StorageBucket, url.PathEscape(objectPath))
 	return url, nil
 }
+
+// ストレージの使用量をディレクトリごとに集計する
+func GetStorageUsage(ctx context.Context) ([]domain.StorageUsage, error) {
+	app, err := newApp(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Firebaseアプリの初期化に失敗: %v", err)
+	}
+	client, err := app.Storage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Storageクライアントの作成に失敗: %v", err)
+	}
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, fmt.Errorf("デフォルトバケットの取得に失敗: %v", err)
+	}
+
+	usages := make(map[string]*domain.StorageUsage)
+	it := bucket.Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("オブジェクトの列挙に失敗: %v", err)
+		}
+		prefix := path.Dir(attrs.Name)
+		usage, ok := usages[prefix]
+		if !ok {
+			usage = &domain.StorageUsage{Prefix: prefix}
+			usages[prefix] = usage
+		}
+		usage.Objects++
+		usage.Bytes += attrs.Size
+	}
+
+	result := make([]domain.StorageUsage, 0, len(usages))
+	for _, usage := range usages {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Prefix < result[j].Prefix
+	})
+	return result, nil
+}
+
+// ユーザーがアップロードしたアイコン（icons/<ユーザーID>.<拡張子>）を削除し、削除した数を返す
+// デフォルトのアイコン（icons/default/）は削除しない
+func DeleteUserIcon(ctx context.Context, userID string) (int, error) {
+	bucket, err := defaultBucket(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// 前方一致では ID が同じ文字列で始まる他のユーザーのアイコンも含まれるため、拡張子を除いた名前が一致するもののみ削除する
+	iconPath := "icons/" + userID
+	deleted := 0
+	it := bucket.Objects(ctx, &storage.Query{Prefix: iconPath})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("オブジェクトの列挙に失敗: %v", err)
+		}
+		if strings.TrimSuffix(attrs.Name, path.Ext(attrs.Name)) != iconPath {
+			continue
+		}
+		if err := bucket.Object(attrs.Name).Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+			return deleted, fmt.Errorf("アイコンの削除に失敗: %v", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// エクスポートしたデータ（ZIPファイル）を非公開で保存する
+func UploadExport(ctx context.Context, objectPath string, data []byte) error {
+	bucket, err := defaultBucket(ctx)
+	if err != nil {
+		return err
+	}
+	wc := bucket.Object(objectPath).NewWriter(ctx)
+	wc.ContentType = "application/zip"
+	if _, err := wc.Write(data); err != nil {
+		wc.Close()
+		return fmt.Errorf("ファイルのアップロードに失敗: %v", err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("ライターのクローズに失敗: %v", err)
+	}
+	return nil
+}
+
+// エクスポートしたデータ（ZIPファイル）を読み込む
+func OpenExport(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	bucket, err := defaultBucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := bucket.Object(objectPath).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ファイルの読み込みに失敗: %v", err)
+	}
+	return reader, nil
+}
+
+// prefix で始まるオブジェクトをすべて削除し、削除した数を返す
+func DeleteObjects(ctx context.Context, prefix string) (int, error) {
+	bucket, err := defaultBucket(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	it := bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("オブジェクトの列挙に失敗: %v", err)
+		}
+		if err := bucket.Object(attrs.Name).Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+			return deleted, fmt.Errorf("オブジェクトの削除に失敗: %v", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// デフォルトのバケットを取得する
+func defaultBucket(ctx context.Context) (*storage.BucketHandle, error) {
+	app, err := newApp(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Firebaseアプリの初期化に失敗: %v", err)
+	}
+	client, err := app.Storage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Storageクライアントの作成に失敗: %v", err)
+	}
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, fmt.Errorf("デフォルトバケットの取得に失敗: %v", err)
+	}
+	return bucket, nil
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// メトリクス名の接頭辞
+const namespace = "chatapp"
+
+// アクティブなセッション数を再集計する間隔（Firestoreへの問い合わせを抑える）
+const activeSessionsRefreshInterval = time.Minute
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "ルートごとのHTTPリクエスト数",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "ルートごとのHTTPリクエストの処理時間",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	datastoreOps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "datastore_operations_total",
+		Help:      "操作ごとのデータストアの呼び出し数",
+	}, []string{"operation", "result"})
+
+	datastoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "datastore_operation_duration_seconds",
+		Help:      "操作ごとのデータストアの呼び出し時間",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	realtimeConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "realtime_connections",
+		Help:      "接続中のリアルタイム通信（ロングポーリングなど）の数",
+	})
+
+	messagesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "送信されたメッセージ数（1分あたりの件数は rate(...[5m]) * 60 で求める）",
+	})
+
+	loginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "理由ごとのログイン失敗数",
+	}, []string{"reason"})
+)
+
+// ログイン失敗の理由（ラベルの値を固定する）
+const (
+	LoginFailureValidation    = "validation"          // 入力値の不備
+	LoginFailureCredentials   = "invalid_credentials" // メールアドレスまたはパスワードの誤り
+	LoginFailureError         = "error"               // サーバー側のエラー
+	LoginFailureSuspended     = "suspended"           // 停止されたアカウント
+	LoginFailurePasswordReset = "password_reset"      // 管理者によりパスワードの再設定が求められている
+)
+
+// 集計対象のHTTPメソッド（それ以外は OTHER にまとめる）
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// ObserveHTTPRequest HTTPリクエストの結果を記録する
+// route にはURLではなくルーティングのパターンを渡す（ラベルの種類を限定するため）
+func ObserveHTTPRequest(route, method string, code int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	if !knownMethods[method] {
+		method = "OTHER"
+	}
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ObserveDatastore データストアの呼び出し結果を記録する
+func ObserveDatastore(operation string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	datastoreOps.WithLabelValues(operation, result).Inc()
+	datastoreDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// RealtimeConnectionOpened リアルタイム通信の接続を記録する
+func RealtimeConnectionOpened() {
+	realtimeConnections.Inc()
+}
+
+// RealtimeConnectionClosed リアルタイム通信の切断を記録する
+func RealtimeConnectionClosed() {
+	realtimeConnections.Dec()
+}
+
+// MessageSent メッセージの送信を記録する
+func MessageSent() {
+	messagesSent.Inc()
+}
+
+// LoginFailed ログインの失敗を記録する
+func LoginFailed(reason string) {
+	loginFailures.WithLabelValues(reason).Inc()
+}
+
+// RegisterActiveSessions アクティブなセッション数を集計する関数を登録する
+// 集計結果は一定時間キャッシュし、スクレイプのたびにデータストアへ問い合わせないようにする
+func RegisterActiveSessions(count func(ctx context.Context) (int, error)) {
+	var mu sync.Mutex
+	var value float64
+	var updatedAt time.Time
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "有効期限内のセッション数",
+	}, func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(updatedAt) < activeSessionsRefreshInterval {
+			return value
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		n, err := count(ctx)
+		if err != nil {
+			slog.Error("アクティブなセッション数の集計に失敗", "error", err)
+			return value
+		}
+		value = float64(n)
+		updatedAt = time.Now()
+		return value
+	})
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+)
+
+// アカウントの削除を予約する（at を過ぎると DeleteScheduledAccounts で削除する）
+// deleteMessages が true の場合は送信したメッセージも削除し、false の場合は送信者を削除されたユーザーに置き換える
+func ScheduleAccountDeletion(userID string, at time.Time, deleteMessages bool) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "DeletionScheduledAt", Value: at},
+		{Path: "DeleteMessages", Value: deleteMessages},
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+}
+
+// アカウントの削除の予約を取り消す
+func CancelAccountDeletion(userID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "DeletionScheduledAt", Value: firestore.Delete},
+		{Path: "DeleteMessages", Value: firestore.Delete},
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+}
+
+// ユーザーを削除し、ユーザーに紐づくデータを消去する
+//   - 作成したボット、セッション・APIトークン・Webhook・端末の公開鍵・連絡先・ブロック、エクスポートしたデータ、アップロードしたアイコンを削除する
+//   - 相手が残っているチャットは、送信したメッセージを削除するか（user.DeleteMessages）送信者を削除されたユーザーに置き換えて残す
+//   - 相手も削除されているチャットは、メッセージごと削除する
+//
+// 通報・監査ログは運営の記録として残す
+// 途中で失敗した場合もやり直せるよう、ユーザーのドキュメントは最後に削除する
+func DeleteUser(ctx context.Context, user *domain.User) error {
+	defer InvalidateUserCache(user.ID)
+
+	// ログインできないよう、最初にセッションを削除する
+	if _, err := DeleteUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+
+	bots, err := GetBotsByOwner(user.ID)
+	if err != nil {
+		return err
+	}
+	for i := range bots {
+		if err := DeleteBot(&bots[i]); err != nil {
+			return fmt.Errorf("ボットの削除に失敗: %v", err)
+		}
+	}
+
+	chats, err := firebase.GetAllChats(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		chatID, _ := chat["id"].(string)
+		participants, _ := chat["participants"].([]interface{})
+		remaining, err := hasRemainingParticipant(participants, user.ID)
+		if err != nil {
+			return err
+		}
+		if !remaining {
+			if err := firebase.DeleteChat(ctx, chatID); err != nil {
+				return fmt.Errorf("チャットの削除に失敗: %v", err)
+			}
+			continue
+		}
+		if _, err := firebase.EraseUserFromChat(ctx, chatID, user.ID, user.DeleteMessages); err != nil {
+			return fmt.Errorf("チャットからの削除に失敗: %v", err)
+		}
+	}
+
+	// ユーザーが持つ、またはユーザーを参照するドキュメント
+	queries := []struct {
+		collection string
+		field      string
+		operator   string
+	}{
+		{"apiTokens", "UserID", "=="},
+		{"webhooks", "OwnerID", "=="},
+		{"incomingWebhooks", "CreatorID", "=="},
+		{"deviceKeys", "UserID", "=="},
+		{"contactLinks", "UserIDs", "array-contains"},
+		{"contactRequests", "FromID", "=="},
+		{"contactRequests", "ToID", "=="},
+		{"blocks", "BlockerID", "=="},
+		{"blocks", "BlockedID", "=="},
+	}
+	for _, q := range queries {
+		if _, err := deleteByQuery(ctx, q.collection, func(ref *firestore.CollectionRef) firestore.Query {
+			return ref.Where(q.field, q.operator, user.ID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := DeleteUserDataExports(ctx, user.ID); err != nil {
+		return err
+	}
+	if _, err := firebase.DeleteUserIcon(ctx, user.ID); err != nil {
+		return err
+	}
+	return firebase.DeleteData("users", user.ID)
+}
+
+// チャットに削除するユーザー以外の参加者が残っているかどうか
+func hasRemainingParticipant(participants []interface{}, userID string) (bool, error) {
+	for _, p := range participants {
+		id, _ := p.(string)
+		if id == "" || id == userID || id == domain.DeletedUserID {
+			continue
+		}
+		if _, err := GetUserByID(id); err != nil {
+			if status.Code(err) == codes.NotFound {
+				continue
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// 削除予定日時を過ぎたアカウントを削除し、削除した数を返す
+// 削除に失敗したアカウントがあっても残りのアカウントの削除を続け、失敗をまとめたエラーを返す（次回の実行で再試行される）
+func DeleteScheduledAccounts(ctx context.Context) (int, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	// 予約していないユーザーはゼロ値（西暦1年）が保存されているため、下限を付けて除く
+	docs, err := client.Collection("users").
+		Where("DeletionScheduledAt", ">", time.Unix(0, 0)).
+		Where("DeletionScheduledAt", "<=", time.Now()).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	var errs []error
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		var user domain.User
+		if err := doc.DataTo(&user); err != nil {
+			slog.ErrorContext(ctx, "ユーザーの変換エラー", "error", err, "user_id", doc.Ref.ID)
+			continue
+		}
+		user.ID = doc.Ref.ID
+		if err := DeleteUser(ctx, &user); err != nil {
+			slog.ErrorContext(ctx, "予約されたアカウントの削除に失敗", "error", err, "target_user_id", user.ID)
+			errs = append(errs, fmt.Errorf("ユーザー %s の削除に失敗: %w", user.ID, err))
+			continue
+		}
+		deleted++
+
+		messages := "anonymized"
+		if user.DeleteMessages {
+			messages = "deleted"
+		}
+		event := &domain.AuditEvent{
+			Action:   domain.AuditUserDeleted,
+			TargetID: user.ID,
+			Metadata: map[string]string{"reason": "scheduled", "messages": messages},
+		}
+		if err := RecordAuditEvent(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "監査ログの記録に失敗", "error", err, "action", event.Action, "target_user_id", user.ID)
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// 削除予定日時を過ぎたアカウントと、ダウンロードの期限が過ぎたエクスポートを interval ごとに削除する（ctx が終了するまで）
+func RunAccountMaintenance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		count, err := DeleteScheduledAccounts(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "予約されたアカウントの削除に失敗", "error", err, "count", count)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "予約されたアカウントを削除しました", "count", count)
+		}
+
+		count, err = PurgeExpiredDataExports(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "期限切れのエクスポートの削除に失敗", "error", err, "count", count)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "期限切れのエクスポートを削除しました", "count", count)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+
+	"cloud.google.com/go/firestore"
+)
+
+// 個人用アクセストークンを発行する
+// 戻り値のトークンはこの時点でしか得られないため、呼び出し元で利用者に一度だけ表示する
+func CreateAPIToken(userID, name string, scopes []string, expiresAt time.Time) (*domain.APIToken, string, error) {
+	tokenID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := domain.APITokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiToken := &domain.APIToken{
+		ID:        tokenID,
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: domain.HashAPIToken(token),
+		Hint:      token[:len(domain.APITokenPrefix)+4],
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := firebase.AddData("apiTokens", apiToken, tokenID); err != nil {
+		slog.Error("APIトークンの保存エラー", "error", err, "user_id", userID)
+		return nil, "", err
+	}
+	return apiToken, token, nil
+}
+
+// ユーザーのAPIトークンを発行日時の新しい順に取得する
+func GetAPITokensByUser(userID string) ([]domain.APIToken, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("apiTokens").Where("UserID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []domain.APIToken
+	for _, doc := range docs {
+		var token domain.APIToken
+		if err := doc.DataTo(&token); err != nil {
+			slog.Error("APIトークンの変換エラー", "error", err)
+			continue
+		}
+		token.ID = doc.Ref.ID
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// トークンに対応するAPIトークンを取得する（見つからない場合は nil を返す）
+func GetAPITokenByToken(ctx context.Context, token string) (*domain.APIToken, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("apiTokens").Where("TokenHash", "==", domain.HashAPIToken(token)).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	var apiToken domain.APIToken
+	if err := docs[0].DataTo(&apiToken); err != nil {
+		return nil, err
+	}
+	apiToken.ID = docs[0].Ref.ID
+	return &apiToken, nil
+}
+
+// APIトークンを失効させる（所有者のトークンのみ削除できる）
+func DeleteAPIToken(userID, tokenID string) error {
+	data, err := firebase.GetData("apiTokens", tokenID)
+	if err != nil {
+		return err
+	}
+	if owner, _ := data["UserID"].(string); owner != userID {
+		return fmt.Errorf("APIトークンの所有者が一致しません")
+	}
+	return firebase.DeleteData("apiTokens", tokenID)
+}
+
+// ユーザーのAPIトークンと、ユーザーが作成したボットのトークンをすべて失効させ、失効させた数を返す
+// 強制ログアウトでセッション以外の認証手段も使えなくするために使う
+func RevokeUserAPITokens(ctx context.Context, userID string) (int, error) {
+	bots, err := GetBotsByOwner(userID)
+	if err != nil {
+		return 0, err
+	}
+	userIDs := []string{userID}
+	for _, bot := range bots {
+		userIDs = append(userIDs, bot.ID)
+	}
+
+	revoked := 0
+	for _, id := range userIDs {
+		count, err := deleteByQuery(ctx, "apiTokens", func(tokens *firestore.CollectionRef) firestore.Query {
+			return tokens.Where("UserID", "==", id)
+		})
+		revoked += count
+		if err != nil {
+			return revoked, err
+		}
+	}
+	return revoked, nil
+}
+
+// APIトークンの最終使用日時を更新する
+func TouchAPIToken(tokenID string, usedAt time.Time) error {
+	return firebase.UpdateField("apiTokens", tokenID, "LastUsedAt", usedAt)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// 監査ログのコレクション（追記のみで、変更・削除する関数は用意しない）
+const auditEventsCollection = "auditEvents"
+
+// 監査ログに記録する（ID・日時を設定する）
+// 既存の記録を上書きしないよう、同じIDのドキュメントがある場合は失敗する
+func RecordAuditEvent(ctx context.Context, event *domain.AuditEvent) error {
+	eventID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	event.ID = eventID
+	event.CreatedAt = time.Now()
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if _, err := client.Collection(auditEventsCollection).Doc(eventID).Create(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "監査ログの保存エラー", "error", err, "action", event.Action)
+		return err
+	}
+	return nil
+}
+
+// ユーザーが操作した、またはユーザーが対象の監査ログを新しい順に最大 limit 件取得する
+func GetUserAuditEvents(ctx context.Context, userID string, limit int) ([]domain.AuditEvent, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// 自分の操作が自分を対象にする場合（ログイン・パスワードの変更など）は両方のクエリに含まれるため、IDで重複を除く
+	seen := make(map[string]bool)
+	var events []domain.AuditEvent
+	for _, field := range []string{"ActorID", "TargetID"} {
+		docs, err := client.Collection(auditEventsCollection).Where(field, "==", userID).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range auditEventsFromDocs(docs) {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+			events = append(events, event)
+		}
+	}
+	return latestAuditEvents(events, limit), nil
+}
+
+// 監査ログを新しい順に最大 limit 件取得する（action が空でない場合はその操作のみ）
+func GetAuditEvents(ctx context.Context, action string, limit int) ([]domain.AuditEvent, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// 操作で絞り込む場合は複合インデックスが不要なよう、並べ替えは取得後に行う
+	query := client.Collection(auditEventsCollection).OrderBy("CreatedAt", firestore.Desc).Limit(limit)
+	if action != "" {
+		query = client.Collection(auditEventsCollection).Where("Action", "==", action)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return latestAuditEvents(auditEventsFromDocs(docs), limit), nil
+}
+
+// ドキュメントを監査ログに変換する
+func auditEventsFromDocs(docs []*firestore.DocumentSnapshot) []domain.AuditEvent {
+	events := make([]domain.AuditEvent, 0, len(docs))
+	for _, doc := range docs {
+		var event domain.AuditEvent
+		if err := doc.DataTo(&event); err != nil {
+			slog.Error("監査ログの変換エラー", "error", err)
+			continue
+		}
+		event.ID = doc.Ref.ID
+		events = append(events, event)
+	}
+	return events
+}
+
+// 監査ログを新しい順に並べ、最大 limit 件にする
+func latestAuditEvents(events []domain.AuditEvent, limit int) []domain.AuditEvent {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.After(events[j].CreatedAt)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
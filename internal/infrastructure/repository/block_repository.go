@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+)
+
+// ユーザーをブロックする
+// 連絡先の関係と、2人の間の連絡先の申請もあわせて削除する
+func BlockUser(blockerID, blockedID string) error {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	blockRef := client.Collection("blocks").Doc(domain.BlockID(blockerID, blockedID))
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(blockRef, domain.Block{
+			ID:        blockRef.ID,
+			BlockerID: blockerID,
+			BlockedID: blockedID,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if err := tx.Delete(client.Collection("contactLinks").Doc(domain.ContactLinkID(blockerID, blockedID))); err != nil {
+			return err
+		}
+		if err := tx.Delete(client.Collection("contactRequests").Doc(domain.ContactRequestID(blockerID, blockedID))); err != nil {
+			return err
+		}
+		return tx.Delete(client.Collection("contactRequests").Doc(domain.ContactRequestID(blockedID, blockerID)))
+	})
+}
+
+// ブロックを解除する
+func UnblockUser(blockerID, blockedID string) error {
+	return firebase.DeleteData("blocks", domain.BlockID(blockerID, blockedID))
+}
+
+// ユーザーがブロックしているユーザーを新しい順に取得する
+func GetBlocksByBlocker(ctx context.Context, blockerID string) ([]domain.Block, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("blocks").Where("BlockerID", "==", blockerID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var blocks []domain.Block
+	for _, doc := range docs {
+		var block domain.Block
+		if err := doc.DataTo(&block); err != nil {
+			slog.Error("ブロックの変換エラー", "error", err)
+			continue
+		}
+		block.ID = doc.Ref.ID
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+	})
+	return blocks, nil
+}
+
+// ユーザーをブロックしているユーザーのIDを取得する
+func GetBlockerIDs(blockedID string) (map[string]bool, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("blocks").Where("BlockedID", "==", blockedID).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		var block domain.Block
+		if err := doc.DataTo(&block); err != nil {
+			slog.Error("ブロックの変換エラー", "error", err)
+			continue
+		}
+		ids[block.BlockerID] = true
+	}
+	return ids, nil
+}
+
+// 2人のユーザーのブロックの状態を取得する
+// blocking はユーザーが相手をブロックしているか、blockedBy は相手にブロックされているか
+func GetBlockState(userID, otherID string) (blocking, blockedBy bool, err error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return false, false, err
+	}
+	defer client.Close()
+
+	docs, err := client.GetAll(context.Background(), []*firestore.DocumentRef{
+		client.Collection("blocks").Doc(domain.BlockID(userID, otherID)),
+		client.Collection("blocks").Doc(domain.BlockID(otherID, userID)),
+	})
+	if err != nil {
+		return false, false, err
+	}
+	return docs[0].Exists(), docs[1].Exists(), nil
+}
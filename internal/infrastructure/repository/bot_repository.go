@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// ボットのトークンに付与するスコープ（プロフィールの変更は作成者のみ行う）
+var botTokenScopes = []string{domain.ScopeReadMessages, domain.ScopeSendMessages}
+
+// ボットを作成する
+// ボットはメールアドレスとパスワードを持たず、APIトークンでのみ操作する
+func CreateBot(ownerID, name, webhookURL string) (*domain.User, error) {
+	botID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bot := &domain.User{
+		ID:            botID,
+		Name:          name,
+		Type:          domain.UserTypeBot,
+		OwnerID:       ownerID,
+		WebhookURL:    webhookURL,
+		WebhookSecret: secret,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := firebase.AddData("users", bot, botID); err != nil {
+		slog.Error("ボットの作成エラー", "error", err, "owner_id", ownerID)
+		return nil, err
+	}
+	return bot, nil
+}
+
+// ユーザーが作成したボットを作成日時の新しい順に取得する
+func GetBotsByOwner(ownerID string) ([]domain.User, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("users").Where("OwnerID", "==", ownerID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var bots []domain.User
+	for _, doc := range docs {
+		var bot domain.User
+		if err := doc.DataTo(&bot); err != nil {
+			slog.Error("ボットの変換エラー", "error", err)
+			continue
+		}
+		bot.ID = doc.Ref.ID
+		if bot.IsBot() {
+			bots = append(bots, bot)
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool {
+		return bots[i].CreatedAt.After(bots[j].CreatedAt)
+	})
+	return bots, nil
+}
+
+// ユーザーが作成したボットを取得する（他のユーザーのボットの場合はエラーを返す）
+func GetOwnedBot(ownerID, botID string) (*domain.User, error) {
+	bot, err := GetUserByID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if !bot.IsBot() || bot.OwnerID != ownerID {
+		return nil, fmt.Errorf("ボットの作成者が一致しません")
+	}
+	return bot, nil
+}
+
+// ボットのトークンを発行し直す（発行済みのトークンはすべて失効する）
+func RegenerateBotToken(bot *domain.User) (string, error) {
+	tokens, err := GetAPITokensByUser(bot.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, token := range tokens {
+		if err := firebase.DeleteData("apiTokens", token.ID); err != nil {
+			return "", err
+		}
+	}
+	_, token, err := CreateAPIToken(bot.ID, bot.Name, botTokenScopes, time.Time{})
+	return token, err
+}
+
+// ボットのWebhookのURLを変更し、署名の秘密鍵を発行し直す
+func UpdateBotWebhook(botID, webhookURL string) (string, error) {
+	defer InvalidateUserCache(botID)
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := firebase.UpdateField("users", botID, "WebhookURL", webhookURL); err != nil {
+		return "", err
+	}
+	if err := firebase.UpdateField("users", botID, "WebhookSecret", secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ボットを削除する（チャットから外し、トークンを失効させる）
+func DeleteBot(bot *domain.User) error {
+	defer InvalidateUserCache(bot.ID)
+
+	chats, err := firebase.GetBotChats(bot.ID)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		chatID, _ := chat["id"].(string)
+		if err := firebase.RemoveChatBot(chatID, bot.ID); err != nil {
+			return err
+		}
+	}
+
+	tokens, err := GetAPITokensByUser(bot.ID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := firebase.DeleteData("apiTokens", token.ID); err != nil {
+			return err
+		}
+	}
+	return firebase.DeleteData("users", bot.ID)
+}
+
+// Webhookの署名に使う秘密鍵を生成する
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+)
+
+// ErrAlreadyContacts 既に連絡先のユーザーに申請した
+var ErrAlreadyContacts = errors.New("既に連絡先に追加されています")
+
+// ErrContactRequestNotFound 承認しようとした申請が存在しない（取り消された・処理済み）
+var ErrContactRequestNotFound = errors.New("連絡先の申請が見つかりません")
+
+// 連絡先の申請を送る
+// 相手から既に申請が届いている場合は、その申請を承認して連絡先にする（accepted が true）
+func SendContactRequest(fromID, toID string) (accepted bool, err error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	linkRef := client.Collection("contactLinks").Doc(domain.ContactLinkID(fromID, toID))
+	requestRef := client.Collection("contactRequests").Doc(domain.ContactRequestID(fromID, toID))
+	reverseRef := client.Collection("contactRequests").Doc(domain.ContactRequestID(toID, fromID))
+
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		accepted = false
+		if _, err := tx.Get(linkRef); err == nil {
+			return ErrAlreadyContacts
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		_, err := tx.Get(reverseRef)
+		if err == nil {
+			accepted = true
+			if err := tx.Delete(reverseRef); err != nil {
+				return err
+			}
+			return tx.Create(linkRef, newContactLink(fromID, toID))
+		}
+		if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		// 同じ相手への申請は上書きする（申請日時のみ更新される）
+		return tx.Set(requestRef, domain.ContactRequest{
+			ID:        requestRef.ID,
+			FromID:    fromID,
+			ToID:      toID,
+			CreatedAt: time.Now(),
+		})
+	})
+	return accepted, err
+}
+
+// 連絡先の申請を承認し、連絡先にする
+func AcceptContactRequest(requestID string) error {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	requestRef := client.Collection("contactRequests").Doc(requestID)
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(requestRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrContactRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var request domain.ContactRequest
+		if err := doc.DataTo(&request); err != nil {
+			return err
+		}
+
+		linkRef := client.Collection("contactLinks").Doc(domain.ContactLinkID(request.FromID, request.ToID))
+		if err := tx.Set(linkRef, newContactLink(request.FromID, request.ToID)); err != nil {
+			return err
+		}
+		return tx.Delete(requestRef)
+	})
+}
+
+// 連絡先の申請を取得する（見つからない場合は nil を返す）
+func GetContactRequest(requestID string) (*domain.ContactRequest, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("contactRequests").Doc(requestID).Get(context.Background())
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var request domain.ContactRequest
+	if err := doc.DataTo(&request); err != nil {
+		return nil, err
+	}
+	request.ID = doc.Ref.ID
+	return &request, nil
+}
+
+// ユーザーに届いた申請と、ユーザーが送った申請を新しい順に取得する
+func GetContactRequests(ctx context.Context, userID string) (incoming, outgoing []domain.ContactRequest, err error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("contactRequests").Where("ToID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	incoming = contactRequestsFromDocs(docs)
+
+	docs, err = client.Collection("contactRequests").Where("FromID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	outgoing = contactRequestsFromDocs(docs)
+	return incoming, outgoing, nil
+}
+
+// 連絡先の申請を削除する（拒否・取り消し）
+func DeleteContactRequest(requestID string) error {
+	return firebase.DeleteData("contactRequests", requestID)
+}
+
+// ユーザーの連絡先のユーザーIDを取得する
+func GetContactIDs(ctx context.Context, userID string) (map[string]bool, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("contactLinks").Where("UserIDs", "array-contains", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		var link domain.ContactLink
+		if err := doc.DataTo(&link); err != nil {
+			slog.Error("連絡先の変換エラー", "error", err)
+			continue
+		}
+		for _, id := range link.UserIDs {
+			if id != userID {
+				ids[id] = true
+			}
+		}
+	}
+	return ids, nil
+}
+
+// 2人のユーザーが連絡先どうしかどうか
+func AreContacts(userID, otherID string) (bool, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	_, err = client.Collection("contactLinks").Doc(domain.ContactLinkID(userID, otherID)).Get(context.Background())
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 連絡先から削除する（相手の連絡先からも削除される）
+func RemoveContact(userID, otherID string) error {
+	return firebase.DeleteData("contactLinks", domain.ContactLinkID(userID, otherID))
+}
+
+// 連絡先の関係を作成する
+func newContactLink(userID, otherID string) domain.ContactLink {
+	return domain.ContactLink{
+		ID:        domain.ContactLinkID(userID, otherID),
+		UserIDs:   []string{userID, otherID},
+		CreatedAt: time.Now(),
+	}
+}
+
+// ドキュメントを連絡先の申請に変換し、新しい順に並べる
+func contactRequestsFromDocs(docs []*firestore.DocumentSnapshot) []domain.ContactRequest {
+	var requests []domain.ContactRequest
+	for _, doc := range docs {
+		var request domain.ContactRequest
+		if err := doc.DataTo(&request); err != nil {
+			slog.Error("連絡先の申請の変換エラー", "error", err)
+			continue
+		}
+		request.ID = doc.Ref.ID
+		requests = append(requests, request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.After(requests[j].CreatedAt)
+	})
+	return requests
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// 既にチャット鍵が保存されている端末が含まれる場合のエラー
+var ErrChatKeyExists = errors.New("既にチャット鍵が保存されている端末があります")
+
+// 端末の公開鍵を登録する
+func RegisterDeviceKey(userID, deviceName, publicKey, fingerprint string) (*domain.DeviceKey, error) {
+	deviceID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &domain.DeviceKey{
+		ID:          deviceID,
+		UserID:      userID,
+		DeviceName:  deviceName,
+		PublicKey:   publicKey,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+	}
+	if err := firebase.AddData("deviceKeys", key, deviceID); err != nil {
+		slog.Error("端末の公開鍵の登録エラー", "error", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// ユーザーの端末の公開鍵を全て取得する
+func GetDeviceKeysByUser(userID string) ([]domain.DeviceKey, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("deviceKeys").Where("UserID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []domain.DeviceKey
+	for _, doc := range docs {
+		var key domain.DeviceKey
+		if err := doc.DataTo(&key); err != nil {
+			slog.Error("端末の公開鍵の変換エラー", "error", err)
+			continue
+		}
+		key.ID = doc.Ref.ID
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// 端末の公開鍵を取得する
+func GetDeviceKey(deviceID string) (*domain.DeviceKey, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	doc, err := client.Collection("deviceKeys").Doc(deviceID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var key domain.DeviceKey
+	if err := doc.DataTo(&key); err != nil {
+		return nil, err
+	}
+	key.ID = doc.Ref.ID
+	return &key, nil
+}
+
+// 端末ごとに暗号化されたチャット鍵を保存する
+// 保存済みの鍵は上書きしない（1つでも保存済みの端末があれば何も保存せず ErrChatKeyExists を返す）
+func SaveWrappedChatKeys(chatID string, keys []domain.WrappedChatKey) error {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	batch := client.Batch()
+	for _, key := range keys {
+		key.CreatedAt = time.Now()
+		ref := client.Collection("chats").Doc(chatID).Collection("keys").Doc(key.DeviceID)
+		batch.Create(ref, key)
+	}
+	if _, err := batch.Commit(ctx); err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return ErrChatKeyExists
+		}
+		slog.Error("チャット鍵の保存エラー", "error", err, "chat_id", chatID)
+		return err
+	}
+	return nil
+}
+
+// チャットのエンドツーエンド暗号化を有効にし、参加者の端末ごとのチャット鍵を保存する
+func EnableChatEncryption(chatID string, keys []domain.WrappedChatKey) error {
+	if err := SaveWrappedChatKeys(chatID, keys); err != nil {
+		return err
+	}
+	return firebase.UpdateField("chats", chatID, "encrypted", true)
+}
+
+// チャットに保存されている暗号化済みチャット鍵を全て取得する
+func GetWrappedChatKeys(chatID string) ([]domain.WrappedChatKey, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("chats").Doc(chatID).Collection("keys").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []domain.WrappedChatKey
+	for _, doc := range docs {
+		var key domain.WrappedChatKey
+		if err := doc.DataTo(&key); err != nil {
+			slog.Error("チャット鍵の変換エラー", "error", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// チャットのエンドツーエンド暗号化が有効かどうか
+func IsChatEncrypted(chatID string) (bool, error) {
+	data, err := firebase.GetData("chats", chatID)
+	if err != nil {
+		return false, err
+	}
+	encrypted, _ := data["encrypted"].(bool)
+	return encrypted, nil
+}
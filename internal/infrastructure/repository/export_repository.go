@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// データのエクスポートのコレクション
+const dataExportsCollection = "dataExports"
+
+// データのエクスポートを作成中の状態で登録する
+// 作成が終わらなかった場合も削除されるよう、期限は申請時点から設定しておく
+func CreateDataExport(ctx context.Context, userID string, expiry time.Duration) (*domain.DataExport, error) {
+	exportID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	export := &domain.DataExport{
+		ID:        exportID,
+		UserID:    userID,
+		Status:    domain.ExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(expiry),
+	}
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if _, err := client.Collection(dataExportsCollection).Doc(exportID).Create(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// データのエクスポートを取得する（見つからない場合は nil）
+func GetDataExport(ctx context.Context, exportID string) (*domain.DataExport, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection(dataExportsCollection).Doc(exportID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var export domain.DataExport
+	if err := doc.DataTo(&export); err != nil {
+		return nil, err
+	}
+	export.ID = doc.Ref.ID
+	return &export, nil
+}
+
+// ユーザーのデータのエクスポートを新しい順に取得する
+func GetUserDataExports(ctx context.Context, userID string) ([]domain.DataExport, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection(dataExportsCollection).Where("UserID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	exports := make([]domain.DataExport, 0, len(docs))
+	for _, doc := range docs {
+		var export domain.DataExport
+		if err := doc.DataTo(&export); err != nil {
+			slog.ErrorContext(ctx, "エクスポートの変換エラー", "error", err)
+			continue
+		}
+		export.ID = doc.Ref.ID
+		exports = append(exports, export)
+	}
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].CreatedAt.After(exports[j].CreatedAt)
+	})
+	return exports, nil
+}
+
+// データのエクスポートを作成済みにし、ダウンロードの期限を設定する
+func CompleteDataExport(ctx context.Context, exportID string, objectPath string, size int64, expiry time.Duration) error {
+	now := time.Now()
+	return updateDataExport(ctx, exportID, []firestore.Update{
+		{Path: "Status", Value: domain.ExportReady},
+		{Path: "ObjectPath", Value: objectPath},
+		{Path: "Size", Value: size},
+		{Path: "CompletedAt", Value: now},
+		{Path: "ExpiresAt", Value: now.Add(expiry)},
+	})
+}
+
+// データのエクスポートを失敗にする
+func FailDataExport(ctx context.Context, exportID string, reason string) error {
+	return updateDataExport(ctx, exportID, []firestore.Update{
+		{Path: "Status", Value: domain.ExportFailed},
+		{Path: "Error", Value: reason},
+		{Path: "CompletedAt", Value: time.Now()},
+	})
+}
+
+// データのエクスポートのフィールドを更新する
+func updateDataExport(ctx context.Context, exportID string, updates []firestore.Update) error {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Collection(dataExportsCollection).Doc(exportID).Update(ctx, updates)
+	return err
+}
+
+// ダウンロードの期限が過ぎたエクスポートを、保存したZIPファイルごと削除し、削除した数を返す
+func PurgeExpiredDataExports(ctx context.Context) (int, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection(dataExportsCollection).Where("ExpiresAt", "<", time.Now()).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, doc := range docs {
+		if objectPath, _ := doc.Data()["ObjectPath"].(string); objectPath != "" {
+			if _, err := firebase.DeleteObjects(ctx, objectPath); err != nil {
+				return deleted, fmt.Errorf("エクスポートのファイルの削除に失敗: %v", err)
+			}
+		}
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// ユーザーのデータのエクスポートを、保存したZIPファイルごとすべて削除する
+func DeleteUserDataExports(ctx context.Context, userID string) error {
+	if _, err := firebase.DeleteObjects(ctx, DataExportPrefix(userID)); err != nil {
+		return err
+	}
+	_, err := deleteByQuery(ctx, dataExportsCollection, func(ref *firestore.CollectionRef) firestore.Query {
+		return ref.Where("UserID", "==", userID)
+	})
+	return err
+}
+
+// ユーザーのエクスポートを保存する Storage のパスの接頭辞
+func DataExportPrefix(userID string) string {
+	return "exports/" + userID + "/"
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// 受信用Webhookを作成する
+// 戻り値のトークンはこの時点でしか得られないため、呼び出し元でURLとして一度だけ表示する
+func CreateIncomingWebhook(chatID, creatorID, name string) (*domain.IncomingWebhook, string, error) {
+	hookID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := generateIncomingWebhookToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook := &domain.IncomingWebhook{
+		ID:        hookID,
+		ChatID:    chatID,
+		CreatorID: creatorID,
+		Name:      name,
+		TokenHash: domain.HashAPIToken(token),
+		Hint:      token[:len(domain.IncomingWebhookPrefix)+4],
+		CreatedAt: time.Now(),
+	}
+	if err := firebase.AddData("incomingWebhooks", hook, hookID); err != nil {
+		slog.Error("受信用Webhookの保存エラー", "error", err, "user_id", creatorID)
+		return nil, "", err
+	}
+	return hook, token, nil
+}
+
+// ユーザーが作成した受信用Webhookを作成日時の新しい順に取得する
+func GetIncomingWebhooksByCreator(creatorID string) ([]domain.IncomingWebhook, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("incomingWebhooks").Where("CreatorID", "==", creatorID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := incomingWebhooksFromDocs(docs)
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].CreatedAt.After(hooks[j].CreatedAt)
+	})
+	return hooks, nil
+}
+
+// トークンに対応する受信用Webhookを取得する（見つからない場合は nil を返す）
+func GetIncomingWebhookByToken(ctx context.Context, token string) (*domain.IncomingWebhook, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("incomingWebhooks").Where("TokenHash", "==", domain.HashAPIToken(token)).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	hooks := incomingWebhooksFromDocs(docs)
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	return &hooks[0], nil
+}
+
+// ユーザーが作成した受信用Webhookを取得する（他のユーザーの受信用Webhookの場合はエラーを返す）
+func GetOwnedIncomingWebhook(creatorID, hookID string) (*domain.IncomingWebhook, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("incomingWebhooks").Doc(hookID).Get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var hook domain.IncomingWebhook
+	if err := doc.DataTo(&hook); err != nil {
+		return nil, err
+	}
+	hook.ID = doc.Ref.ID
+	if hook.CreatorID != creatorID {
+		return nil, fmt.Errorf("受信用Webhookの作成者が一致しません")
+	}
+	return &hook, nil
+}
+
+// 受信用Webhookのトークンを発行し直す（以前のURLは使えなくなる）
+func RegenerateIncomingWebhookToken(hookID string) (string, error) {
+	token, err := generateIncomingWebhookToken()
+	if err != nil {
+		return "", err
+	}
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	_, err = client.Collection("incomingWebhooks").Doc(hookID).Update(context.Background(), []firestore.Update{
+		{Path: "TokenHash", Value: domain.HashAPIToken(token)},
+		{Path: "Hint", Value: token[:len(domain.IncomingWebhookPrefix)+4]},
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// 受信用Webhookを削除する
+func DeleteIncomingWebhook(hookID string) error {
+	return firebase.DeleteData("incomingWebhooks", hookID)
+}
+
+// 受信用Webhookの最後に投稿された日時を更新する
+func TouchIncomingWebhook(hookID string, usedAt time.Time) error {
+	return firebase.UpdateField("incomingWebhooks", hookID, "LastUsedAt", usedAt)
+}
+
+// 受信用Webhookのトークンを生成する
+func generateIncomingWebhookToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return domain.IncomingWebhookPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// ドキュメントを受信用Webhookに変換する
+func incomingWebhooksFromDocs(docs []*firestore.DocumentSnapshot) []domain.IncomingWebhook {
+	var hooks []domain.IncomingWebhook
+	for _, doc := range docs {
+		var hook domain.IncomingWebhook
+		if err := doc.DataTo(&hook); err != nil {
+			slog.Error("受信用Webhookの変換エラー", "error", err)
+			continue
+		}
+		hook.ID = doc.Ref.ID
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
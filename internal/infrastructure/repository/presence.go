@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+)
+
+// このインスタンスでオンラインにしたユーザーのID（終了時にオフラインへ戻すため）
+var onlineUsers = struct {
+	sync.Mutex
+	ids map[string]struct{}
+}{ids: make(map[string]struct{})}
+
+// 最終接続日時を更新する間隔（リクエストごとに書き込まないようにする）
+const lastSeenInterval = 5 * time.Minute
+
+// MarkUserOnline ユーザーをオンライン状態にする
+// オンライン状態に変化がある場合と、最終接続日時が古くなった場合のみ更新する
+func MarkUserOnline(user *domain.User) error {
+	onlineUsers.Lock()
+	onlineUsers.ids[user.ID] = struct{}{}
+	onlineUsers.Unlock()
+
+	if user.IsOnline && time.Since(user.LastSeenAt) < lastSeenInterval {
+		return nil
+	}
+	return updateUserFields(user.ID, []firestore.Update{
+		{Path: "IsOnline", Value: true},
+		{Path: "LastSeenAt", Value: time.Now()},
+	})
+}
+
+// MarkUserOffline ユーザーをオフライン状態にする
+func MarkUserOffline(userID string) error {
+	onlineUsers.Lock()
+	delete(onlineUsers.ids, userID)
+	onlineUsers.Unlock()
+
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "IsOnline", Value: false},
+		{Path: "LastSeenAt", Value: time.Now()},
+	})
+}
+
+// MarkOnlineUsersOffline このインスタンスでオンラインにしたユーザーをすべてオフライン状態にする
+// サーバーの終了時に呼び出す
+func MarkOnlineUsersOffline(ctx context.Context) (int, error) {
+	onlineUsers.Lock()
+	ids := make([]string, 0, len(onlineUsers.ids))
+	for id := range onlineUsers.ids {
+		ids = append(ids, id)
+	}
+	onlineUsers.ids = make(map[string]struct{})
+	onlineUsers.Unlock()
+
+	err := firebase.UpdateFieldBatch(ctx, "users", ids, "IsOnline", false)
+	for _, id := range ids {
+		InvalidateUserCache(id)
+	}
+	return len(ids), err
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// 通報を保存する（ID・状態・日時を設定する）
+func CreateReport(report *domain.Report) error {
+	reportID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	report.ID = reportID
+	report.Status = domain.ReportStatusOpen
+	report.CreatedAt = now
+	report.UpdatedAt = now
+	if err := firebase.AddData("reports", report, reportID); err != nil {
+		slog.Error("通報の保存エラー", "error", err, "reporter_id", report.ReporterID)
+		return err
+	}
+	return nil
+}
+
+// 通報を取得する（見つからない場合は nil を返す）
+func GetReport(reportID string) (*domain.Report, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("reports").Doc(reportID).Get(context.Background())
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var report domain.Report
+	if err := doc.DataTo(&report); err != nil {
+		return nil, err
+	}
+	report.ID = doc.Ref.ID
+	return &report, nil
+}
+
+// 指定した状態の通報を古い順に取得する（対応待ちの順）
+func GetReportsByStatus(statuses ...string) ([]domain.Report, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("reports").Where("Status", "in", statuses).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var reports []domain.Report
+	for _, doc := range docs {
+		var report domain.Report
+		if err := doc.DataTo(&report); err != nil {
+			slog.Error("通報の変換エラー", "error", err)
+			continue
+		}
+		report.ID = doc.Ref.ID
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.Before(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// 通報への操作を記録し、通報の状態を更新する
+// 記録は追記のみで、通報の状態と同時に保存する
+func RecordModerationAction(action *domain.ModerationAction, reportStatus string) error {
+	actionID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	action.ID = actionID
+	action.CreatedAt = time.Now()
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	reportRef := client.Collection("reports").Doc(action.ReportID)
+	actionRef := client.Collection("moderationActions").Doc(actionID)
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Update(reportRef, []firestore.Update{
+			{Path: "Status", Value: reportStatus},
+			{Path: "UpdatedAt", Value: action.CreatedAt},
+		}); err != nil {
+			return err
+		}
+		return tx.Create(actionRef, action)
+	})
+}
+
+// 通報への操作の記録を古い順に取得する
+func GetModerationActions(reportID string) ([]domain.ModerationAction, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("moderationActions").Where("ReportID", "==", reportID).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var actions []domain.ModerationAction
+	for _, doc := range docs {
+		var action domain.ModerationAction
+		if err := doc.DataTo(&action); err != nil {
+			slog.Error("モデレーションの記録の変換エラー", "error", err)
+			continue
+		}
+		action.ID = doc.Ref.ID
+		actions = append(actions, action)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].CreatedAt.Before(actions[j].CreatedAt)
+	})
+	return actions, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+// CountActiveSessions 無操作による有効期限が切れていないセッションの数を数える
+func CountActiveSessions(ctx context.Context) (int, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	count, err := countDocuments(ctx, client.Collection("sessions").Where("IdleExpiredAt", ">", time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("セッション数の集計に失敗: %v", err)
+	}
+	return count, nil
+}
+
+// ユーザーのセッションを作成日時の新しい順に取得する（有効期限が切れたものを含む）
+func GetUserSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("sessions").Where("UserID", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]domain.Session, 0, len(docs))
+	for _, doc := range docs {
+		var session domain.Session
+		if err := doc.DataTo(&session); err != nil {
+			slog.ErrorContext(ctx, "セッションの変換エラー", "error", err)
+			continue
+		}
+		session.ID = doc.Ref.ID
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// ユーザーのセッションをすべて削除し、削除した数を返す（強制ログアウト）
+func DeleteUserSessions(ctx context.Context, userID string) (int, error) {
+	return deleteByQuery(ctx, "sessions", func(sessions *firestore.CollectionRef) firestore.Query {
+		return sessions.Where("UserID", "==", userID)
+	})
+}
+
+// 有効期限が切れたセッションを削除し、削除した数を返す
+// 無操作による有効期限は絶対期限を超えないため、無操作による有効期限のみで判定する
+func PurgeExpiredSessions(ctx context.Context) (int, error) {
+	return deleteByQuery(ctx, "sessions", func(sessions *firestore.CollectionRef) firestore.Query {
+		return sessions.Where("IdleExpiredAt", "<", time.Now())
+	})
+}
+
+// コレクションのクエリに一致するドキュメントをまとめて削除し、削除した数を返す
+func deleteByQuery(ctx context.Context, collection string, query func(*firestore.CollectionRef) firestore.Query) (int, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	docs, err := query(client.Collection(collection)).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	writer := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range docs {
+		job, err := writer.Delete(doc.Ref)
+		if err != nil {
+			writer.End()
+			return 0, fmt.Errorf("%sの削除の登録に失敗: %v", collection, err)
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
+
+	deleted := 0
+	for _, job := range jobs {
+		if _, err := job.Results(); err == nil {
+			deleted++
+		}
+	}
+	if deleted < len(jobs) {
+		return deleted, fmt.Errorf("%sの%d件のドキュメントの削除に失敗しました", collection, len(jobs)-deleted)
+	}
+	return deleted, nil
+}
+
+// クエリに一致するドキュメントの数を数える（ドキュメントは読み込まない）
+func countDocuments(ctx context.Context, query firestore.Query) (int, error) {
+	result, err := query.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := result["count"].(*pb.Value)
+	if !ok {
+		return 0, fmt.Errorf("集計結果が不正です")
+	}
+	return int(value.GetIntegerValue()), nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+)
+
+// サービス全体の件数と、直近の日ごとの件数を集計する
+// メッセージはすべてのチャットの messages サブコレクションをまとめて数える
+// （created_at にコレクショングループのインデックスが必要）
+func GetStats(ctx context.Context, days int) (*domain.Stats, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	users := client.Collection("users").Query
+	chats := client.Collection("chats").Query
+	messages := client.CollectionGroup("messages").Query
+
+	var stats domain.Stats
+	if stats.Users, err = countDocuments(ctx, users); err != nil {
+		return nil, err
+	}
+	if stats.Chats, err = countDocuments(ctx, chats); err != nil {
+		return nil, err
+	}
+	if stats.Messages, err = countDocuments(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := 0; i < days; i++ {
+		start := today.AddDate(0, 0, -i)
+		end := start.AddDate(0, 0, 1)
+		daily := domain.DailyStats{Date: start}
+		if daily.Users, err = countDocuments(ctx, between(users, "CreatedAt", start, end)); err != nil {
+			return nil, err
+		}
+		if daily.Chats, err = countDocuments(ctx, between(chats, "createdAt", start, end)); err != nil {
+			return nil, err
+		}
+		if daily.Messages, err = countDocuments(ctx, between(messages, "created_at", start, end)); err != nil {
+			return nil, err
+		}
+		stats.Daily = append(stats.Daily, daily)
+	}
+	return &stats, nil
+}
+
+// 日時のフィールドが start 以上 end 未満のドキュメントに絞り込む
+func between(query firestore.Query, field string, start, end time.Time) firestore.Query {
+	return query.Where(field, ">=", start).Where(field, "<", end)
+}
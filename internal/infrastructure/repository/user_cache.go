@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"security_chat_app/internal/domain"
+)
+
+// ユーザー情報のキャッシュの有効期間
+// 破棄はインスタンスごとのため、複数台で動かす場合は他のインスタンスでの変更がこの期間だけ遅れて反映される
+// セッションとAPIトークンの検証は ReloadUser で読み直すため、停止や資格情報の変更は遅れない
+const userCacheTTL = 30 * time.Second
+
+// キャッシュされたユーザー情報
+type cachedUser struct {
+	user     domain.User
+	cachedAt time.Time
+}
+
+// ユーザーIDをキーとしたユーザー情報のキャッシュ
+var userCache = struct {
+	sync.RWMutex
+	items map[string]cachedUser
+}{items: make(map[string]cachedUser)}
+
+// キャッシュからユーザー情報を取得する（呼び出し側で変更できるようコピーを返す）
+func getCachedUser(userID string) (*domain.User, bool) {
+	userCache.RLock()
+	item, ok := userCache.items[userID]
+	userCache.RUnlock()
+	if !ok || time.Since(item.cachedAt) > userCacheTTL {
+		return nil, false
+	}
+	user := item.user
+	return &user, true
+}
+
+// ユーザー情報をキャッシュに保存する
+func setCachedUser(user *domain.User) {
+	if user == nil {
+		return
+	}
+	userCache.Lock()
+	userCache.items[user.ID] = cachedUser{user: *user, cachedAt: time.Now()}
+	userCache.Unlock()
+}
+
+// InvalidateUserCache ユーザー情報のキャッシュを破棄する
+func InvalidateUserCache(userID string) {
+	userCache.Lock()
+	delete(userCache.items, userID)
+	userCache.Unlock()
+}
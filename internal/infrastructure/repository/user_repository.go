@@ -2,17 +2,50 @@ package repository
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"sort"
+	"time"
 
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+
+	"cloud.google.com/go/firestore"
 )
 
-// メールアドレスでユーザーを検索する
+// ユーザーを作成する（パスワードはハッシュ化して保存する）
+func CreateUser(name, email, password string) (*domain.User, error) {
+	hashedPassword, err := uuid.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		ID:        userID,
+		Name:      name,
+		Email:     domain.NormalizeEmail(email),
+		Password:  hashedPassword,
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsOnline:  false,
+	}
+	if err := firebase.AddData("users", user, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// メールアドレスでユーザーを検索する（大文字・小文字は区別しない）
 func GetUserByEmail(email string) (*domain.User, error) {
+	email = domain.NormalizeEmail(email)
 	client, err := firebase.InitFirebase()
 	if err != nil {
-		log.Printf("Firebase初期化エラー: %v", err)
+		slog.Error("Firebase初期化エラー", "error", err)
 		return nil, err
 	}
 	defer client.Close()
@@ -21,19 +54,19 @@ func GetUserByEmail(email string) (*domain.User, error) {
 	query := client.Collection("users").Where("Email", "==", email)
 	docs, err := query.Documents(ctx).GetAll()
 	if err != nil {
-		log.Printf("Firestoreクエリエラー: %v", err)
+		slog.Error("Firestoreクエリエラー", "error", err)
 		return nil, err
 	}
 
 	// ユーザーが見つからない場合
 	if len(docs) == 0 {
-		log.Printf("ユーザーが見つかりません: email=%s", email)
+		slog.Debug("ユーザーが見つかりません", "email", email)
 		return nil, nil
 	}
 
 	var user domain.User
 	if err := docs[0].DataTo(&user); err != nil {
-		log.Printf("ユーザーデータ変換エラー: %v", err)
+		slog.Error("ユーザーデータ変換エラー", "error", err)
 		return nil, err
 	}
 
@@ -42,8 +75,17 @@ func GetUserByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
-// ユーザーIDからユーザー情報を取得する
+// ユーザーIDからユーザー情報を取得する（短時間キャッシュされる）
 func GetUserByID(userID string) (*domain.User, error) {
+	if user, ok := getCachedUser(userID); ok {
+		return user, nil
+	}
+	return ReloadUser(userID)
+}
+
+// ReloadUser キャッシュを使わずにユーザー情報を読み込み、キャッシュを更新する
+// セッションやトークンの検証など、停止や資格情報の変更を即座に反映する必要がある場合に使う
+func ReloadUser(userID string) (*domain.User, error) {
 	client, err := firebase.InitFirebase()
 	if err != nil {
 		return nil, err
@@ -61,6 +103,173 @@ func GetUserByID(userID string) (*domain.User, error) {
 		return nil, err
 	}
 	user.ID = doc.Ref.ID
+	setCachedUser(&user)
 
 	return &user, nil
 }
+
+// ユーザーの特定フィールドを更新し、キャッシュを破棄する
+func UpdateUserField(userID string, field string, value interface{}) error {
+	defer InvalidateUserCache(userID)
+	return firebase.UpdateField("users", userID, field, value)
+}
+
+// ユーザーの複数のフィールドをまとめて更新し、キャッシュを破棄する
+func updateUserFields(userID string, updates []firestore.Update) error {
+	defer InvalidateUserCache(userID)
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Collection("users").Doc(userID).Update(context.Background(), updates)
+	return err
+}
+
+// ユーザーの認証情報（パスワード・メールアドレス）を更新する
+// 認証情報のバージョンを進めることで、既存のセッションはすべて無効になる
+func UpdateUserCredential(userID string, field string, value interface{}) error {
+	defer InvalidateUserCache(userID)
+
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	updates := []firestore.Update{
+		{Path: field, Value: value},
+		{Path: "CredentialVersion", Value: firestore.Increment(1)},
+		{Path: "UpdatedAt", Value: time.Now()},
+	}
+	if field == "Password" {
+		// パスワードを変更したので、管理者によるパスワードの再設定の要求は解除する
+		updates = append(updates, firestore.Update{Path: "PasswordResetRequired", Value: false})
+	}
+
+	ctx := context.Background()
+	_, err = client.Collection("users").Doc(userID).Update(ctx, updates)
+	if err != nil {
+		slog.Error("認証情報の更新エラー", "error", err, "user_id", userID, "field", field)
+		return err
+	}
+	return nil
+}
+
+// チャットをミュートする
+func MuteChat(userID, chatID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "MutedChatIDs", Value: firestore.ArrayUnion(chatID)},
+	})
+}
+
+// チャットのミュートを解除する
+func UnmuteChat(userID, chatID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "MutedChatIDs", Value: firestore.ArrayRemove(chatID)},
+	})
+}
+
+// アカウントを停止する（ログイン・APIの利用ができなくなり、オフラインにする）
+func SuspendUser(userID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "Suspended", Value: true},
+		{Path: "SuspendedAt", Value: time.Now()},
+		{Path: "IsOnline", Value: false},
+	})
+}
+
+// アカウントの停止を解除する
+func UnsuspendUser(userID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "Suspended", Value: false},
+		{Path: "SuspendedAt", Value: firestore.Delete},
+	})
+}
+
+// パスワードの再設定を要求する
+// 認証情報のバージョンを進めて既存のセッションをすべて無効にし、再設定するまでログインできなくする
+func RequirePasswordReset(userID string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "PasswordResetRequired", Value: true},
+		{Path: "CredentialVersion", Value: firestore.Increment(1)},
+		{Path: "IsOnline", Value: false},
+	})
+}
+
+// ユーザーの権限を変更する
+// 権限の変更後は新しいセッションでログインし直させるため、認証情報のバージョンを進めて既存のセッションをすべて無効にする
+func UpdateUserRole(userID string, role string) error {
+	return updateUserFields(userID, []firestore.Update{
+		{Path: "Role", Value: role},
+		{Path: "CredentialVersion", Value: firestore.Increment(1)},
+		{Path: "IsOnline", Value: false},
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+}
+
+// すべてのユーザーを登録日の新しい順に取得する
+func GetAllUsers() ([]domain.User, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("users").Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]domain.User, 0, len(docs))
+	for _, doc := range docs {
+		var user domain.User
+		if err := doc.DataTo(&user); err != nil {
+			slog.Error("ユーザーの変換エラー", "error", err, "user_id", doc.Ref.ID)
+			continue
+		}
+		user.ID = doc.Ref.ID
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+	return users, nil
+}
+
+// 保存されているメールアドレスを小文字にする（データ移行用）
+// 小文字にすると他のアカウントと重複する場合は変更せず、手動で対応できるようログに残す
+func NormalizeUserEmails(ctx context.Context) (int, error) {
+	users, err := GetAllUsers()
+	if err != nil {
+		return 0, err
+	}
+	owners := make(map[string]string, len(users))
+	for _, user := range users {
+		if user.Email == domain.NormalizeEmail(user.Email) {
+			owners[user.Email] = user.ID
+		}
+	}
+
+	count := 0
+	for _, user := range users {
+		email := domain.NormalizeEmail(user.Email)
+		if email == user.Email {
+			continue
+		}
+		if owner, ok := owners[email]; ok {
+			slog.WarnContext(ctx, "小文字にしたメールアドレスが他のアカウントと重複するため変更しません", "user_id", user.ID, "duplicate_user_id", owner)
+			continue
+		}
+		if err := updateUserFields(user.ID, []firestore.Update{
+			{Path: "Email", Value: email},
+			{Path: "UpdatedAt", Value: time.Now()},
+		}); err != nil {
+			return count, err
+		}
+		owners[email] = user.ID
+		count++
+	}
+	return count, nil
+}
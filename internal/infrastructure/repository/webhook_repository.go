@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// 配信履歴を残す件数（Webhookごと）
+const webhookDeliveryLogSize = 20
+
+// 送信用Webhookを登録する
+func CreateWebhook(ownerID, chatID, url string, events []string) (*domain.Webhook, error) {
+	webhookID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &domain.Webhook{
+		ID:        webhookID,
+		OwnerID:   ownerID,
+		ChatID:    chatID,
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now(),
+	}
+	if err := firebase.AddData("webhooks", hook, webhookID); err != nil {
+		slog.Error("Webhookの保存エラー", "error", err, "user_id", ownerID)
+		return nil, err
+	}
+	return hook, nil
+}
+
+// ユーザーが登録したWebhookを登録日時の新しい順に取得する
+func GetWebhooksByOwner(ownerID string) ([]domain.Webhook, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("webhooks").Where("OwnerID", "==", ownerID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := webhooksFromDocs(docs)
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].CreatedAt.After(hooks[j].CreatedAt)
+	})
+	return hooks, nil
+}
+
+// チャットのイベントを送信するWebhookを取得する
+// チャットの参加者が登録した、停止されていないWebhookのうち、チャットとイベントが一致するもの
+func GetWebhooksForEvent(ctx context.Context, participants []string, chatID, event string) ([]domain.Webhook, error) {
+	if len(participants) == 0 {
+		return nil, nil
+	}
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("webhooks").Where("OwnerID", "in", participants).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []domain.Webhook
+	for _, hook := range webhooksFromDocs(docs) {
+		if hook.Subscribes(chatID, event) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+// ユーザーが登録したWebhookを取得する（他のユーザーのWebhookの場合はエラーを返す）
+func GetOwnedWebhook(ownerID, webhookID string) (*domain.Webhook, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("webhooks").Doc(webhookID).Get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var hook domain.Webhook
+	if err := doc.DataTo(&hook); err != nil {
+		return nil, err
+	}
+	hook.ID = doc.Ref.ID
+	if hook.OwnerID != ownerID {
+		return nil, fmt.Errorf("Webhookの所有者が一致しません")
+	}
+	return &hook, nil
+}
+
+// 停止したWebhookを再開する（連続失敗回数もリセットする）
+func EnableWebhook(webhookID string) error {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Collection("webhooks").Doc(webhookID).Update(context.Background(), []firestore.Update{
+		{Path: "Disabled", Value: false},
+		{Path: "DisabledReason", Value: ""},
+		{Path: "FailureCount", Value: 0},
+	})
+	return err
+}
+
+// Webhookと配信履歴を削除する
+func DeleteWebhook(webhookID string) error {
+	deliveries, err := GetWebhookDeliveries(webhookID)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if err := firebase.DeleteData("webhookDeliveries", delivery.ID); err != nil {
+			return err
+		}
+	}
+	return firebase.DeleteData("webhooks", webhookID)
+}
+
+// 配信の結果を記録し、Webhookの最後の状態を更新する
+// 連続して domain.WebhookFailureLimit 回失敗した場合はWebhookを停止する
+// Webhookが停止・削除され、以降の再送が不要な場合は true を返す
+func RecordWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (disabled bool, err error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	deliveryID, err := uuid.GenerateUUID()
+	if err != nil {
+		return false, err
+	}
+	delivery.ID = deliveryID
+
+	// 同じWebhookへの配信が並行して記録されても失敗回数を取りこぼさないよう、トランザクションで更新する
+	hookRef := client.Collection("webhooks").Doc(delivery.WebhookID)
+	var ownerID string
+	var disabledNow bool
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		disabled, disabledNow = false, false
+		doc, err := tx.Get(hookRef)
+		if err != nil {
+			return err
+		}
+		var hook domain.Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			return err
+		}
+		ownerID = hook.OwnerID
+
+		disabledNow = hook.RecordDelivery(delivery)
+		disabled = hook.Disabled && !delivery.Succeeded()
+		updates := []firestore.Update{
+			{Path: "LastStatus", Value: hook.LastStatus},
+			{Path: "LastError", Value: hook.LastError},
+			{Path: "LastDeliveryAt", Value: hook.LastDeliveryAt},
+			{Path: "FailureCount", Value: hook.FailureCount},
+		}
+		if disabledNow {
+			updates = append(updates,
+				firestore.Update{Path: "Disabled", Value: true},
+				firestore.Update{Path: "DisabledReason", Value: hook.DisabledReason},
+			)
+		}
+		if err := tx.Update(hookRef, updates); err != nil {
+			return err
+		}
+		return tx.Set(client.Collection("webhookDeliveries").Doc(deliveryID), delivery)
+	})
+	if status.Code(err) == codes.NotFound {
+		// 配信中にWebhookが削除された
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if disabledNow {
+		slog.WarnContext(ctx, "連続して配信に失敗したWebhookを停止", "webhook_id", delivery.WebhookID, "user_id", ownerID)
+	}
+	pruneWebhookDeliveries(ctx, client, delivery.WebhookID)
+	return disabled, nil
+}
+
+// Webhookの配信履歴を新しい順に取得する
+func GetWebhookDeliveries(webhookID string) ([]domain.WebhookDelivery, error) {
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	docs, err := client.Collection("webhookDeliveries").Where("WebhookID", "==", webhookID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return webhookDeliveriesFromDocs(docs), nil
+}
+
+// 古い配信履歴を削除する（失敗しても配信には影響しないため、ログのみ残す）
+func pruneWebhookDeliveries(ctx context.Context, client *firestore.Client, webhookID string) {
+	docs, err := client.Collection("webhookDeliveries").Where("WebhookID", "==", webhookID).Documents(ctx).GetAll()
+	if err != nil {
+		slog.WarnContext(ctx, "Webhookの配信履歴の取得に失敗", "error", err, "webhook_id", webhookID)
+		return
+	}
+	deliveries := webhookDeliveriesFromDocs(docs)
+	if len(deliveries) <= webhookDeliveryLogSize {
+		return
+	}
+	for _, delivery := range deliveries[webhookDeliveryLogSize:] {
+		if _, err := client.Collection("webhookDeliveries").Doc(delivery.ID).Delete(ctx); err != nil {
+			slog.WarnContext(ctx, "Webhookの配信履歴の削除に失敗", "error", err, "delivery_id", delivery.ID)
+		}
+	}
+}
+
+// ドキュメントをWebhookに変換する
+func webhooksFromDocs(docs []*firestore.DocumentSnapshot) []domain.Webhook {
+	var hooks []domain.Webhook
+	for _, doc := range docs {
+		var hook domain.Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			slog.Error("Webhookの変換エラー", "error", err)
+			continue
+		}
+		hook.ID = doc.Ref.ID
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// ドキュメントを配信履歴に変換し、新しい順に並べる
+func webhookDeliveriesFromDocs(docs []*firestore.DocumentSnapshot) []domain.WebhookDelivery {
+	var deliveries []domain.WebhookDelivery
+	for _, doc := range docs {
+		var delivery domain.WebhookDelivery
+		if err := doc.DataTo(&delivery); err != nil {
+			slog.Error("Webhookの配信履歴の変換エラー", "error", err)
+			continue
+		}
+		delivery.ID = doc.Ref.ID
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries
+}
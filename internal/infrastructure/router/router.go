@@ -1,6 +1,7 @@
 package router
 
 import (
+	"io/fs"
 	"net/http"
 
 	"security_chat_app/internal/domain"
@@ -9,28 +10,94 @@ import (
 )
 
 // ルーティングの設定
-func SetupRouter(chatUsecase domain.ChatUsecase) *http.ServeMux {
-	rootDir := "internal/web/"
+// assets にはテンプレートと静的ファイル（css / js / images）を含むファイルシステムを渡す
+func SetupRouter(chatUsecase domain.ChatUsecase, assets fs.FS) http.Handler {
 	httpRouter := http.NewServeMux()
-	// 静的ファイル (CSS/JS)
-	httpRouter.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir(rootDir+"css"))))
-	httpRouter.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.Dir(rootDir+"js"))))
-	httpRouter.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir(rootDir+"images"))))
+	// 静的ファイル (CSS/JS/画像)
+	for _, dir := range []string{"css", "js", "images"} {
+		sub, err := fs.Sub(assets, dir)
+		if err != nil {
+			return nil
+		}
+		prefix := "/" + dir + "/"
+		httpRouter.Handle(prefix, http.StripPrefix(prefix, http.FileServerFS(sub)))
+	}
+	// ヘルスチェック（セッションのミドルウェアを通さない）
+	httpRouter.HandleFunc("/healthz", handler.HealthzHandler)
+	httpRouter.HandleFunc("/readyz", handler.ReadyzHandler)
+	httpRouter.HandleFunc("/version", handler.VersionHandler)
+	httpRouter.Handle("/metrics", handler.MetricsHandler())
 	// ルーティング
-	httpRouter.Handle("/", middleware.Middleware(http.HandlerFunc(handler.SearchHandler)))
-	httpRouter.Handle("/login", http.HandlerFunc(handler.LoginHandler))
-	httpRouter.Handle("/logout", http.HandlerFunc(handler.LogoutHandler))
-	httpRouter.Handle("/signup", http.HandlerFunc(handler.SignupHandler))
-	httpRouter.Handle("/signup/confirm", http.HandlerFunc(handler.SignupConfirmHandler))
-	httpRouter.Handle("/reset-password", http.HandlerFunc(handler.ResetPasswordHandler))
-	httpRouter.Handle("/profile", middleware.Middleware(http.HandlerFunc(handler.ProfileHandler)))
-	httpRouter.Handle("/profile/", middleware.Middleware(http.HandlerFunc(handler.ProfileHandler)))
-	httpRouter.Handle("/profile/icon", middleware.Middleware(http.HandlerFunc(handler.ProfileIconHandler)))
-	httpRouter.Handle("/chat/", middleware.Middleware(http.HandlerFunc(handler.StartChatHandler)))
-	httpRouter.Handle("/chat", middleware.Middleware(http.HandlerFunc(handler.ChatHandler)))
-	httpRouter.Handle("/search", middleware.Middleware(http.HandlerFunc(handler.SearchHandler)))
-	httpRouter.Handle("/settings", middleware.Middleware(http.HandlerFunc(handler.SettingsHandler)))
-	httpRouter.Handle("/settings/username", middleware.Middleware(http.HandlerFunc(handler.SettingsHandler)))
+	// チャット・検索・プロフィールの画面はAPIトークンでも呼び出せる（それ以外の画面はセッションのみ）
+	readScope := middleware.TokenScope{Read: domain.ScopeReadMessages}
+	chatScope := middleware.TokenScope{Read: domain.ScopeReadMessages, Write: domain.ScopeSendMessages}
+	profileScope := middleware.TokenScope{Write: domain.ScopeManageProfile}
+	httpRouter.Handle("/", middleware.ScopedMiddleware(readScope, middleware.AppHandler(handler.SearchHandler)))
+	httpRouter.Handle("/login", middleware.AppHandler(handler.LoginHandler))
+	httpRouter.Handle("/logout", middleware.AppHandler(handler.LogoutHandler))
+	httpRouter.Handle("/signup", middleware.AppHandler(handler.SignupHandler))
+	httpRouter.Handle("/signup/confirm", middleware.AppHandler(handler.SignupConfirmHandler))
+	httpRouter.Handle("/reset-password", middleware.AppHandler(handler.ResetPasswordHandler))
+	httpRouter.Handle("/profile", middleware.ScopedMiddleware(readScope, middleware.AppHandler(handler.ProfileHandler)))
+	httpRouter.Handle("/profile/", middleware.ScopedMiddleware(readScope, middleware.AppHandler(handler.ProfileHandler)))
+	httpRouter.Handle("/profile/icon", middleware.ScopedMiddleware(profileScope, middleware.AppHandler(handler.ProfileIconHandler)))
+	httpRouter.Handle("/chat/", middleware.ScopedMiddleware(chatScope, middleware.AppHandler(handler.StartChatHandler)))
+	httpRouter.Handle("/chat", middleware.ScopedMiddleware(chatScope, middleware.AppHandler(handler.ChatHandler)))
+	httpRouter.Handle("/chat/bots", middleware.Middleware(middleware.AppHandler(handler.ChatBotHandler)))
+	httpRouter.Handle("/chat/keys", middleware.Middleware(http.HandlerFunc(handler.ChatKeyHandler)))
+	httpRouter.Handle("/keys/devices", middleware.Middleware(http.HandlerFunc(handler.DeviceKeyHandler)))
+	httpRouter.Handle("/search", middleware.ScopedMiddleware(readScope, middleware.AppHandler(handler.SearchHandler)))
+	httpRouter.Handle("/contacts", middleware.Middleware(handler.ContactsHandler(chatUsecase)))
+	httpRouter.Handle("/contacts/requests", middleware.Middleware(middleware.AppHandler(handler.ContactRequestHandler)))
+	httpRouter.Handle("/contacts/requests/accept", middleware.Middleware(middleware.AppHandler(handler.ContactRequestHandler)))
+	httpRouter.Handle("/contacts/requests/decline", middleware.Middleware(middleware.AppHandler(handler.ContactRequestHandler)))
+	httpRouter.Handle("/contacts/requests/cancel", middleware.Middleware(middleware.AppHandler(handler.ContactRequestHandler)))
+	httpRouter.Handle("/contacts/remove", middleware.Middleware(middleware.AppHandler(handler.ContactRequestHandler)))
+	httpRouter.Handle("/settings", middleware.Middleware(middleware.AppHandler(handler.SettingsHandler)))
+	httpRouter.Handle("/settings/username", middleware.Middleware(middleware.AppHandler(handler.SettingsHandler)))
+	httpRouter.Handle("/settings/audit", middleware.Middleware(middleware.AppHandler(handler.AuditSettingsHandler)))
+	httpRouter.Handle("/settings/delete", middleware.Middleware(middleware.AppHandler(handler.AccountDeletionHandler)))
+	httpRouter.Handle("/settings/delete/cancel", middleware.Middleware(middleware.AppHandler(handler.AccountDeletionHandler)))
+	httpRouter.Handle("/settings/export", middleware.Middleware(middleware.AppHandler(handler.DataExportHandler)))
+	httpRouter.Handle("/settings/export/download", middleware.Middleware(middleware.AppHandler(handler.DataExportHandler)))
+	httpRouter.Handle("/settings/privacy", middleware.Middleware(middleware.AppHandler(handler.PrivacySettingsHandler)))
+	httpRouter.Handle("/settings/blocks", middleware.Middleware(middleware.AppHandler(handler.BlockSettingsHandler)))
+	httpRouter.Handle("/settings/blocks/remove", middleware.Middleware(middleware.AppHandler(handler.BlockSettingsHandler)))
+	httpRouter.Handle("/settings/mutes", middleware.Middleware(middleware.AppHandler(handler.BlockSettingsHandler)))
+	httpRouter.Handle("/settings/mutes/remove", middleware.Middleware(middleware.AppHandler(handler.BlockSettingsHandler)))
+	httpRouter.Handle("/settings/tokens", middleware.Middleware(middleware.AppHandler(handler.APITokenSettingsHandler)))
+	httpRouter.Handle("/settings/tokens/revoke", middleware.Middleware(middleware.AppHandler(handler.APITokenSettingsHandler)))
+	httpRouter.Handle("/settings/bots", middleware.Middleware(middleware.AppHandler(handler.BotSettingsHandler)))
+	httpRouter.Handle("/settings/bots/token", middleware.Middleware(middleware.AppHandler(handler.BotSettingsHandler)))
+	httpRouter.Handle("/settings/bots/webhook", middleware.Middleware(middleware.AppHandler(handler.BotSettingsHandler)))
+	httpRouter.Handle("/settings/bots/delete", middleware.Middleware(middleware.AppHandler(handler.BotSettingsHandler)))
+	httpRouter.Handle("/settings/webhooks", middleware.Middleware(middleware.AppHandler(handler.WebhookSettingsHandler)))
+	httpRouter.Handle("/settings/webhooks/enable", middleware.Middleware(middleware.AppHandler(handler.WebhookSettingsHandler)))
+	httpRouter.Handle("/settings/webhooks/delete", middleware.Middleware(middleware.AppHandler(handler.WebhookSettingsHandler)))
+	httpRouter.Handle("/settings/incoming-webhooks", middleware.Middleware(middleware.AppHandler(handler.IncomingWebhookSettingsHandler)))
+	httpRouter.Handle("/settings/incoming-webhooks/regenerate", middleware.Middleware(middleware.AppHandler(handler.IncomingWebhookSettingsHandler)))
+	httpRouter.Handle("/settings/incoming-webhooks/delete", middleware.Middleware(middleware.AppHandler(handler.IncomingWebhookSettingsHandler)))
+	httpRouter.Handle("/report", middleware.Middleware(middleware.AppHandler(handler.ReportHandler)))
+	httpRouter.Handle("/admin", middleware.Middleware(middleware.RequireAdmin(handler.AdminHandler)))
+	httpRouter.Handle("/admin/users", middleware.Middleware(middleware.RequireAdmin(handler.AdminUsersHandler)))
+	httpRouter.Handle("/admin/users/", middleware.Middleware(middleware.RequireAdmin(handler.AdminUserActionHandler)))
+	httpRouter.Handle("/admin/audit", middleware.Middleware(middleware.RequireAdmin(handler.AdminAuditHandler)))
+	httpRouter.Handle("/admin/reports", middleware.Middleware(middleware.RequireAdmin(handler.AdminReportsHandler)))
+	httpRouter.Handle("/admin/reports/", middleware.Middleware(middleware.RequireAdmin(handler.AdminReportHandler)))
+	httpRouter.Handle(middleware.CSPReportPath, http.HandlerFunc(handler.CSPReportHandler))
 
-	return httpRouter
+	// JSON API（画面のセッションのミドルウェアではなく、401をJSONで返す APIAuth を通す）
+	for _, route := range handler.APIRoutes() {
+		var h http.Handler = route.Handler
+		if !route.Public {
+			h = middleware.APIAuth(middleware.RequireScope(route.Scope, h))
+		}
+		httpRouter.Handle(route.APIPattern(), h)
+	}
+	httpRouter.HandleFunc("GET "+handler.APIPrefix+handler.OpenAPIPath, handler.OpenAPIHandler)
+	httpRouter.Handle(handler.APIPrefix+"/", handler.APINotFoundHandler(httpRouter))
+
+	// すべてのレスポンスにセキュリティヘッダーを付与し、panicからは回復する
+	// アクセスログにはリクエストIDを含めるため、リクエストIDの割り当ての直後に記録する
+	return middleware.RequestID(middleware.AccessLog(middleware.Metrics(middleware.SecurityHeaders(middleware.Recover(httpRouter)))))
 }
@@ -1,14 +1,78 @@
 package router
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"security_chat_app/internal/config"
 	"security_chat_app/internal/domain"
 )
 
 // メインサーバーを起動する
-func StartMainServer(chatUsecase domain.ChatUsecase) error {
-	mux := SetupRouter(chatUsecase)
-	return http.ListenAndServe(":"+config.Config.Port, mux)
+func StartMainServer(chatUsecase domain.ChatUsecase, assets fs.FS) error {
+	srv := NewServer(":"+config.Config.Port, SetupRouter(chatUsecase, assets))
+	return Serve(srv)
+}
+
+// NewServer 設定のタイムアウトとヘッダーサイズでHTTPサーバーを作成する
+func NewServer(addr string, handler http.Handler) *http.Server {
+	// 終了処理の開始時にキャンセルされるコンテキスト
+	// 長時間接続するリクエスト（リアルタイム通信など）は r.Context() の終了で接続を閉じる
+	baseCtx, cancel := context.WithCancel(context.Background())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       config.Config.ReadTimeout,
+		ReadHeaderTimeout: config.Config.ReadHeaderTimeout,
+		WriteTimeout:      config.Config.WriteTimeout,
+		IdleTimeout:       config.Config.IdleTimeout,
+		MaxHeaderBytes:    config.Config.MaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	srv.RegisterOnShutdown(cancel)
+	return srv
+}
+
+// Serve サーバーを起動し、SIGTERM/SIGINT を受信したら処理中のリクエストの完了を待って停止する
+func Serve(srv *http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	// 2回目のシグナルでは即座に終了できるようにする
+	stop()
+	slog.Info("終了シグナルを受信しました。処理中のリクエストの完了を待ちます", "timeout", config.Config.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Config.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("処理中のリクエストの完了を待てませんでした: %v", err)
+	}
+	slog.Info("HTTPサーバーを停止しました")
+	return nil
 }
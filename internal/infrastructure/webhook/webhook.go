@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/utils/buildinfo"
+)
+
+// 署名などのリクエストヘッダー
+const (
+	HeaderEvent     = "X-Webhook-Event"     // イベントの種類
+	HeaderTimestamp = "X-Webhook-Timestamp" // 送信日時（Unix秒）
+	HeaderSignature = "X-Webhook-Signature" // sha256=<HMAC-SHA256(秘密鍵, "<timestamp>.<body>") の16進数>
+)
+
+// レスポンスボディを読み捨てる最大サイズ（接続を再利用するため）
+const maxResponseBytes = 64 << 10
+
+// ErrBlockedAddress 送信先がループバック・プライベートアドレスのため送信しなかった
+var ErrBlockedAddress = errors.New("送信先のアドレスは許可されていません")
+
+// 内部ネットワークへのリクエストの踏み台にされないよう、接続先のアドレスを確認するクライアント
+// 名前解決の結果で判定するため、DNSで内部アドレスを返すドメインも拒否する
+var client = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip != nil && !config.Config.AllowPrivateWebhooks() && isPrivateIP(ip) {
+					return ErrBlockedAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	},
+	// リダイレクト先が内部アドレスでも接続時に拒否されるが、送信先を固定するため追従しない
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ValidateURL 送信先のURLとして使えるかを確認する
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("URLの形式が不正です")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("URLは http:// または https:// で始めてください")
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("URLの形式が不正です")
+	}
+	if config.Config.AllowPrivateWebhooks() {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("URLは https:// で始めてください")
+	}
+	if u.Hostname() == "localhost" {
+		return ErrBlockedAddress
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && isPrivateIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// ループバック・プライベート・リンクローカルなど、外部に公開されていないアドレスかどうか
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// Sign 送信日時と本文の署名を作る
+// 受信側は同じ計算をして X-Webhook-Signature と比較し、古い送信日時のリクエストを拒否する
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post 署名付きのJSONを送信し、レスポンスのステータスコードを返す
+// 2xx 以外のステータスコードもエラーとして返す
+func Post(ctx context.Context, targetURL, secret, event string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Config.WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "security-chat-webhook/"+buildinfo.Version)
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("送信先が %d を返しました", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Attempt 1回の送信の結果
+type Attempt struct {
+	Number     int           // 試行回数（1から数える）
+	StatusCode int           // レスポンスのステータスコード（接続できなかった場合は0）
+	Err        error         // エラー（成功した場合は nil）
+	Duration   time.Duration // 送信にかかった時間
+	SentAt     time.Time     // 送信日時
+}
+
+// Deliver 送信に成功するまで、最大 WebhookMaxAttempts 回まで指数バックオフで再送する
+// 試行ごとに record を呼び出し、record が false を返した場合（Webhookが停止された場合など）は再送しない
+// 送信先が存在しない・リクエストが拒否されたなど、再送しても成功しない失敗は再送しない
+func Deliver(ctx context.Context, targetURL, secret, event string, body []byte, record func(Attempt) bool) error {
+	backoff := config.Config.WebhookRetryBackoff
+	for number := 1; ; number++ {
+		sentAt := time.Now()
+		status, err := Post(ctx, targetURL, secret, event, body)
+		attempt := Attempt{Number: number, StatusCode: status, Err: err, Duration: time.Since(sentAt), SentAt: sentAt}
+		if !record(attempt) || err == nil {
+			return err
+		}
+		if number >= config.Config.WebhookMaxAttempts || !isRetryable(status, err) {
+			return err
+		}
+
+		// 複数のWebhookの再送が同時に集中しないよう、待ち時間を最大25%ずらす
+		wait := backoff + rand.N(backoff/4+1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// 再送すれば成功する可能性がある失敗かどうか
+func isRetryable(status int, err error) bool {
+	if errors.Is(err, ErrBlockedAddress) {
+		return false
+	}
+	switch {
+	case status == 0:
+		// 接続・タイムアウトのエラー
+		return true
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= 500
+	}
+}
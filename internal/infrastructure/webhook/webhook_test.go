@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"security_chat_app/internal/config"
+)
+
+// テスト用に再送の待ち時間を短くし、ローカルの受信サーバーへの送信を許可する
+func setupConfig(t *testing.T, allowPrivate string) {
+	t.Helper()
+	saved := config.Config
+	t.Cleanup(func() { config.Config = saved })
+	config.Config.WebhookAllowPrivate = allowPrivate
+	config.Config.WebhookTimeout = 5 * time.Second
+	config.Config.WebhookMaxAttempts = 3
+	config.Config.WebhookRetryBackoff = time.Millisecond
+}
+
+// 指定したステータスコードを順に返す受信サーバー（使い切った後は最後のステータスコードを返す）
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// 試行を全て記録する record
+func recordAll(attempts *[]Attempt) func(Attempt) bool {
+	return func(a Attempt) bool {
+		*attempts = append(*attempts, a)
+		return true
+	}
+}
+
+func TestPostSignsRequest(t *testing.T) {
+	setupConfig(t, "true")
+	const secret = "test-secret"
+	body := []byte(`{"type":"message.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(HeaderEvent); got != "message.created" {
+			t.Errorf("%s = %q, want message.created", HeaderEvent, got)
+		}
+		timestamp := r.Header.Get(HeaderTimestamp)
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Errorf("%s = %q is not a Unix time: %v", HeaderTimestamp, timestamp, err)
+		}
+
+		// 受信側と同じ手順で署名を検証する
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "." + string(received)))
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get(HeaderSignature); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := Post(context.Background(), server.URL, secret, "message.created", body)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Post() = %d, %v; want 204, nil", status, err)
+	}
+}
+
+func TestSignDependsOnInputs(t *testing.T) {
+	body := []byte("{}")
+	base := Sign("secret", 1700000000, body)
+	if Sign("other", 1700000000, body) == base {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("secret", 1700000001, body) == base {
+		t.Error("signature does not depend on the timestamp")
+	}
+	if Sign("secret", 1700000000, []byte(`{"a":1}`)) == base {
+		t.Error("signature does not depend on the body")
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"success", []int{http.StatusOK}, 1, false},
+		{"retry on 5xx", []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}, 3, false},
+		{"retry on 429", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"give up after max attempts", []int{http.StatusBadGateway}, 3, true},
+		{"no retry on 4xx", []int{http.StatusBadRequest}, 1, true},
+		{"no retry on 404", []int{http.StatusNotFound}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfig(t, "true")
+			server, calls := newReceiver(t, tt.statuses...)
+
+			var attempts []Attempt
+			err := Deliver(context.Background(), server.URL, "secret", "message.created", []byte("{}"), recordAll(&attempts))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(attempts) != tt.wantAttempts || int(calls.Load()) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, requests = %d; want %d", len(attempts), calls.Load(), tt.wantAttempts)
+			}
+			for i, a := range attempts {
+				if a.Number != i+1 {
+					t.Errorf("attempts[%d].Number = %d, want %d", i, a.Number, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverStopsWhenRecordReturnsFalse(t *testing.T) {
+	setupConfig(t, "true")
+	server, calls := newReceiver(t, http.StatusInternalServerError)
+
+	err := Deliver(context.Background(), server.URL, "secret", "message.created", []byte("{}"), func(Attempt) bool {
+		return false // Webhookが停止された
+	})
+	if err == nil || calls.Load() != 1 {
+		t.Fatalf("Deliver() = %v after %d requests; want an error after 1 request", err, calls.Load())
+	}
+}
+
+func TestDeliverBlocksPrivateAddress(t *testing.T) {
+	setupConfig(t, "false")
+	server, calls := newReceiver(t, http.StatusOK)
+
+	var attempts []Attempt
+	err := Deliver(context.Background(), server.URL, "secret", "message.created", []byte("{}"), recordAll(&attempts))
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Deliver() error = %v, want %v", err, ErrBlockedAddress)
+	}
+	if len(attempts) != 1 || calls.Load() != 0 {
+		t.Fatalf("attempts = %d, requests = %d; want 1 attempt and no request", len(attempts), calls.Load())
+	}
+	if err := ValidateURL(server.URL); err == nil {
+		t.Errorf("ValidateURL(%q) = nil, want an error", server.URL)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{0, errors.New("connection refused"), true},
+		{http.StatusRequestTimeout, nil, true},
+		{http.StatusTooManyRequests, nil, true},
+		{http.StatusInternalServerError, nil, true},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusBadRequest, nil, false},
+		{http.StatusUnauthorized, nil, false},
+		{http.StatusGone, nil, false},
+		{0, ErrBlockedAddress, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.status, tt.err); got != tt.want {
+			t.Errorf("isRetryable(%d, %v) = %v, want %v", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 管理画面の集計で日ごとの件数を表示する日数
+const adminStatsDays = 14
+
+// ユーザー一覧に一度に表示する最大件数
+const adminUsersLimit = 100
+
+// 管理画面のトップページのデータ構造体
+type AdminPageData struct {
+	IsLoggedIn   bool               // ログイン状態
+	User         *domain.User       // ユーザー情報
+	Stats        *domain.Stats      // サービス全体の集計（取得できなかった場合は nil）
+	Storage      []StorageUsageView // ストレージの使用量（取得できなかった場合は nil）
+	StorageTotal StorageUsageView   // ストレージの使用量の合計
+	Errors       []string           // 集計に失敗した項目のエラー
+	OpenReports  int                // 対応待ちの通報の数
+}
+
+// 管理画面に表示するストレージの使用量
+type StorageUsageView struct {
+	domain.StorageUsage
+	Size string // 合計サイズの表示（例: 1.5 MB）
+}
+
+// ユーザー一覧ページのデータ構造体
+type AdminUsersPageData struct {
+	IsLoggedIn bool            // ログイン状態
+	User       *domain.User    // ユーザー情報
+	Query      string          // 検索キーワード（名前・メールアドレス）
+	Users      []AdminUserView // 検索結果のユーザー
+	Total      int             // 検索に一致したユーザーの数
+	Truncated  bool            // 表示の上限を超えたため一部のみ表示しているかどうか
+}
+
+// 管理画面に表示するユーザー
+type AdminUserView struct {
+	domain.User
+	IsAdmin bool // 管理者かどうか
+	IsSelf  bool // 操作している管理者自身かどうか
+}
+
+// 管理画面のトップページ（集計・ストレージの使用量）のハンドラ（管理者のみ）
+func AdminHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path != "/admin" {
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+	if r.Method != http.MethodGet {
+		return domain.NewMethodNotAllowedError()
+	}
+	admin := middleware.SessionUser(r)
+	data := AdminPageData{IsLoggedIn: true, User: admin}
+
+	// 集計は時間がかかる・インデックスが無いなどで失敗することがあるため、失敗した項目以外は表示する
+	stats, err := repository.GetStats(r.Context(), adminStatsDays)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "集計の取得に失敗", "error", err)
+		data.Errors = append(data.Errors, "ユーザー数・チャット数・メッセージ数の集計に失敗しました")
+	}
+	data.Stats = stats
+
+	usages, err := firebase.GetStorageUsage(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "ストレージの使用量の取得に失敗", "error", err)
+		data.Errors = append(data.Errors, "ストレージの使用量の取得に失敗しました")
+	}
+	for _, usage := range usages {
+		data.Storage = append(data.Storage, newStorageUsageView(usage))
+		data.StorageTotal.Objects += usage.Objects
+		data.StorageTotal.Bytes += usage.Bytes
+	}
+	data.StorageTotal = newStorageUsageView(data.StorageTotal.StorageUsage)
+
+	reports, err := repository.GetReportsByStatus(domain.ReportStatusOpen, domain.ReportStatusReviewing)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "通報の取得に失敗", "error", err)
+		data.Errors = append(data.Errors, "通報の取得に失敗しました")
+	}
+	data.OpenReports = len(reports)
+
+	return markup.GenerateHTML(w, data, "layout", "header", "admin", "footer")
+}
+
+// ユーザー一覧（名前・メールアドレスで検索）のハンドラ（管理者のみ）
+func AdminUsersHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return domain.NewMethodNotAllowedError()
+	}
+	admin := middleware.SessionUser(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	users, err := repository.GetAllUsers()
+	if err != nil {
+		return domain.NewInternalError("ユーザーの取得に失敗しました", err)
+	}
+
+	data := AdminUsersPageData{IsLoggedIn: true, User: admin, Query: query}
+	keyword := strings.ToLower(query)
+	for _, user := range users {
+		if keyword != "" && !strings.Contains(strings.ToLower(user.Name), keyword) && !strings.Contains(strings.ToLower(user.Email), keyword) {
+			continue
+		}
+		data.Total++
+		if len(data.Users) >= adminUsersLimit {
+			data.Truncated = true
+			continue
+		}
+		data.Users = append(data.Users, AdminUserView{
+			User:    user,
+			IsAdmin: middleware.IsAdmin(&user),
+			IsSelf:  user.ID == admin.ID,
+		})
+	}
+	return markup.GenerateHTML(w, data, "layout", "header", "admin_users", "footer")
+}
+
+// ユーザーへの操作（POST /admin/users/{id}/{操作}）のハンドラ（管理者のみ）
+// 操作: suspend（停止）・unsuspend（停止の解除）・logout（強制ログアウト、APIトークンとボットのトークンも失効させる）・reset-password（パスワードの再設定の要求）・role（権限の変更）
+func AdminUserActionHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+	admin := middleware.SessionUser(r)
+	r.ParseForm()
+
+	userID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/users/"), "/")
+	if userID == "" {
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+	target, err := repository.GetUserByID(userID)
+	if err != nil {
+		return domain.NewNotFoundError("ユーザーが見つかりません", err)
+	}
+
+	switch action {
+	case "suspend":
+		if target.ID == admin.ID || middleware.IsAdmin(target) {
+			return domain.NewForbiddenError("管理者のアカウントは停止できません", nil)
+		}
+		if err := repository.SuspendUser(target.ID); err != nil {
+			return domain.NewInternalError("アカウントの停止に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "アカウントを停止", "user_id", admin.ID, "target_user_id", target.ID)
+		recordAudit(r, domain.AuditUserSuspended, admin.ID, target.ID, nil)
+
+	case "unsuspend":
+		if err := repository.UnsuspendUser(target.ID); err != nil {
+			return domain.NewInternalError("アカウントの停止の解除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "アカウントの停止を解除", "user_id", admin.ID, "target_user_id", target.ID)
+		recordAudit(r, domain.AuditUserUnsuspended, admin.ID, target.ID, nil)
+
+	case "logout":
+		count, err := repository.DeleteUserSessions(r.Context(), target.ID)
+		if err != nil {
+			return domain.NewInternalError("セッションの削除に失敗しました", err)
+		}
+		// セッションを削除してもAPIトークンでは操作できるため、ユーザーとユーザーが作成したボットのトークンも失効させる
+		tokens, err := repository.RevokeUserAPITokens(r.Context(), target.ID)
+		if err != nil {
+			return domain.NewInternalError("APIトークンの失効に失敗しました", err)
+		}
+		if err := repository.UpdateUserField(target.ID, "IsOnline", false); err != nil {
+			slog.ErrorContext(r.Context(), "オンライン状態の更新に失敗", "error", err, "target_user_id", target.ID)
+		}
+		slog.InfoContext(r.Context(), "強制ログアウト", "user_id", admin.ID, "target_user_id", target.ID, "sessions", count, "tokens", tokens)
+		recordAudit(r, domain.AuditSessionsRevoked, admin.ID, target.ID, map[string]string{"sessions": strconv.Itoa(count), "tokens": strconv.Itoa(tokens)})
+
+	case "reset-password":
+		if target.IsBot() {
+			return domain.NewValidationError("ボットにはパスワードがありません", nil)
+		}
+		if target.ID == admin.ID {
+			return domain.NewValidationError("自分のパスワードは設定ページから変更してください", nil)
+		}
+		if err := repository.RequirePasswordReset(target.ID); err != nil {
+			return domain.NewInternalError("パスワードの再設定の要求に失敗しました", err)
+		}
+		// 認証情報のバージョンを進めたので既存のセッションは使えないが、残さないよう削除する
+		count, err := repository.DeleteUserSessions(r.Context(), target.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "セッションの削除に失敗", "error", err, "target_user_id", target.ID)
+		}
+		slog.InfoContext(r.Context(), "パスワードの再設定を要求", "user_id", admin.ID, "target_user_id", target.ID)
+		recordAudit(r, domain.AuditPasswordResetRequired, admin.ID, target.ID, map[string]string{"sessions": strconv.Itoa(count)})
+
+	case "role":
+		role := r.FormValue("role")
+		if role != domain.RoleUser && role != domain.RoleAdmin {
+			return domain.NewValidationError("権限が正しくありません", nil)
+		}
+		if target.IsBot() {
+			return domain.NewValidationError("ボットの権限は変更できません", nil)
+		}
+		if target.ID == admin.ID {
+			return domain.NewValidationError("自分の権限は変更できません", nil)
+		}
+		if err := repository.UpdateUserRole(target.ID, role); err != nil {
+			return domain.NewInternalError("権限の変更に失敗しました", err)
+		}
+		// 認証情報のバージョンを進めたので既存のセッションは使えないが、残さないよう削除する
+		count, err := repository.DeleteUserSessions(r.Context(), target.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "セッションの削除に失敗", "error", err, "target_user_id", target.ID)
+		}
+		slog.InfoContext(r.Context(), "権限を変更", "user_id", admin.ID, "target_user_id", target.ID, "role", role)
+		recordAudit(r, domain.AuditRoleChanged, admin.ID, target.ID, map[string]string{"old_role": target.Role, "new_role": role, "sessions": strconv.Itoa(count)})
+
+	default:
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+
+	http.Redirect(w, r, localReturnPath(r, "/admin/users"), http.StatusSeeOther)
+	return nil
+}
+
+// ストレージの使用量を表示用に変換する
+func newStorageUsageView(usage domain.StorageUsage) StorageUsageView {
+	return StorageUsageView{StorageUsage: usage, Size: formatBytes(usage.Bytes)}
+}
+
+// バイト数を読みやすい単位で表示する
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
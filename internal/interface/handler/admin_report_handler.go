@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 管理者のメモの最大文字数
+const maxModerationNoteLength = 500
+
+// 通報一覧ページのデータ構造体
+type AdminReportsPageData struct {
+	IsLoggedIn bool         // ログイン状態
+	User       *domain.User // ユーザー情報
+	Reports    []ReportView // 通報の一覧
+	ShowClosed bool         // 対応済み・却下の通報を表示しているかどうか
+}
+
+// 通報の詳細ページのデータ構造体
+type AdminReportPageData struct {
+	IsLoggedIn bool                   // ログイン状態
+	User       *domain.User           // ユーザー情報
+	Report     ReportView             // 通報
+	Messages   []domain.Message       // 通報時点で保存した前後のメッセージ
+	Actions    []ModerationActionView // 通報への操作の記録
+}
+
+// 管理画面に表示する通報
+type ReportView struct {
+	domain.Report
+	ReporterName string // 通報したユーザーの名前
+	TargetName   string // 通報されたユーザーの名前
+	ReasonLabel  string // 通報の理由の表示名
+	StatusLabel  string // 通報の状態の表示名
+	IsMessage    bool   // メッセージの通報かどうか
+}
+
+// 管理画面に表示する通報への操作の記録
+type ModerationActionView struct {
+	domain.ModerationAction
+	ModeratorName string // 操作した管理者の名前
+	ActionLabel   string // 操作の表示名
+}
+
+// 通報の一覧（モデレーションキュー）のハンドラ（管理者のみ）
+// 未対応・確認中の通報を古い順に表示する（?status=closed で対応済み・却下の通報）
+func AdminReportsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return domain.NewMethodNotAllowedError()
+	}
+	admin := middleware.SessionUser(r)
+
+	showClosed := r.URL.Query().Get("status") == "closed"
+	statuses := []string{domain.ReportStatusOpen, domain.ReportStatusReviewing}
+	if showClosed {
+		statuses = []string{domain.ReportStatusResolved, domain.ReportStatusDismissed}
+	}
+	reports, err := repository.GetReportsByStatus(statuses...)
+	if err != nil {
+		return domain.NewInternalError("通報の取得に失敗しました", err)
+	}
+
+	names := userNameResolver()
+	views := make([]ReportView, 0, len(reports))
+	for _, report := range reports {
+		views = append(views, newReportView(report, names))
+	}
+	data := AdminReportsPageData{
+		IsLoggedIn: true,
+		User:       admin,
+		Reports:    views,
+		ShowClosed: showClosed,
+	}
+	return markup.GenerateHTML(w, data, "layout", "header", "admin_reports", "footer")
+}
+
+// 通報の詳細（GET /admin/reports/{id}）と通報への操作（POST /admin/reports/{id}/actions）のハンドラ（管理者のみ）
+func AdminReportHandler(w http.ResponseWriter, r *http.Request) error {
+	admin := middleware.SessionUser(r)
+
+	reportID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/reports/"), "/")
+	report, err := repository.GetReport(reportID)
+	if err != nil {
+		return domain.NewInternalError("通報の取得に失敗しました", err)
+	}
+	if report == nil {
+		return domain.NewNotFoundError("通報が見つかりません", nil)
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		return renderAdminReport(w, r, admin, report)
+	case action == "actions" && r.Method == http.MethodPost:
+		if err := moderateReport(r, admin, report); err != nil {
+			return err
+		}
+		http.Redirect(w, r, "/admin/reports/"+report.ID, http.StatusSeeOther)
+		return nil
+	case action == "" || action == "actions":
+		return domain.NewMethodNotAllowedError()
+	}
+	return domain.NewNotFoundError("ページが見つかりません", nil)
+}
+
+// 通報の詳細ページを表示する
+func renderAdminReport(w http.ResponseWriter, r *http.Request, admin *domain.User, report *domain.Report) error {
+	names := userNameResolver()
+	data := AdminReportPageData{
+		IsLoggedIn: true,
+		User:       admin,
+		Report:     newReportView(*report, names),
+	}
+
+	if report.TargetType == domain.ReportTargetMessage {
+		messages, err := firebase.GetReportContext(r.Context(), report.ID, report.ChatID)
+		if err != nil {
+			return domain.NewInternalError("通報されたメッセージの取得に失敗しました", err)
+		}
+		for _, msg := range messages {
+			data.Messages = append(data.Messages, messageFromData(report.ChatID, msg))
+		}
+	}
+
+	actions, err := repository.GetModerationActions(report.ID)
+	if err != nil {
+		return domain.NewInternalError("通報への操作の記録の取得に失敗しました", err)
+	}
+	for _, action := range actions {
+		data.Actions = append(data.Actions, ModerationActionView{
+			ModerationAction: action,
+			ModeratorName:    names(action.ModeratorID),
+			ActionLabel:      domain.ModerationActionLabel(action.Action),
+		})
+	}
+	return markup.GenerateHTML(w, data, "layout", "header", "admin_report", "footer")
+}
+
+// 通報に対応し、操作を記録する
+// 確認中にする以外の操作で通報は閉じられ、閉じた通報には操作できない
+func moderateReport(r *http.Request, admin *domain.User, report *domain.Report) error {
+	r.ParseForm()
+	if !report.IsOpen() {
+		return domain.NewConflictError("この通報は対応済みです", nil)
+	}
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len([]rune(note)) > maxModerationNoteLength {
+		return domain.NewValidationError("メモは500文字以内で入力してください", nil)
+	}
+
+	action := &domain.ModerationAction{
+		ReportID:     report.ID,
+		ModeratorID:  admin.ID,
+		Action:       r.FormValue("action"),
+		TargetUserID: report.TargetUserID,
+		ChatID:       report.ChatID,
+		MessageID:    report.MessageID,
+		Note:         note,
+	}
+
+	var reportStatus string
+	switch action.Action {
+	case domain.ModerationReview:
+		reportStatus = domain.ReportStatusReviewing
+
+	case domain.ModerationDismiss:
+		reportStatus = domain.ReportStatusDismissed
+
+	case domain.ModerationDeleteMessage:
+		if report.TargetType != domain.ReportTargetMessage {
+			return domain.NewValidationError("メッセージの通報ではありません", nil)
+		}
+		if err := firebase.DeleteChatMessage(r.Context(), report.ChatID, report.MessageID); err != nil {
+			return domain.NewInternalError("メッセージの削除に失敗しました", err)
+		}
+		reportStatus = domain.ReportStatusResolved
+
+	case domain.ModerationSuspend:
+		target, err := GetUserData(report.TargetUserID)
+		if err != nil {
+			return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+		}
+		if middleware.IsAdmin(target) {
+			return domain.NewForbiddenError("管理者のアカウントは停止できません", nil)
+		}
+		if err := repository.SuspendUser(target.ID); err != nil {
+			return domain.NewInternalError("アカウントの停止に失敗しました", err)
+		}
+		reportStatus = domain.ReportStatusResolved
+
+	default:
+		return domain.NewValidationError("操作が正しくありません", nil)
+	}
+
+	if err := repository.RecordModerationAction(action, reportStatus); err != nil {
+		return domain.NewInternalError("操作の記録に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "通報に対応", "user_id", admin.ID, "report_id", report.ID, "action", action.Action, "target_user_id", report.TargetUserID)
+	metadata := map[string]string{"report_id": report.ID, "action": action.Action}
+	if report.MessageID != "" {
+		metadata["chat_id"] = report.ChatID
+		metadata["message_id"] = report.MessageID
+	}
+	recordAudit(r, domain.AuditReportModerated, admin.ID, report.TargetUserID, metadata)
+	if action.Action == domain.ModerationSuspend {
+		recordAudit(r, domain.AuditUserSuspended, admin.ID, report.TargetUserID, map[string]string{"report_id": report.ID})
+	}
+	return nil
+}
+
+// 通報を管理画面の表示用に変換する
+func newReportView(report domain.Report, names func(string) string) ReportView {
+	return ReportView{
+		Report:       report,
+		ReporterName: names(report.ReporterID),
+		TargetName:   names(report.TargetUserID),
+		ReasonLabel:  domain.ReportReasonLabel(report.Reason),
+		StatusLabel:  domain.ReportStatusLabel(report.Status),
+		IsMessage:    report.TargetType == domain.ReportTargetMessage,
+	}
+}
+
+// ユーザーIDから名前を取得する関数を返す（同じページ内で同じユーザーを何度も取得しない）
+// 削除されたユーザーなど取得できない場合はIDを返す
+func userNameResolver() func(string) string {
+	names := make(map[string]string)
+	return func(userID string) string {
+		if name, ok := names[userID]; ok {
+			return name
+		}
+		name := userID
+		if userID == domain.DeletedUserID {
+			name = domain.DeletedUserName
+		} else if user, err := GetUserData(userID); err == nil {
+			name = user.Name
+		}
+		names[userID] = name
+		return name
+	}
+}
@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// APIのパスの接頭辞
+const APIPrefix = "/api/v1"
+
+// 一覧の1ページあたりの件数
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// JSONのリクエストボディの最大サイズ
+const maxAPIRequestBytes = 64 << 10
+
+// APIRoute APIのエンドポイントの定義
+// ルーティングの登録と OpenAPI ドキュメントの生成の両方に使う
+type APIRoute struct {
+	Method    string     // HTTPメソッド
+	Path      string     // パス（APIPrefix からの相対パス、パスパラメータは {id} の形式）
+	Summary   string     // エンドポイントの説明
+	Tag       string     // ドキュメントでの分類
+	Public    bool       // 認証なしで呼び出せるかどうか
+	Scope     string     // APIトークンで呼び出す場合に必要なスコープ（空の場合はセッションでのみ呼び出せる）
+	Query     []APIParam // クエリパラメータ
+	Request   any        // リクエストボディの型（nil の場合はボディなし）
+	Multipart string     // multipart/form-data で受け取るファイルのフィールド名
+	Response  any        // レスポンスボディの型（nil の場合は 204 No Content）
+	Status    int        // 成功時のステータスコード（0 の場合は 200）
+	Paginated bool       // Response を要素とするページ分割された一覧を返すかどうか
+	Handler   middleware.APIHandler
+}
+
+// APIParam クエリパラメータの定義
+type APIParam struct {
+	Name        string
+	Description string
+	Type        string // string / integer
+	Required    bool
+}
+
+// ページ分割のクエリパラメータ
+var pageParams = []APIParam{
+	{Name: "limit", Description: "1ページあたりの件数（1〜100、既定は50）", Type: "integer"},
+	{Name: "cursor", Description: "前のレスポンスの next_cursor", Type: "string"},
+}
+
+// APIRoutes すべてのAPIのエンドポイント
+func APIRoutes() []APIRoute {
+	return []APIRoute{
+		// 認証
+		{Method: http.MethodPost, Path: "/auth/signup", Summary: "ユーザーを登録してログインする", Tag: "auth", Public: true,
+			Request: apiSignupRequest{}, Response: apiMe{}, Status: http.StatusCreated, Handler: apiSignup},
+		{Method: http.MethodPost, Path: "/auth/login", Summary: "ログインしてセッションを発行する", Tag: "auth", Public: true,
+			Request: apiLoginRequest{}, Response: apiMe{}, Handler: apiLogin},
+		{Method: http.MethodPost, Path: "/auth/logout", Summary: "ログアウトしてセッションを破棄する", Tag: "auth",
+			Handler: apiLogout},
+
+		// ユーザー・プロフィール
+		{Method: http.MethodGet, Path: "/users/me", Summary: "ログイン中のユーザーのプロフィールを取得する", Tag: "profile",
+			Response: apiMe{}, Scope: domain.ScopeReadMessages, Handler: apiGetMe},
+		{Method: http.MethodPatch, Path: "/users/me", Summary: "ログイン中のユーザーのプロフィールを更新する", Tag: "profile",
+			Request: apiUpdateProfileRequest{}, Response: apiMe{}, Scope: domain.ScopeManageProfile, Handler: apiUpdateMe},
+		{Method: http.MethodPut, Path: "/users/me/password", Summary: "パスワードを変更する（他の端末のセッションは無効になる）", Tag: "profile",
+			Request: apiChangePasswordRequest{}, Handler: apiChangePassword},
+		{Method: http.MethodPut, Path: "/users/me/icon", Summary: "アイコン画像を変更する（.jpg / .jpeg / .png、5MBまで）", Tag: "profile",
+			Multipart: "icon", Response: apiMe{}, Scope: domain.ScopeManageProfile, Handler: apiUpdateIcon},
+		{Method: http.MethodGet, Path: "/users", Summary: "ユーザー名で検索する（自分とボットは含まない）", Tag: "users",
+			Query:    append([]APIParam{{Name: "q", Description: "ユーザー名の一部（省略時はすべてのユーザー）", Type: "string"}}, pageParams...),
+			Response: apiUser{}, Paginated: true, Scope: domain.ScopeReadMessages, Handler: apiSearchUsers},
+		{Method: http.MethodGet, Path: "/users/{id}", Summary: "ユーザーの公開プロフィールを取得する", Tag: "users",
+			Response: apiUser{}, Scope: domain.ScopeReadMessages, Handler: apiGetUser},
+
+		// チャット
+		{Method: http.MethodGet, Path: "/chats", Summary: "参加しているチャットを更新日時の新しい順に取得する", Tag: "chats",
+			Query: pageParams, Response: apiChat{}, Paginated: true, Scope: domain.ScopeReadMessages, Handler: apiListChats},
+		{Method: http.MethodPost, Path: "/chats", Summary: "ユーザーとのチャットを開始する（既にある場合はそのチャットを返す）", Tag: "chats",
+			Request: apiStartChatRequest{}, Response: apiChat{}, Status: http.StatusCreated, Scope: domain.ScopeSendMessages, Handler: apiStartChat},
+		{Method: http.MethodGet, Path: "/chats/{id}", Summary: "チャットを取得する", Tag: "chats",
+			Response: apiChat{}, Scope: domain.ScopeReadMessages, Handler: apiGetChat},
+
+		// メッセージ・既読
+		{Method: http.MethodGet, Path: "/chats/{id}/messages", Summary: "チャットのメッセージを新しい順に取得する", Tag: "messages",
+			Query: pageParams, Response: apiMessage{}, Paginated: true, Scope: domain.ScopeReadMessages, Handler: apiListMessages},
+		{Method: http.MethodPost, Path: "/chats/{id}/messages", Summary: "メッセージを送信する", Tag: "messages",
+			Request: apiSendMessageRequest{}, Response: apiMessage{}, Status: http.StatusCreated, Scope: domain.ScopeSendMessages, Handler: apiSendMessage},
+		{Method: http.MethodPatch, Path: "/chats/{id}/messages/{message_id}", Summary: "自分が送信したメッセージの本文を編集する", Tag: "messages",
+			Request: apiSendMessageRequest{}, Response: apiMessage{}, Scope: domain.ScopeSendMessages, Handler: apiEditMessage},
+		{Method: http.MethodPost, Path: "/chats/{id}/read", Summary: "チャットの相手からのメッセージをすべて既読にする", Tag: "messages",
+			Response: apiReadResult{}, Scope: domain.ScopeReadMessages, Handler: apiMarkRead},
+
+		// ボット
+		{Method: http.MethodPost, Path: "/chats/{id}/bots", Summary: "チャットに自分が作成したボットを追加する（暗号化されたチャットは不可）", Tag: "bots",
+			Request: apiAddChatBotRequest{}, Scope: domain.ScopeSendMessages, Handler: apiAddChatBot},
+		{Method: http.MethodDelete, Path: "/chats/{id}/bots/{bot_id}", Summary: "チャットからボットを外す", Tag: "bots",
+			Scope: domain.ScopeSendMessages, Handler: apiRemoveChatBot},
+		{Method: http.MethodGet, Path: "/bots/updates", Summary: "ボットが追加されたチャットの新しいメッセージを待って取得する（ボットのトークンのみ）", Tag: "bots",
+			Query: botUpdatesParams, Response: apiBotUpdates{}, Scope: domain.ScopeReadMessages, Handler: apiGetBotUpdates},
+
+		// 受信用Webhook（URLに含まれるトークンで認証する）
+		{Method: http.MethodPost, Path: "/hooks/{token}", Summary: "受信用Webhookのチャットにメッセージを投稿する", Tag: "integrations", Public: true,
+			Request: apiIncomingWebhookRequest{}, Response: apiMessage{}, Status: http.StatusCreated, Handler: apiPostIncomingWebhook},
+	}
+}
+
+// APIPattern ServeMux に登録するパターン（例: GET /api/v1/chats/{id}）
+func (route APIRoute) APIPattern() string {
+	return route.Method + " " + APIPrefix + route.Path
+}
+
+// APINotFoundHandler APIのどのエンドポイントにも一致しないリクエストに応答する
+// パスが一致してメソッドのみ異なる場合は405を返す
+func APINotFoundHandler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); strings.HasPrefix(pattern, method+" ") {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			middleware.WriteAPIError(w, r, domain.NewMethodNotAllowedError())
+			return
+		}
+		middleware.WriteAPIError(w, r, domain.NewNotFoundError("エンドポイントが見つかりません", nil))
+	})
+}
+
+// JSONのリクエストボディを読み込む
+// フォームの送信によるクロスサイトリクエストを防ぐため、Content-Type は application/json のみ受け付ける
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return domain.NewValidationError("Content-Type には application/json を指定してください", nil)
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return domain.NewValidationError("リクエストの形式が不正です", err)
+	}
+	return nil
+}
+
+// 成功時のレスポンスを書き出す
+func writeAPIResponse(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Cache-Control", "no-store")
+	if v == nil {
+		w.WriteHeader(status)
+		return nil
+	}
+	writeJSON(w, status, v)
+	return nil
+}
+
+// apiList ページ分割された一覧のレスポンス
+type apiList[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// 一覧のカーソル（前のページで最後に返した要素の並び順のキーとID）
+// 件数ではなく要素を基準にするため、ページの間に要素が追加・削除されても重複や抜けが起きない
+type apiCursor struct {
+	Kind string `json:"k"`           // カーソルを発行した一覧の種類（他の一覧のカーソルを受け付けない）
+	Time int64  `json:"t,omitempty"` // 日時順の一覧のキー（UnixNano）
+	Name string `json:"n,omitempty"` // 名前順の一覧のキー
+	ID   string `json:"i"`
+}
+
+// カーソルを発行する一覧の種類
+const (
+	cursorChats    = "chats"
+	cursorMessages = "messages"
+	cursorUsers    = "users"
+)
+
+// 一覧の取得範囲
+type apiPage struct {
+	kind  string     // 一覧の種類
+	limit int        // 1ページあたりの件数
+	after *apiCursor // nil の場合は最初のページ
+}
+
+// クエリパラメータから一覧の取得範囲を読み込む（kind は一覧の種類）
+func parseAPIPage(r *http.Request, kind string) (apiPage, error) {
+	page := apiPage{kind: kind, limit: defaultPageSize}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageSize {
+			return page, domain.NewValidationError("limit には1〜100の整数を指定してください", err)
+		}
+		page.limit = n
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err == nil && after.Kind != kind {
+			err = fmt.Errorf("%s の一覧のカーソルです", after.Kind)
+		}
+		if err != nil {
+			return page, domain.NewValidationError("cursor が不正です", err)
+		}
+		page.after = after
+	}
+	return page, nil
+}
+
+// 並び順に取得した要素（取得範囲の件数より1件多く取得したもの）から一覧のレスポンスを作る
+// 取得範囲を超える要素がある場合は、最後に返す要素のカーソルを次のページのカーソルにする
+func newAPIList[T any](items []T, page apiPage, cursorOf func(T) apiCursor) apiList[T] {
+	list := apiList[T]{Data: items}
+	if list.Data == nil {
+		list.Data = []T{}
+	}
+	if len(items) > page.limit {
+		list.Data = items[:page.limit]
+		cursor := cursorOf(list.Data[page.limit-1])
+		cursor.Kind = page.kind
+		list.NextCursor = encodeCursor(cursor)
+	}
+	return list
+}
+
+// 並び順に並べた一覧から、カーソルより後の取得範囲を切り出す
+// isAfter は要素がカーソルより後に並ぶかどうかを返す
+func paginate[T any](items []T, page apiPage, cursorOf func(T) apiCursor, isAfter func(T, apiCursor) bool) apiList[T] {
+	start := 0
+	if page.after != nil {
+		start = len(items)
+		for i, item := range items {
+			if isAfter(item, *page.after) {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+page.limit+1, len(items))
+	return newAPIList(items[start:end], page, cursorOf)
+}
+
+// カーソルを不透明な文字列にする
+func encodeCursor(cursor apiCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// 文字列からカーソルを取り出す
+func decodeCursor(cursor string) (*apiCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var c apiCursor
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil || c.Kind == "" || c.ID == "" {
+		return nil, errors.New("カーソルの形式が不正です")
+	}
+	return &c, nil
+}
+
+// apiUser ユーザーの公開プロフィール
+type apiUser struct {
+	ID       string `json:"id" doc:"ユーザーID"`
+	Name     string `json:"name" doc:"ユーザー名"`
+	Icon     string `json:"icon,omitempty" doc:"アイコン画像のURL"`
+	IsOnline bool   `json:"is_online" doc:"オンラインかどうか"`
+	IsBot    bool   `json:"is_bot,omitempty" doc:"ボットかどうか"`
+}
+
+// apiMe ログイン中のユーザーのプロフィール
+type apiMe struct {
+	ID        string    `json:"id" doc:"ユーザーID"`
+	Name      string    `json:"name" doc:"ユーザー名"`
+	Email     string    `json:"email" doc:"メールアドレス"`
+	Icon      string    `json:"icon,omitempty" doc:"アイコン画像のURL"`
+	CreatedAt time.Time `json:"created_at" doc:"登録日時"`
+	UpdatedAt time.Time `json:"updated_at" doc:"更新日時"`
+}
+
+// apiChat チャット
+type apiChat struct {
+	ID          string      `json:"id" doc:"チャットID"`
+	IsEncrypted bool        `json:"is_encrypted" doc:"エンドツーエンド暗号化が有効かどうか（有効な場合、本文は暗号文）"`
+	Contact     apiUser     `json:"contact" doc:"チャットの相手"`
+	LastMessage *apiMessage `json:"last_message,omitempty" doc:"最新のメッセージ"`
+	IsMuted     bool        `json:"is_muted" doc:"チャットをミュートしているかどうか"`
+	UnreadCount int         `json:"unread_count" doc:"相手からの未読メッセージの数（ミュート中は0）"`
+	UpdatedAt   time.Time   `json:"updated_at" doc:"最新のメッセージの日時"`
+	Bots        []apiUser   `json:"bots,omitempty" doc:"チャットに追加されたボット"`
+}
+
+// apiMessage メッセージ
+type apiMessage struct {
+	ID                  string          `json:"id" doc:"メッセージID"`
+	ChatID              string          `json:"chat_id" doc:"チャットID"`
+	SenderID            string          `json:"sender_id" doc:"送信者のユーザーID"`
+	SenderName          string          `json:"sender_name" doc:"送信者の名前"`
+	SenderIsBot         bool            `json:"sender_is_bot" doc:"送信者がボットかどうか"`
+	SenderIsIntegration bool            `json:"sender_is_integration,omitempty" doc:"受信用Webhookから投稿されたかどうか"`
+	Content             string          `json:"content" doc:"本文（エンドツーエンド暗号化されている場合は暗号文）"`
+	IsEncrypted         bool            `json:"is_encrypted" doc:"エンドツーエンド暗号化されているかどうか"`
+	IsRead              bool            `json:"is_read" doc:"相手が既読にしたかどうか"`
+	ReadBy              []string        `json:"read_by,omitempty" doc:"既読にしたユーザーのID"`
+	CreatedAt           time.Time       `json:"created_at" doc:"送信日時"`
+	EditedAt            *time.Time      `json:"edited_at,omitempty" doc:"編集日時（編集されていない場合は省略）"`
+	Attachments         []apiAttachment `json:"attachments,omitempty" doc:"添付（リンク）"`
+}
+
+// apiAttachment メッセージの添付（リンク）
+type apiAttachment struct {
+	Title string `json:"title,omitempty" doc:"表示名"`
+	URL   string `json:"url" doc:"リンク先のURL"`
+}
+
+// ユーザーを公開プロフィールに変換する
+func toAPIUser(user *domain.User) apiUser {
+	return apiUser{ID: user.ID, Name: user.Name, Icon: user.Icon, IsOnline: user.IsOnline, IsBot: user.IsBot()}
+}
+
+// ユーザーをログイン中のユーザーのプロフィールに変換する
+func toAPIMe(user *domain.User) apiMe {
+	return apiMe{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Icon:      user.Icon,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+// メッセージをレスポンスの形式に変換する
+func toAPIMessage(message domain.Message) apiMessage {
+	result := apiMessage{
+		ID:                  message.ID,
+		ChatID:              message.ChatID,
+		SenderID:            message.SenderID,
+		SenderName:          message.SenderName,
+		SenderIsBot:         message.IsBot,
+		SenderIsIntegration: message.IsIntegration,
+		Content:             message.Content,
+		IsEncrypted:         message.IsEncrypted,
+		IsRead:              message.IsRead,
+		ReadBy:              message.ReadBy,
+		CreatedAt:           message.CreatedAt,
+	}
+	if !message.EditedAt.IsZero() {
+		result.EditedAt = &message.EditedAt
+	}
+	for _, attachment := range message.Attachments {
+		result.Attachments = append(result.Attachments, apiAttachment{Title: attachment.Title, URL: attachment.URL})
+	}
+	return result
+}
+
+// チャットをレスポンスの形式に変換する
+func toAPIChat(chat domain.Chat) apiChat {
+	result := apiChat{
+		ID:          chat.ID,
+		IsEncrypted: chat.IsEncrypted,
+		Contact: apiUser{
+			ID:       chat.Contact.ID,
+			Name:     chat.Contact.Username,
+			Icon:     chat.Contact.Icon,
+			IsOnline: chat.Contact.IsOnline,
+		},
+		IsMuted:     chat.IsMuted,
+		UnreadCount: chat.UnreadCount,
+		UpdatedAt:   chat.UpdatedAt,
+	}
+	for _, bot := range chat.Bots {
+		result.Bots = append(result.Bots, apiUser{ID: bot.ID, Name: bot.Username, Icon: bot.Icon, IsBot: true})
+	}
+	if len(chat.Messages) > 0 {
+		last := toAPIMessage(chat.Messages[len(chat.Messages)-1])
+		result.LastMessage = &last
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/metrics"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// ユーザー登録のリクエスト
+type apiSignupRequest struct {
+	Name     string `json:"name" doc:"ユーザー名"`
+	Email    string `json:"email" doc:"メールアドレス"`
+	Password string `json:"password" doc:"パスワード（8文字以上）"`
+}
+
+// ログインのリクエスト
+type apiLoginRequest struct {
+	Email    string `json:"email" doc:"メールアドレス"`
+	Password string `json:"password" doc:"パスワード"`
+}
+
+// ユーザーを登録し、そのままログインする
+func apiSignup(w http.ResponseWriter, r *http.Request) error {
+	var req apiSignupRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	form := domain.SignupForm{Name: req.Name, Email: domain.NormalizeEmail(req.Email), Password: req.Password}
+
+	if validationErrors := validateSignupForm(form); len(validationErrors) > 0 {
+		return domain.NewValidationError(strings.Join(validationErrors, "、"), nil)
+	}
+	exists, err := checkEmailDuplicate(form.Email)
+	if err != nil {
+		return domain.NewInternalError("ユーザーの登録に失敗しました", err)
+	}
+	if exists {
+		slog.InfoContext(r.Context(), "メールアドレス重複エラー", "email", form.Email)
+		return domain.NewConflictError("このメールアドレスは既に登録されています", nil)
+	}
+
+	user, err := createAndSaveUser(form)
+	if err != nil {
+		return domain.NewInternalError("ユーザーの登録に失敗しました", err)
+	}
+	if _, err := middleware.RotateSession(w, r, user); err != nil {
+		return domain.NewInternalError("セッションの作成に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusCreated, toAPIMe(user))
+}
+
+// メールアドレスとパスワードでログインし、セッションクッキーを発行する
+func apiLogin(w http.ResponseWriter, r *http.Request) error {
+	var req apiLoginRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		metrics.LoginFailed(metrics.LoginFailureValidation)
+		return err
+	}
+	if req.Email == "" || req.Password == "" {
+		metrics.LoginFailed(metrics.LoginFailureValidation)
+		return domain.NewValidationError("メールアドレスとパスワードを入力してください", nil)
+	}
+
+	user, err := repository.GetUserByEmail(req.Email)
+	if err != nil {
+		metrics.LoginFailed(metrics.LoginFailureError)
+		return domain.NewInternalError("認証エラーが発生しました", err)
+	}
+	if user == nil || !uuid.VerifyPassword(user.Password, req.Password) {
+		metrics.LoginFailed(metrics.LoginFailureCredentials)
+		recordLoginFailure(r, user, req.Email, metrics.LoginFailureCredentials)
+		return domain.NewUnauthorizedError("メールアドレスまたはパスワードが誤っています", nil)
+	}
+	if user.Suspended {
+		metrics.LoginFailed(metrics.LoginFailureSuspended)
+		recordLoginFailure(r, user, req.Email, metrics.LoginFailureSuspended)
+		return domain.NewForbiddenError("このアカウントは停止されています", nil)
+	}
+	if user.PasswordResetRequired {
+		metrics.LoginFailed(metrics.LoginFailurePasswordReset)
+		recordLoginFailure(r, user, req.Email, metrics.LoginFailurePasswordReset)
+		return domain.NewForbiddenError("パスワードの再設定が必要です", nil)
+	}
+
+	if _, err := middleware.RotateSession(w, r, user); err != nil {
+		metrics.LoginFailed(metrics.LoginFailureError)
+		return domain.NewInternalError("セッションの作成に失敗しました", err)
+	}
+	recordAudit(r, domain.AuditLoginSucceeded, user.ID, user.ID, map[string]string{"via": "api"})
+	return writeAPIResponse(w, http.StatusOK, toAPIMe(user))
+}
+
+// セッションを破棄してログアウトする
+func apiLogout(w http.ResponseWriter, r *http.Request) error {
+	user := middleware.CurrentUser(r)
+	if err := repository.MarkUserOffline(user.ID); err != nil {
+		slog.ErrorContext(r.Context(), "ユーザー状態の更新に失敗", "error", err)
+	}
+	if err := middleware.DeleteSession(w, r); err != nil {
+		return domain.NewInternalError("ログアウトに失敗しました", err)
+	}
+	recordAudit(r, domain.AuditLogout, user.ID, user.ID, map[string]string{"via": "api"})
+	return writeAPIResponse(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// チャット開始のリクエスト
+type apiStartChatRequest struct {
+	UserID string `json:"user_id" doc:"チャットの相手のユーザーID"`
+}
+
+// メッセージ送信のリクエスト
+type apiSendMessageRequest struct {
+	Content string `json:"content" doc:"本文（暗号化チャットでは暗号文）"`
+}
+
+// 既読にした結果
+type apiReadResult struct {
+	Count int `json:"count" doc:"新たに既読にしたメッセージの数"`
+}
+
+// 参加しているチャットの一覧を返す
+func apiListChats(w http.ResponseWriter, r *http.Request) error {
+	page, err := parseAPIPage(r, cursorChats)
+	if err != nil {
+		return err
+	}
+	user := middleware.CurrentUser(r)
+
+	chats, err := getChatHistory(r.Context(), user)
+	if err != nil {
+		return domain.NewInternalError("チャット履歴の取得に失敗しました", err)
+	}
+	result := make([]apiChat, 0, len(chats))
+	for _, chat := range chats {
+		result = append(result, toAPIChat(chat))
+	}
+
+	// 更新日時の新しい順（同じ日時はIDの降順）に並べ、カーソルより後を返す
+	slices.SortFunc(result, compareAPIChats)
+	return writeAPIResponse(w, http.StatusOK, paginate(result, page, apiChatCursor, apiChatIsAfter))
+}
+
+// チャットの一覧の並び順（更新日時の新しい順、同じ日時はIDの降順）
+func compareAPIChats(a, b apiChat) int {
+	if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(b.ID, a.ID)
+}
+
+// チャットの一覧のカーソル
+func apiChatCursor(c apiChat) apiCursor {
+	return apiCursor{Time: c.UpdatedAt.UnixNano(), ID: c.ID}
+}
+
+// チャットが一覧でカーソルより後に並ぶかどうか
+func apiChatIsAfter(c apiChat, after apiCursor) bool {
+	t := c.UpdatedAt.UnixNano()
+	return t < after.Time || (t == after.Time && c.ID < after.ID)
+}
+
+// ユーザーとのチャットを開始する
+// 同じ相手とのチャットが既にある場合は新しく作らずにそのチャットを返す
+func apiStartChat(w http.ResponseWriter, r *http.Request) error {
+	var req apiStartChatRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	user := middleware.CurrentUser(r)
+	if user.IsBot() {
+		return domain.NewForbiddenError("ボットはチャットを開始できません", nil)
+	}
+
+	if req.UserID == "" {
+		return domain.NewValidationError("ユーザーIDが指定されていません", nil)
+	}
+	if req.UserID == user.ID {
+		return domain.NewValidationError("自分自身とはチャットを開始できません", nil)
+	}
+	target, err := GetUserData(req.UserID)
+	if err != nil {
+		return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+	}
+	existing, err := prepareStartChat(r.Context(), user, target)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return writeAPIResponse(w, http.StatusOK, toAPIChat(*existing))
+	}
+
+	chatID, err := firebase.StartChat(user.ID, target.ID)
+	if err != nil {
+		return domain.NewInternalError("チャットの開始に失敗しました", err)
+	}
+	dispatchChatStarted(r.Context(), chatID, user, target)
+	recordAudit(r, domain.AuditChatMemberAdded, user.ID, target.ID, map[string]string{"chat_id": chatID})
+	chat := domain.Chat{
+		ID: chatID,
+		Contact: domain.Contact{
+			ID:       target.ID,
+			Username: target.Name,
+			Icon:     target.Icon,
+			IsOnline: target.IsOnline,
+		},
+		UpdatedAt: time.Now(),
+	}
+	return writeAPIResponse(w, http.StatusCreated, toAPIChat(chat))
+}
+
+// チャットを返す
+func apiGetChat(w http.ResponseWriter, r *http.Request) error {
+	user := middleware.CurrentUser(r)
+	chatID := r.PathValue("id")
+
+	if _, err := requireChatParticipant(chatID, user.ID); err != nil {
+		return err
+	}
+	chats, err := getChatHistory(r.Context(), user)
+	if err != nil {
+		return domain.NewInternalError("チャット履歴の取得に失敗しました", err)
+	}
+	for _, chat := range chats {
+		if chat.ID == chatID {
+			return writeAPIResponse(w, http.StatusOK, toAPIChat(chat))
+		}
+	}
+	return domain.NewNotFoundError("チャットが見つかりません", nil)
+}
+
+// チャットのメッセージを新しい順に返す
+func apiListMessages(w http.ResponseWriter, r *http.Request) error {
+	page, err := parseAPIPage(r, cursorMessages)
+	if err != nil {
+		return err
+	}
+	user := middleware.CurrentUser(r)
+	chatID := r.PathValue("id")
+
+	if _, err := requireChatParticipant(chatID, user.ID); err != nil {
+		return err
+	}
+
+	// 次のページの有無を判定するため、1件多く取得する
+	var before time.Time
+	var beforeID string
+	if page.after != nil {
+		before, beforeID = time.Unix(0, page.after.Time), page.after.ID
+	}
+	messagesData, err := firebase.GetChatMessagesBefore(r.Context(), chatID, before, beforeID, page.limit+1)
+	if err != nil {
+		return domain.NewInternalError("メッセージの取得に失敗しました", err)
+	}
+
+	messages := make([]apiMessage, 0, len(messagesData))
+	for _, msg := range messagesData {
+		messages = append(messages, toAPIMessage(messageFromData(chatID, msg)))
+	}
+	return writeAPIResponse(w, http.StatusOK, newAPIList(messages, page, func(m apiMessage) apiCursor {
+		return apiCursor{Time: m.CreatedAt.UnixNano(), ID: m.ID}
+	}))
+}
+
+// メッセージを送信する
+func apiSendMessage(w http.ResponseWriter, r *http.Request) error {
+	var req apiSendMessageRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	message, err := sendChatMessage(r.Context(), middleware.CurrentUser(r), r.PathValue("id"), req.Content)
+	if err != nil {
+		return err
+	}
+	return writeAPIResponse(w, http.StatusCreated, toAPIMessage(*message))
+}
+
+// 自分が送信したメッセージの本文を編集する
+func apiEditMessage(w http.ResponseWriter, r *http.Request) error {
+	var req apiSendMessageRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	message, err := editChatMessage(r.Context(), middleware.CurrentUser(r), r.PathValue("id"), r.PathValue("message_id"), req.Content)
+	if err != nil {
+		return err
+	}
+	return writeAPIResponse(w, http.StatusOK, toAPIMessage(*message))
+}
+
+// チャットの相手からのメッセージをすべて既読にする
+func apiMarkRead(w http.ResponseWriter, r *http.Request) error {
+	user := middleware.CurrentUser(r)
+	chatID := r.PathValue("id")
+
+	// ボットは既読を付けない
+	if user.IsBot() {
+		return domain.NewForbiddenError("ボットは既読を付けられません", nil)
+	}
+	if _, err := requireChatParticipant(chatID, user.ID); err != nil {
+		return err
+	}
+	count, err := firebase.MarkChatMessagesRead(r.Context(), chatID, user.ID)
+	if err != nil {
+		return domain.NewInternalError("既読の更新に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusOK, apiReadResult{Count: count})
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"security_chat_app/internal/interface/middleware"
+	"security_chat_app/internal/utils/buildinfo"
+)
+
+// OpenAPI ドキュメントのパス（APIPrefix からの相対パス）
+const OpenAPIPath = "/openapi.json"
+
+// パスパラメータ（{id} など）
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// ルートの定義は起動後に変わらないため、ドキュメントは初回に一度だけ生成する
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument map[string]any
+)
+
+// OpenAPIHandler APIRoutes から生成した OpenAPI 3 のドキュメントを返す
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIDocument = buildOpenAPI(APIRoutes())
+	})
+	writeJSON(w, http.StatusOK, openAPIDocument)
+}
+
+// OpenAPI のスキーマを型から組み立てる
+type openAPISchemas struct {
+	components map[string]any
+}
+
+// ドキュメントを組み立てる
+func buildOpenAPI(routes []APIRoute) map[string]any {
+	schemas := &openAPISchemas{components: map[string]any{}}
+	errorResponse := map[string]any{
+		"description": "エラー",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schemas.ref(reflect.TypeOf(middleware.APIError{}))},
+		},
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		path := APIPrefix + route.Path
+		operations, _ := paths[path].(map[string]any)
+		if operations == nil {
+			operations = map[string]any{}
+			paths[path] = operations
+		}
+		operations[strings.ToLower(route.Method)] = schemas.operation(route, errorResponse)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Security Chat API",
+			"version":     buildinfo.Get().Version,
+			"description": "JSONでチャットを操作するためのAPI。POST " + APIPrefix + "/auth/login で発行されたセッションクッキー、または Authorization: Bearer の個人用アクセストークンで認証する。",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": middleware.SessionCookieName()},
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "description": "/settings で発行した個人用アクセストークン"},
+			},
+		},
+		"security": []any{map[string]any{"cookieAuth": []string{}}},
+	}
+}
+
+// エンドポイントの定義を組み立てる
+func (s *openAPISchemas) operation(route APIRoute, errorResponse map[string]any) map[string]any {
+	op := map[string]any{
+		"summary":     route.Summary,
+		"tags":        []string{route.Tag},
+		"operationId": operationID(route),
+	}
+	switch {
+	case route.Public:
+		op["security"] = []any{}
+	case route.Scope != "":
+		op["description"] = "APIトークンで呼び出す場合は " + route.Scope + " のスコープが必要です。"
+		op["security"] = []any{map[string]any{"cookieAuth": []string{}}, map[string]any{"bearerAuth": []string{}}}
+	default:
+		op["description"] = "セッションでのみ呼び出せます（APIトークンでは呼び出せません）。"
+	}
+
+	var params []any
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		params = append(params, map[string]any{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, q := range route.Query {
+		params = append(params, map[string]any{
+			"name": q.Name, "in": "query", "required": q.Required, "description": q.Description,
+			"schema": map[string]any{"type": q.Type},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if route.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": s.ref(reflect.TypeOf(route.Request))},
+			},
+		}
+	} else if route.Multipart != "" {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{"schema": map[string]any{
+					"type":       "object",
+					"required":   []string{route.Multipart},
+					"properties": map[string]any{route.Multipart: map[string]any{"type": "string", "format": "binary"}},
+				}},
+			},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if route.Response == nil {
+		status = http.StatusNoContent
+		success["description"] = http.StatusText(status)
+	} else {
+		schema := s.ref(reflect.TypeOf(route.Response))
+		if route.Paginated {
+			schema = map[string]any{
+				"type":     "object",
+				"required": []string{"data"},
+				"properties": map[string]any{
+					"data":        map[string]any{"type": "array", "items": schema},
+					"next_cursor": map[string]any{"type": "string", "description": "次のページのカーソル（最後のページでは省略）"},
+				},
+			}
+		}
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	}
+	op["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default":            errorResponse,
+	}
+	return op
+}
+
+// 型のスキーマを components に登録し、参照を返す
+func (s *openAPISchemas) ref(t reflect.Type) map[string]any {
+	name := schemaName(t)
+	if _, ok := s.components[name]; !ok {
+		// 再帰的な型に備えて先に登録しておく
+		s.components[name] = nil
+		s.components[name] = s.object(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// 構造体のスキーマを組み立てる
+func (s *openAPISchemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := s.schema(field.Type)
+		if doc := field.Tag.Get("doc"); doc != "" {
+			if _, isRef := schema["$ref"]; isRef {
+				// $ref と並べた項目は無視されるため allOf で包む
+				schema = map[string]any{"allOf": []any{schema}, "description": doc}
+			} else {
+				schema["description"] = doc
+			}
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	object := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// 型のスキーマを組み立てる
+func (s *openAPISchemas) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.ref(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// スキーマ名は型名から api 接頭辞を除いたもの（apiChat → Chat）
+func schemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// operationId はメソッドとパスから組み立てる（例: GET /chats/{id}/messages → getChatsIdMessages）
+func operationID(route APIRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"security_chat_app/internal/interface/middleware"
+)
+
+// クエリパラメータを付けたリクエストから一覧の取得範囲を読み込む
+func parseTestPage(t *testing.T, kind string, query url.Values) (apiPage, error) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/chats?"+query.Encode(), nil)
+	return parseAPIPage(r, kind)
+}
+
+// エラーをAPIのレスポンスにした時のステータスコード
+func apiErrorStatus(t *testing.T, err error) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	middleware.WriteAPIError(w, httptest.NewRequest(http.MethodGet, "/api/v1/chats", nil), err)
+	return w.Code
+}
+
+func TestPaginateBreaksTimestampTiesByID(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	chats := []apiChat{
+		{ID: "a", UpdatedAt: base},
+		{ID: "e", UpdatedAt: base},
+		{ID: "c", UpdatedAt: base},
+		{ID: "z", UpdatedAt: base.Add(-time.Second)},
+		{ID: "d", UpdatedAt: base},
+		{ID: "b", UpdatedAt: base},
+		{ID: "y", UpdatedAt: base.Add(time.Second)},
+	}
+	slices.SortFunc(chats, compareAPIChats)
+	want := []string{"y", "e", "d", "c", "b", "a", "z"}
+
+	// 同じ日時のチャットがページの境目をまたいでも、重複や抜けなく全件を辿れる
+	for _, limit := range []int{1, 2, 3, 6, 7, 100} {
+		var got []string
+		query := url.Values{"limit": {strconv.Itoa(limit)}}
+		for pages := 0; ; pages++ {
+			if pages > len(chats) {
+				t.Fatalf("limit %d: pagination did not end", limit)
+			}
+			page, err := parseTestPage(t, cursorChats, query)
+			if err != nil {
+				t.Fatalf("limit %d: parseAPIPage() error = %v", limit, err)
+			}
+			list := paginate(chats, page, apiChatCursor, apiChatIsAfter)
+			if len(list.Data) > limit {
+				t.Fatalf("limit %d: page has %d items", limit, len(list.Data))
+			}
+			for _, chat := range list.Data {
+				got = append(got, chat.ID)
+			}
+			if list.NextCursor == "" {
+				break
+			}
+			query.Set("cursor", list.NextCursor)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("limit %d: got %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func TestNewAPIListNextCursor(t *testing.T) {
+	page := apiPage{kind: cursorMessages, limit: 2}
+	cursorOf := func(id string) apiCursor { return apiCursor{ID: id} }
+
+	tests := []struct {
+		name       string
+		items      []string
+		wantData   []string
+		wantCursor string // 次のページのカーソルが指す要素のID（空の場合はカーソルなし）
+	}{
+		{"empty", nil, []string{}, ""},
+		{"fewer than the limit", []string{"a"}, []string{"a"}, ""},
+		{"exactly the limit is the last page", []string{"a", "b"}, []string{"a", "b"}, ""},
+		{"more than the limit", []string{"a", "b", "c"}, []string{"a", "b"}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := newAPIList(tt.items, page, cursorOf)
+			if !slices.Equal(list.Data, tt.wantData) || list.Data == nil {
+				t.Fatalf("Data = %#v, want %#v", list.Data, tt.wantData)
+			}
+			if tt.wantCursor == "" {
+				if list.NextCursor != "" {
+					t.Fatalf("NextCursor = %q on the last page, want none", list.NextCursor)
+				}
+				body, _ := json.Marshal(list)
+				if strings.Contains(string(body), "next_cursor") {
+					t.Fatalf("response %s contains next_cursor on the last page", body)
+				}
+				return
+			}
+			cursor, err := decodeCursor(list.NextCursor)
+			if err != nil {
+				t.Fatalf("decodeCursor(%q) error = %v", list.NextCursor, err)
+			}
+			if cursor.ID != tt.wantCursor || cursor.Kind != cursorMessages {
+				t.Fatalf("NextCursor = %+v, want ID %q of kind %q", cursor, tt.wantCursor, cursorMessages)
+			}
+		})
+	}
+}
+
+func TestParseAPIPageRejectsInvalidCursors(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not json", encode("chat-1")},
+		{"missing id", encode(`{"k":"chats","t":1}`)},
+		{"missing kind", encode(`{"t":1,"i":"a"}`)},
+		{"unknown field", encode(`{"k":"chats","i":"a","offset":10}`)},
+		{"cursor of another list", encodeCursor(apiCursor{Kind: cursorMessages, Time: 1, ID: "a"})},
+		{"cursor of the users list", encodeCursor(apiCursor{Kind: cursorUsers, Name: "alice", ID: "a"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTestPage(t, cursorChats, url.Values{"cursor": {tt.cursor}})
+			if err == nil {
+				t.Fatal("parseAPIPage() accepted the cursor")
+			}
+			if status := apiErrorStatus(t, err); status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", status)
+			}
+		})
+	}
+
+	// 同じ種類の一覧が発行したカーソルは受け付ける
+	valid := encodeCursor(apiCursor{Kind: cursorChats, Time: 1, ID: "a"})
+	page, err := parseTestPage(t, cursorChats, url.Values{"cursor": {valid}})
+	if err != nil || page.after == nil || page.after.ID != "a" || page.after.Time != 1 {
+		t.Fatalf("parseAPIPage() = %+v, %v; want the decoded cursor", page.after, err)
+	}
+}
+
+func TestParseAPIPageLimit(t *testing.T) {
+	tests := []struct {
+		limit   string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultPageSize, false},
+		{"1", 1, false},
+		{"100", maxPageSize, false},
+		{"0", 0, true},
+		{"101", 0, true},
+		{"-1", 0, true},
+		{"ten", 0, true},
+		{"1.5", 0, true},
+	}
+	for _, tt := range tests {
+		query := url.Values{}
+		if tt.limit != "" {
+			query.Set("limit", tt.limit)
+		}
+		page, err := parseTestPage(t, cursorChats, query)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("limit=%q: parseAPIPage() accepted the limit", tt.limit)
+			} else if status := apiErrorStatus(t, err); status != http.StatusBadRequest {
+				t.Errorf("limit=%q: status = %d, want 400", tt.limit, status)
+			}
+			continue
+		}
+		if err != nil || page.limit != tt.want {
+			t.Errorf("limit=%q: parseAPIPage() = %d, %v; want %d", tt.limit, page.limit, err, tt.want)
+		}
+	}
+}
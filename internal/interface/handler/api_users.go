@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// プロフィール更新のリクエスト（指定した項目のみ更新する）
+type apiUpdateProfileRequest struct {
+	Name *string `json:"name,omitempty" doc:"新しいユーザー名（2〜20文字）"`
+}
+
+// パスワード変更のリクエスト
+type apiChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" doc:"現在のパスワード"`
+	NewPassword     string `json:"new_password" doc:"新しいパスワード（8文字以上）"`
+}
+
+// ログイン中のユーザーのプロフィールを返す
+func apiGetMe(w http.ResponseWriter, r *http.Request) error {
+	user, err := repository.GetUserByID(middleware.CurrentUser(r).ID)
+	if err != nil {
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusOK, toAPIMe(user))
+}
+
+// ログイン中のユーザーのプロフィールを更新する
+func apiUpdateMe(w http.ResponseWriter, r *http.Request) error {
+	var req apiUpdateProfileRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	current := middleware.CurrentUser(r)
+	userID := current.ID
+
+	if req.Name != nil {
+		if message := validateUsername(*req.Name); message != "" {
+			return domain.NewValidationError(message, nil)
+		}
+		if err := repository.UpdateUserField(userID, "Name", *req.Name); err != nil {
+			return domain.NewInternalError("ユーザー名の更新に失敗しました", err)
+		}
+		recordAudit(r, domain.AuditUsernameChanged, userID, userID, map[string]string{"old_name": current.Name, "new_name": *req.Name, "via": "api"})
+	}
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusOK, toAPIMe(user))
+}
+
+// パスワードを変更し、操作中のクライアントのみ新しいセッションでログイン状態を維持する
+func apiChangePassword(w http.ResponseWriter, r *http.Request) error {
+	var req apiChangePasswordRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	current := middleware.CurrentUser(r)
+
+	if len(req.NewPassword) < 8 {
+		return domain.NewValidationError("パスワードは8文字以上で入力してください", nil)
+	}
+	if !uuid.VerifyPassword(current.Password, req.CurrentPassword) {
+		return domain.NewValidationError("現在のパスワードが正しくありません", nil)
+	}
+
+	hashedPassword, err := uuid.HashPassword(req.NewPassword)
+	if err != nil {
+		return domain.NewInternalError("パスワードの更新に失敗しました", err)
+	}
+	if err := repository.UpdateUserCredential(current.ID, "Password", hashedPassword); err != nil {
+		return domain.NewInternalError("パスワードの更新に失敗しました", err)
+	}
+	recordAudit(r, domain.AuditPasswordChanged, current.ID, current.ID, map[string]string{"via": "api"})
+
+	user, err := repository.GetUserByID(current.ID)
+	if err != nil {
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
+	}
+	if _, err := middleware.RotateSession(w, r, user); err != nil {
+		return domain.NewInternalError("セッションの再発行に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusNoContent, nil)
+}
+
+// アイコン画像を変更する（multipart/form-data の icon フィールド）
+func apiUpdateIcon(w http.ResponseWriter, r *http.Request) error {
+	userID := middleware.CurrentUser(r).ID
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		return domain.NewValidationError("multipart/form-data の形式で icon を送信してください", err)
+	}
+	file, header, err := r.FormFile("icon")
+	if err != nil {
+		return domain.NewValidationError("アイコンファイルを選択してください", err)
+	}
+	defer file.Close()
+
+	icon, err := saveProfileIcon(r.Context(), userID, file, header)
+	if err != nil {
+		return err
+	}
+	recordAudit(r, domain.AuditIconChanged, userID, userID, map[string]string{"icon": icon, "via": "api"})
+
+	user, err := repository.GetUserByID(userID)
+	if err != nil {
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusOK, toAPIMe(user))
+}
+
+// ユーザー名で検索する（自分とボット、自分をブロックしているユーザーは含まない）
+func apiSearchUsers(w http.ResponseWriter, r *http.Request) error {
+	page, err := parseAPIPage(r, cursorUsers)
+	if err != nil {
+		return err
+	}
+	userID := middleware.CurrentUser(r).ID
+
+	blockerIDs, err := repository.GetBlockerIDs(userID)
+	if err != nil {
+		return domain.NewInternalError("ブロックの取得に失敗しました", err)
+	}
+
+	// 名前とIDの順にカーソルより後のユーザーを読み込み、次のページの有無を判定するため1件多く集める
+	// 名前は大文字小文字を区別せずに部分一致で絞り込む
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	var afterName, afterID string
+	if page.after != nil {
+		afterName, afterID = page.after.Name, page.after.ID
+	}
+	users := []apiUser{}
+	err = firebase.ScanUsersByName(r.Context(), afterName, afterID, func(data map[string]interface{}) bool {
+		user, ok := apiUserFromData(data)
+		if !ok || user.ID == userID || user.IsBot || blockerIDs[user.ID] {
+			return true
+		}
+		if query != "" && !strings.Contains(strings.ToLower(user.Name), query) {
+			return true
+		}
+		users = append(users, user)
+		return len(users) <= page.limit
+	})
+	if err != nil {
+		return domain.NewInternalError("ユーザーの検索に失敗しました", err)
+	}
+	return writeAPIResponse(w, http.StatusOK, newAPIList(users, page, func(u apiUser) apiCursor {
+		return apiCursor{Name: u.Name, ID: u.ID}
+	}))
+}
+
+// ユーザーの公開プロフィールを返す（自分をブロックしているユーザーのオンライン状態は返さない）
+func apiGetUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := repository.GetUserByID(r.PathValue("id"))
+	if err != nil {
+		return domain.NewNotFoundError("ユーザーが見つかりません", err)
+	}
+	result := toAPIUser(user)
+	if result.IsOnline {
+		_, blockedBy, err := repository.GetBlockState(middleware.CurrentUser(r).ID, user.ID)
+		if err != nil {
+			return domain.NewInternalError("ブロックの確認に失敗しました", err)
+		}
+		result.IsOnline = !blockedBy
+	}
+	return writeAPIResponse(w, http.StatusOK, result)
+}
+
+// Firestoreのユーザーのデータを公開プロフィールに変換する
+func apiUserFromData(data map[string]interface{}) (apiUser, bool) {
+	id, ok := data["ID"].(string)
+	if !ok {
+		if id, ok = data["id"].(string); !ok {
+			return apiUser{}, false
+		}
+	}
+	user := apiUser{ID: id}
+	user.Name, _ = data["Name"].(string)
+	user.Icon, _ = data["Icon"].(string)
+	user.IsOnline, _ = data["IsOnline"].(bool)
+	user.IsBot = data["Type"] == domain.UserTypeBot
+	return user, true
+}
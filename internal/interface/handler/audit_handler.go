@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 監査ログのページに表示する最大件数
+const auditEventsLimit = 200
+
+// 監査ログのページのデータ構造体（設定ページの自分の記録・管理画面の全体の記録で共通）
+type AuditPageData struct {
+	IsLoggedIn  bool                 // ログイン状態
+	User        *domain.User         // ユーザー情報
+	IsAdminView bool                 // 管理画面（全ユーザーの記録）かどうか
+	Events      []AuditEventView     // 監査ログ（新しい順）
+	Actions     []domain.AuditAction // 絞り込みに使える操作（管理画面のみ）
+	Action      string               // 絞り込んでいる操作（管理画面のみ）
+	Query       string               // 絞り込んでいるユーザーのIDまたはメールアドレス（管理画面のみ）
+	Limit       int                  // 表示する最大件数
+	Error       string               // 絞り込みのエラー
+}
+
+// 画面に表示する監査ログ
+type AuditEventView struct {
+	domain.AuditEvent
+	ActionLabel string   // 操作の表示名
+	ActorName   string   // 操作したユーザーの名前
+	TargetName  string   // 操作の対象のユーザーの名前
+	Details     []string // 詳細（"キー: 値" の形式、キーの順）
+}
+
+// 監査ログに記録する
+// 記録に失敗しても操作自体は取り消さず、エラーをログに残す
+func recordAudit(r *http.Request, action, actorID, targetID string, metadata map[string]string) {
+	event := &domain.AuditEvent{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+		IP:       middleware.ClientIP(r),
+		Metadata: metadata,
+	}
+	if err := repository.RecordAuditEvent(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "監査ログの記録に失敗", "error", err, "action", action, "user_id", actorID, "target_user_id", targetID)
+	}
+}
+
+// 自分のアカウントの監査ログ（設定ページ）のハンドラ
+// 自分の操作と、自分が対象の操作（管理者による操作・ログインの失敗など）を表示する
+func AuditSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+
+	events, err := repository.GetUserAuditEvents(r.Context(), session.User.ID, auditEventsLimit)
+	if err != nil {
+		return domain.NewInternalError("監査ログの取得に失敗しました", err)
+	}
+	data := AuditPageData{
+		IsLoggedIn: true,
+		User:       session.User,
+		Events:     newAuditEventViews(events, session.User, false),
+		Limit:      auditEventsLimit,
+	}
+	return markup.GenerateHTML(w, data, "layout", "header", "audit", "footer")
+}
+
+// 全ユーザーの監査ログのハンドラ（管理者のみ）
+// ?action= で操作、?user= でユーザー（IDまたはメールアドレス）を絞り込む
+func AdminAuditHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return domain.NewMethodNotAllowedError()
+	}
+	admin := middleware.SessionUser(r)
+
+	data := AuditPageData{
+		IsLoggedIn:  true,
+		User:        admin,
+		IsAdminView: true,
+		Actions:     domain.AuditActions,
+		Action:      r.URL.Query().Get("action"),
+		Query:       strings.TrimSpace(r.URL.Query().Get("user")),
+		Limit:       auditEventsLimit,
+	}
+
+	var events []domain.AuditEvent
+	var err error
+	if data.Query != "" {
+		userID, findErr := findAuditUserID(data.Query)
+		if findErr != nil {
+			return findErr
+		}
+		if userID == "" {
+			data.Error = "ユーザーが見つかりません"
+			return markup.GenerateHTML(w, data, "layout", "header", "audit", "footer")
+		}
+		events, err = repository.GetUserAuditEvents(r.Context(), userID, auditEventsLimit)
+		if data.Action != "" {
+			events = slices.DeleteFunc(events, func(event domain.AuditEvent) bool { return event.Action != data.Action })
+		}
+	} else {
+		events, err = repository.GetAuditEvents(r.Context(), data.Action, auditEventsLimit)
+	}
+	if err != nil {
+		return domain.NewInternalError("監査ログの取得に失敗しました", err)
+	}
+	data.Events = newAuditEventViews(events, admin, true)
+	return markup.GenerateHTML(w, data, "layout", "header", "audit", "footer")
+}
+
+// 絞り込みに指定したユーザー（IDまたはメールアドレス）のIDを返す（見つからない場合は空）
+// 削除されたユーザーの記録も確認できるよう、IDはユーザーが存在しなくてもそのまま使う
+func findAuditUserID(query string) (string, error) {
+	if !strings.Contains(query, "@") {
+		return query, nil
+	}
+	user, err := repository.GetUserByEmail(query)
+	if err != nil {
+		return "", domain.NewInternalError("ユーザーの取得に失敗しました", err)
+	}
+	if user == nil {
+		return "", nil
+	}
+	return user.ID, nil
+}
+
+// 監査ログを表示用に変換する
+// 自分の記録では、他のユーザーが自分を対象にした管理者の操作は操作した管理者を伏せる
+func newAuditEventViews(events []domain.AuditEvent, viewer *domain.User, adminView bool) []AuditEventView {
+	names := userNameResolver()
+	views := make([]AuditEventView, 0, len(events))
+	for _, event := range events {
+		view := AuditEventView{
+			AuditEvent:  event,
+			ActionLabel: domain.AuditActionLabel(event.Action),
+			ActorName:   auditActorName(event, names),
+		}
+		if event.TargetID != "" {
+			view.TargetName = names(event.TargetID)
+		}
+		if !adminView && event.ActorID != "" && event.ActorID != viewer.ID &&
+			event.Action != domain.AuditChatMemberAdded && event.Action != domain.AuditChatMemberRemoved {
+			view.ActorName = "管理者"
+		}
+
+		keys := make([]string, 0, len(event.Metadata))
+		for key := range event.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			view.Details = append(view.Details, key+": "+event.Metadata[key])
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// 監査ログの操作したユーザーの表示名
+func auditActorName(event domain.AuditEvent, names func(string) string) string {
+	switch {
+	case event.ActorID != "":
+		return names(event.ActorID)
+	case event.Metadata["source"] == "cli":
+		return "管理用コマンド"
+	case event.Metadata["reason"] == "scheduled":
+		return "予約による自動処理"
+	}
+	return "-"
+}
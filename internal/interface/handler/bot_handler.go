@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/infrastructure/webhook"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// ロングポーリングの待機時間
+const (
+	defaultBotPollTimeout = 25 * time.Second
+	maxBotPollTimeout     = 50 * time.Second
+	botPollInterval       = 3 * time.Second // 他のインスタンスで送信されたメッセージを確認する間隔
+)
+
+// apiEvent チャットのイベント（ボット・Webhook・ロングポーリングで共通の形式）
+type apiEvent struct {
+	ID        string      `json:"id" doc:"イベントID"`
+	Type      string      `json:"type" doc:"イベントの種類（message.created / message.edited / member.joined）"`
+	ChatID    string      `json:"chat_id" doc:"チャットID"`
+	Message   *apiMessage `json:"message,omitempty" doc:"メッセージ（message.created / message.edited）"`
+	Member    *apiUser    `json:"member,omitempty" doc:"参加したユーザー（member.joined）"`
+	CreatedAt time.Time   `json:"created_at" doc:"イベントの発生日時"`
+}
+
+// ロングポーリングの結果
+type apiBotUpdates struct {
+	Events []apiEvent `json:"events" doc:"cursor より後に届いたイベント（古い順）"`
+	Cursor string     `json:"cursor" doc:"次のリクエストの cursor に指定する値"`
+}
+
+// チャットにボットを追加するリクエスト
+type apiAddChatBotRequest struct {
+	BotID string `json:"bot_id" doc:"追加するボットのユーザーID（自分が作成したボットのみ）"`
+}
+
+// ロングポーリングのクエリパラメータ
+var botUpdatesParams = []APIParam{
+	{Name: "cursor", Description: "前のレスポンスの cursor（省略した場合は呼び出した時点以降のメッセージを待つ）", Type: "string"},
+	{Name: "timeout", Description: "メッセージが届くまで待つ秒数（0〜50、既定は25）", Type: "integer"},
+}
+
+// ロングポーリングで待機しているボットに新しいメッセージを知らせる
+// 同じインスタンスで送信されたメッセージはすぐに、それ以外は botPollInterval ごとの確認で届く
+var botWaiters = struct {
+	sync.Mutex
+	channels map[string]map[chan struct{}]struct{}
+}{channels: make(map[string]map[chan struct{}]struct{})}
+
+// ボットへの通知を待つチャネルを登録する
+func waitBotUpdates(botID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	botWaiters.Lock()
+	if botWaiters.channels[botID] == nil {
+		botWaiters.channels[botID] = make(map[chan struct{}]struct{})
+	}
+	botWaiters.channels[botID][ch] = struct{}{}
+	botWaiters.Unlock()
+
+	return ch, func() {
+		botWaiters.Lock()
+		delete(botWaiters.channels[botID], ch)
+		if len(botWaiters.channels[botID]) == 0 {
+			delete(botWaiters.channels, botID)
+		}
+		botWaiters.Unlock()
+	}
+}
+
+// 待機しているボットに通知する
+func wakeBot(botID string) {
+	botWaiters.Lock()
+	defer botWaiters.Unlock()
+	for ch := range botWaiters.channels[botID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// チャットに追加されたボットに新しいメッセージを届ける
+// 送信のレスポンスを遅らせないよう、ボットの取得とWebhookの送信は非同期で行う
+func notifyChatBots(ctx context.Context, chatID string, message domain.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		botIDs, err := firebase.GetChatBots(chatID)
+		if err != nil {
+			slog.ErrorContext(ctx, "チャットのボットの取得に失敗", "error", err, "chat_id", chatID)
+			return
+		}
+		for _, botID := range botIDs {
+			// ボット自身が送信したメッセージは届けない
+			if botID == message.SenderID {
+				continue
+			}
+			wakeBot(botID)
+
+			bot, err := repository.GetUserByID(botID)
+			if err != nil {
+				slog.ErrorContext(ctx, "ボットの取得に失敗", "error", err, "bot_id", botID)
+				continue
+			}
+			if bot.WebhookURL != "" {
+				deliverBotWebhook(ctx, bot, newMessageEvent(domain.EventMessageCreated, message))
+			}
+		}
+	}()
+}
+
+// ボットのWebhookにイベントを送信する
+func deliverBotWebhook(ctx context.Context, bot *domain.User, event apiEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "Webhookの本文の作成に失敗", "error", err)
+		return
+	}
+	webhook.Deliver(ctx, bot.WebhookURL, bot.WebhookSecret, event.Type, body, func(attempt webhook.Attempt) bool {
+		if attempt.Err != nil {
+			slog.WarnContext(ctx, "ボットのWebhookの送信に失敗", "error", attempt.Err, "bot_id", bot.ID,
+				"status", attempt.StatusCode, "attempt", attempt.Number)
+		} else {
+			slog.DebugContext(ctx, "ボットのWebhookを送信", "bot_id", bot.ID, "status", attempt.StatusCode)
+		}
+		return true
+	})
+}
+
+// メッセージのイベント（送信・編集）を作る
+func newMessageEvent(eventType string, message domain.Message) apiEvent {
+	apiMessage := toAPIMessage(message)
+	event := apiEvent{
+		ID:        message.ChatID + "/" + message.ID,
+		Type:      eventType,
+		ChatID:    message.ChatID,
+		Message:   &apiMessage,
+		CreatedAt: message.CreatedAt,
+	}
+	if eventType == domain.EventMessageEdited {
+		event.ID += "/edited/" + strconv.FormatInt(message.EditedAt.UnixNano(), 10)
+		event.CreatedAt = message.EditedAt
+	}
+	return event
+}
+
+// チャットへの参加のイベントを作る
+func newMemberEvent(chatID string, member *domain.User) apiEvent {
+	apiMember := toAPIUser(member)
+	return apiEvent{
+		ID:        chatID + "/member/" + member.ID,
+		Type:      domain.EventMemberJoined,
+		ChatID:    chatID,
+		Member:    &apiMember,
+		CreatedAt: time.Now(),
+	}
+}
+
+// ボットのロングポーリング
+// cursor より後に、ボットが追加されたチャットに届いたメッセージを返す（届くまで timeout 秒待つ）
+func apiGetBotUpdates(w http.ResponseWriter, r *http.Request) error {
+	bot := middleware.CurrentUser(r)
+	if !bot.IsBot() {
+		return domain.NewForbiddenError("ボットのトークンで呼び出してください", nil)
+	}
+
+	since := time.Now()
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		t, err := decodeEventCursor(cursor)
+		if err != nil {
+			return domain.NewValidationError("cursor が不正です", err)
+		}
+		since = t
+	}
+	timeout := defaultBotPollTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxBotPollTimeout {
+			return domain.NewValidationError("timeout には0〜50の整数を指定してください", err)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	// サーバー全体の書き込みのタイムアウトより長く待てるようにする
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second)); err != nil {
+		slog.DebugContext(r.Context(), "書き込みのタイムアウトを延長できません", "error", err)
+	}
+
+	wake, stop := waitBotUpdates(bot.ID)
+	defer stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		events, err := collectBotEvents(r.Context(), bot.ID, since)
+		if err != nil {
+			return domain.NewInternalError("メッセージの取得に失敗しました", err)
+		}
+		if len(events) > 0 {
+			cursor := encodeEventCursor(events[len(events)-1].CreatedAt)
+			return writeAPIResponse(w, http.StatusOK, apiBotUpdates{Events: events, Cursor: cursor})
+		}
+
+		select {
+		case <-wake:
+		case <-time.After(botPollInterval):
+		case <-deadline.C:
+			return writeAPIResponse(w, http.StatusOK, apiBotUpdates{Events: []apiEvent{}, Cursor: encodeEventCursor(since)})
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// ボットが追加されたチャットから、since より後に他の参加者が送信したメッセージを集める
+func collectBotEvents(ctx context.Context, botID string, since time.Time) ([]apiEvent, error) {
+	chats, err := firebase.GetBotChats(botID)
+	if err != nil {
+		return nil, err
+	}
+	events := []apiEvent{}
+	for _, chat := range chats {
+		chatID, _ := chat["id"].(string)
+		messagesData, err := firebase.GetChatMessagesSince(ctx, chatID, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range messagesData {
+			message := messageFromData(chatID, data)
+			if message.SenderID == botID {
+				continue
+			}
+			events = append(events, newMessageEvent(domain.EventMessageCreated, message))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events, nil
+}
+
+// イベントのカーソルは最後に受け取ったイベントの日時を不透明な文字列にしたもの
+func encodeEventCursor(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("t:" + strconv.FormatInt(t.UnixNano(), 10)))
+}
+
+// カーソルから日時を取り出す
+func decodeEventCursor(cursor string) (time.Time, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value, ok := strings.CutPrefix(string(b), "t:")
+	if !ok {
+		return time.Time{}, errors.New("カーソルの形式が不正です")
+	}
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("カーソルの形式が不正です")
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// チャットにボットを追加する
+func apiAddChatBot(w http.ResponseWriter, r *http.Request) error {
+	var req apiAddChatBotRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	if err := addChatBot(r, middleware.CurrentUser(r), r.PathValue("id"), req.BotID); err != nil {
+		return err
+	}
+	return writeAPIResponse(w, http.StatusNoContent, nil)
+}
+
+// チャットからボットを外す
+func apiRemoveChatBot(w http.ResponseWriter, r *http.Request) error {
+	if err := removeChatBot(r, middleware.CurrentUser(r), r.PathValue("id"), r.PathValue("bot_id")); err != nil {
+		return err
+	}
+	return writeAPIResponse(w, http.StatusNoContent, nil)
+}
+
+// チャット画面からボットを追加・削除するハンドラ
+func ChatBotHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+
+	chatID := r.FormValue("chat_id")
+	botID := r.FormValue("bot_id")
+	if r.FormValue("action") == "remove" {
+		err = removeChatBot(r, session.User, chatID, botID)
+	} else {
+		err = addChatBot(r, session.User, chatID, botID)
+	}
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/chat?chat_id="+chatID, http.StatusSeeOther)
+	return nil
+}
+
+// チャットに自分が作成したボットを追加する
+func addChatBot(r *http.Request, user *domain.User, chatID, botID string) error {
+	if user.IsBot() {
+		return domain.NewForbiddenError("ボットは他のボットを追加できません", nil)
+	}
+	if botID == "" {
+		return domain.NewValidationError("ボットが指定されていません", nil)
+	}
+	participants, err := requireChatParticipant(chatID, user.ID)
+	if err != nil {
+		return err
+	}
+	bot, err := repository.GetOwnedBot(user.ID, botID)
+	if err != nil {
+		return domain.NewNotFoundError("ボットが見つかりません", err)
+	}
+
+	// ボットは鍵を持たないため、暗号化されたチャットには参加できない
+	encrypted, err := repository.IsChatEncrypted(chatID)
+	if err != nil {
+		return domain.NewInternalError("ボットの追加に失敗しました", err)
+	}
+	if encrypted {
+		return domain.NewValidationError("エンドツーエンド暗号化されたチャットにはボットを追加できません", nil)
+	}
+
+	if err := firebase.AddChatBot(chatID, botID); err != nil {
+		return domain.NewInternalError("ボットの追加に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "チャットにボットを追加", "chat_id", chatID, "bot_id", botID, "user_id", user.ID)
+	recordAudit(r, domain.AuditChatMemberAdded, user.ID, botID, map[string]string{"chat_id": chatID})
+
+	dispatchWebhookEvent(r.Context(), participants, newMemberEvent(chatID, bot))
+	return nil
+}
+
+// チャットからボットを外す（チャットの参加者であれば誰でも外せる）
+func removeChatBot(r *http.Request, user *domain.User, chatID, botID string) error {
+	if user.IsBot() && user.ID != botID {
+		return domain.NewForbiddenError("ボットは他のボットを外せません", nil)
+	}
+	if _, err := requireChatParticipant(chatID, user.ID); err != nil {
+		return err
+	}
+	bots, err := firebase.GetChatBots(chatID)
+	if err != nil {
+		return domain.NewInternalError("ボットの削除に失敗しました", err)
+	}
+	if !containsString(bots, botID) {
+		return domain.NewNotFoundError("ボットが見つかりません", nil)
+	}
+
+	if err := firebase.RemoveChatBot(chatID, botID); err != nil {
+		return domain.NewInternalError("ボットの削除に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "チャットからボットを削除", "chat_id", chatID, "bot_id", botID, "user_id", user.ID)
+	recordAudit(r, domain.AuditChatMemberRemoved, user.ID, botID, map[string]string{"chat_id": chatID})
+	return nil
+}
@@ -1,9 +1,10 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sort"
 	"strings"
@@ -17,66 +18,105 @@ import (
 )
 
 // チャット開始ハンドラ
-func StartChatHandler(w http.ResponseWriter, r *http.Request) {
+func StartChatHandler(w http.ResponseWriter, r *http.Request) error {
 	if r.Method != http.MethodPost {
-		log.Fatalf("メソッドが許可されていません")
-		return
+		return domain.NewMethodNotAllowedError()
 	}
 
-	// セッションの検証
-	session, err := middleware.ValidateSession(w, r)
-	if err != nil {
-		log.Fatalf("認証されていません: %v", err)
-		return
+	// セッション（またはAPIトークン）の検証はミドルウェアで行う
+	sessionUser := middleware.SessionUser(r)
+	if sessionUser == nil {
+		return domain.NewUnauthorizedError("ログインしてください", nil)
 	}
 
 	// セッションからユーザー情報を取得
-	user, err := repository.GetUserByID(session.User.ID)
+	user, err := repository.GetUserByID(sessionUser.ID)
 	if err != nil {
-		log.Fatalf("ユーザー情報の取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
 	}
 
 	// URLから対象ユーザーIDを取得
 	targetUserID := r.URL.Path[len("/chat/"):]
 	if targetUserID == "" {
-		log.Fatalf("ユーザーIDが指定されていません")
-		return
+		return domain.NewValidationError("ユーザーIDが指定されていません", nil)
+	}
+	if targetUserID == user.ID {
+		return domain.NewValidationError("自分自身とはチャットを開始できません", nil)
 	}
 
 	// 対象ユーザーの存在確認
-	_, err = GetUserData(targetUserID)
+	target, err := GetUserData(targetUserID)
 	if err != nil {
-		log.Fatalf("対象ユーザーが見つかりません: %v", err)
-		return
+		return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+	}
+	existing, err := prepareStartChat(r.Context(), user, target)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		http.Redirect(w, r, fmt.Sprintf("/chat?chat_id=%s", existing.ID), http.StatusSeeOther)
+		return nil
 	}
 
 	// チャットを開始
 	chatID, err := firebase.StartChat(user.ID, targetUserID)
 	if err != nil {
-		log.Fatalf("チャットの開始に失敗: %v", err)
-		return
+		return domain.NewInternalError("チャットの開始に失敗しました", err)
 	}
+	dispatchChatStarted(r.Context(), chatID, user, target)
+	recordAudit(r, domain.AuditChatMemberAdded, user.ID, target.ID, map[string]string{"chat_id": chatID})
 
 	// チャットページにリダイレクト
 	redirectURL := fmt.Sprintf("/chat?chat_id=%s", chatID)
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+	return nil
 }
 
-// チャットページのハンドラ
-func ChatHandler(w http.ResponseWriter, r *http.Request) {
-	// セッションの検証
-	session, err := middleware.ValidateSession(w, r)
+// チャットを開始できるかを確認する（画面・APIで共通の処理）
+// 相手とのチャットが既にある場合はそのチャットを返す
+func prepareStartChat(ctx context.Context, user, target *domain.User) (*domain.Chat, error) {
+	if target.IsBot() {
+		return nil, domain.NewValidationError("ボットとはチャットを開始できません", nil)
+	}
+	if err := requireNotBlocked(user.ID, target.ID); err != nil {
+		return nil, err
+	}
+
+	chats, err := getChatHistory(ctx, user)
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+		return nil, domain.NewInternalError("チャット履歴の取得に失敗しました", err)
+	}
+	for _, chat := range chats {
+		if chat.Contact.ID == target.ID {
+			return &chat, nil
+		}
+	}
+
+	// 相手が連絡先のユーザーのみに制限している場合
+	if target.ContactsOnly {
+		isContact, err := repository.AreContacts(user.ID, target.ID)
+		if err != nil {
+			return nil, domain.NewInternalError("連絡先の確認に失敗しました", err)
+		}
+		if !isContact {
+			return nil, domain.NewForbiddenError("このユーザーとチャットを開始するには、連絡先に追加されている必要があります", nil)
+		}
+	}
+	return nil, nil
+}
+
+// チャットページのハンドラ
+func ChatHandler(w http.ResponseWriter, r *http.Request) error {
+	// セッション（またはAPIトークン）の検証はミドルウェアで行う
+	sessionUser := middleware.SessionUser(r)
+	if sessionUser == nil {
+		return domain.NewUnauthorizedError("ログインしてください", nil)
 	}
 
 	// セッションからユーザー情報を取得
-	user, err := repository.GetUserByID(session.User.ID)
+	user, err := repository.GetUserByID(sessionUser.ID)
 	if err != nil {
-		log.Fatalf("ユーザー情報の取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
 	}
 
 	// POSTリクエストの場合はメッセージ送信処理
@@ -85,56 +125,29 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		chatID := r.FormValue("chatID")
 		content := r.FormValue("content")
 
-		if chatID == "" || content == "" {
-			log.Fatalf("チャットIDとメッセージ内容が必要です")
-			return
-		}
-
-		// メッセージIDを生成
-		messageID := generateMessageID()
-
-		// メッセージを作成
-		message := map[string]interface{}{
-			"id":         messageID,
-			"sender_id":  user.ID,
-			"sender_name": user.Name,
-			"content":    content,
-			"created_at": time.Now(),
-			"is_read":    false,
-			"type":       "text",
-		}
-
-		// メッセージを保存
-		err = firebase.AddChatMessage(chatID, message)
+		message, err := sendChatMessage(r.Context(), user, chatID, content)
 		if err != nil {
-			log.Fatalf("メッセージの送信に失敗: %v", err)
-			return
-		}
-
-		// チャットの最終更新時刻を更新
-		err = firebase.UpdateField("chats", chatID, "updated_at", time.Now())
-		if err != nil {
-			log.Printf("チャットの更新時刻の更新に失敗: %v", err)
+			return err
 		}
 
 		// JSONレスポンスを返す
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":         messageID,
-			"content":    content,
-			"sender_id":  user.ID,
-			"sender_name": user.Name,
-			"created_at": time.Now().Format("15:04"),
-			"is_read":    false,
+			"id":          message.ID,
+			"content":     message.Content,
+			"sender_id":   message.SenderID,
+			"sender_name": message.SenderName,
+			"created_at":  message.CreatedAt.Format("15:04"),
+			"is_read":     false,
+			"encrypted":   message.IsEncrypted,
 		})
-		return
+		return nil
 	}
 
 	// チャット履歴を取得
-	chats, err := getChatHistory(user)
+	chats, err := getChatHistory(r.Context(), user)
 	if err != nil {
-		log.Fatalf("チャット一覧の取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("チャット一覧の取得に失敗しました", err)
 	}
 
 	// URLからチャットIDを取得
@@ -147,104 +160,54 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 			Chats:      chats,
 			ChatID:     "", // 空のチャットIDを設定
 		}
-		markup.GenerateHTML(w, data, "layout", "header", "chat", "footer")
-		return
+		return markup.GenerateHTML(w, data, "layout", "header", "chat", "footer")
 	}
 
 	// チャットの存在確認
 	exists, err := firebase.CheckChatExists(chatID)
 	if err != nil {
-		log.Fatalf("チャットの確認に失敗: %v", err)
-		return
+		return domain.NewInternalError("チャットの確認に失敗しました", err)
 	}
 	if !exists {
-		log.Fatalf("チャットが見つかりません")
-		return
+		return domain.NewNotFoundError("チャットが見つかりません", nil)
 	}
 
 	// チャットの参加者を取得
 	participants, err := firebase.GetChatParticipants(chatID)
 	if err != nil {
-		log.Fatalf("チャットの参加者情報の取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("チャットの参加者情報の取得に失敗しました", err)
 	}
 
 	// 対象ユーザーを特定
 	var targetUserID string
+	isParticipant := false
 	for _, p := range participants {
-		if p != user.ID {
+		if p == user.ID {
+			isParticipant = true
+		} else if targetUserID == "" {
 			targetUserID = p
-			break
 		}
 	}
-
-	// 対象ユーザーの存在確認
-	_, err = GetUserData(targetUserID)
-	if err != nil {
-		log.Fatalf("対象ユーザーが見つかりません: %v", err)
-		return
+	if !isParticipant {
+		return domain.NewForbiddenError("このチャットを閲覧する権限がありません", nil)
 	}
 
 	// 対象ユーザーの情報を取得
 	targetUser, err := GetUserData(targetUserID)
 	if err != nil {
-		log.Fatalf("対象ユーザーの情報の取得に失敗: %v", err)
-		return
+		return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
 	}
 
 	// メッセージを取得
-	messagesData, err := firebase.GetChatMessages(chatID)
+	messagesData, err := firebase.GetChatMessages(r.Context(), chatID)
 	if err != nil {
-		log.Fatalf("メッセージの取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("メッセージの取得に失敗しました", err)
 	}
 
 	// メッセージの型変換
 	var messages []domain.Message
 	for _, msg := range messagesData {
-		// 各フィールドを安全に取得する関数
-		getString := func(key string) string {
-			if val, exists := msg[key]; exists && val != nil {
-				if str, ok := val.(string); ok {
-					return str
-				}
-			}
-			// 大文字のキーも試す
-			if val, exists := msg[strings.ToUpper(key)]; exists && val != nil {
-				if str, ok := val.(string); ok {
-					return str
-				}
-			}
-			return ""
-		}
-
-		// 時刻の取得
-		var createdAt time.Time
-		if t, ok := msg["created_at"].(time.Time); ok {
-			createdAt = t
-		} else if t, ok := msg["CreatedAt"].(time.Time); ok {
-			createdAt = t
-		} else {
-			createdAt = time.Now() // デフォルト値
-		}
-
-		// 既読状態の取得
-		isRead := false
-		if r, ok := msg["is_read"].(bool); ok {
-			isRead = r
-		} else if r, ok := msg["IsRead"].(bool); ok {
-			isRead = r
-		}
-
-		message := domain.Message{
-			ID:         getString("id"),
-			Content:    getString("content"),
-			SenderID:   getString("sender_id"),
-			SenderName: getString("sender_name"),
-			CreatedAt:  createdAt,
-			IsRead:     isRead,
-		}
-		messages = append(messages, message)
+		messages = append(messages, messageFromData(chatID, msg))
 	}
 
 	// 現在のチャットを特定
@@ -256,6 +219,15 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// チャットに追加できる自分のボット（暗号化されたチャットには追加できない）
+	var ownBots []domain.User
+	if currentChat != nil && !currentChat.IsEncrypted {
+		ownBots, err = repository.GetBotsByOwner(user.ID)
+		if err != nil {
+			return domain.NewInternalError("ボットの取得に失敗しました", err)
+		}
+	}
+
 	// チャットページのデータを取得
 	data := domain.TemplateData{
 		IsLoggedIn:  true,
@@ -265,20 +237,33 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		Chats:       chats,
 		CurrentChat: currentChat,
 		ChatID:      chatID,
+		OwnBots:     ownBots,
 	}
 
 	// テンプレートのレンダリング
-	markup.GenerateHTML(w, data, "layout", "header", "chat", "footer")
+	return markup.GenerateHTML(w, data, "layout", "header", "chat", "footer")
 }
 
 // チャット履歴を取得
-func getChatHistory(user *domain.User) ([]domain.Chat, error) {
-	// チャット履歴を取得
-	chats, err := firebase.GetAllChats(user.ID)
+func getChatHistory(ctx context.Context, user *domain.User) ([]domain.Chat, error) {
+	// チャット履歴を取得（ボットは追加されたチャット）
+	var chats []map[string]interface{}
+	var err error
+	if user.IsBot() {
+		chats, err = firebase.GetBotChats(user.ID)
+	} else {
+		chats, err = firebase.GetAllChats(ctx, user.ID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("チャット履歴の取得に失敗しました: %v", err)
 	}
 
+	// 自分をブロックしているユーザーにはオンライン状態を表示しない
+	blockerIDs, err := repository.GetBlockerIDs(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ブロックの取得に失敗しました: %v", err)
+	}
+
 	var chatHistory []domain.Chat
 	seenChats := make(map[string]bool) // 重複チェック用のマップ
 
@@ -286,7 +271,7 @@ func getChatHistory(user *domain.User) ([]domain.Chat, error) {
 		// チャットIDの取得
 		chatID, ok := chatData["id"].(string)
 		if !ok {
-			log.Printf("チャットIDの取得に失敗: %v", chatData)
+			slog.Warn("チャットIDの取得に失敗")
 			continue
 		}
 
@@ -297,7 +282,8 @@ func getChatHistory(user *domain.User) ([]domain.Chat, error) {
 		}
 
 		// 自分が参加者に含まれているか確認
-		isParticipant := false
+		// ボットの場合は、作成者以外の参加者をチャットの相手とする
+		isParticipant := user.IsBot()
 		var targetUserID string
 		for _, p := range participants {
 			participantID, ok := p.(string)
@@ -306,7 +292,7 @@ func getChatHistory(user *domain.User) ([]domain.Chat, error) {
 			}
 			if participantID == user.ID {
 				isParticipant = true
-			} else {
+			} else if !user.IsBot() || participantID != user.OwnerID || targetUserID == "" {
 				targetUserID = participantID
 			}
 		}
@@ -319,85 +305,77 @@ func getChatHistory(user *domain.User) ([]domain.Chat, error) {
 		seenChats[chatID] = true
 
 		// メッセージの取得
-		messagesData, err := firebase.GetChatMessages(chatID)
+		messagesData, err := firebase.GetChatMessages(ctx, chatID)
 		if err != nil {
-			log.Printf("メッセージの取得に失敗: chatID=%s, error=%v", chatID, err)
+			slog.ErrorContext(ctx, "メッセージの取得に失敗", "error", err, "chat_id", chatID)
 			continue
 		}
 
 		// メッセージの型変換
 		var messages []domain.Message
 		var lastMessageTime time.Time
+		var unreadCount int
 		for _, msg := range messagesData {
-			// 各フィールドを安全に取得する関数
-			getString := func(key string) string {
-				if val, exists := msg[key]; exists && val != nil {
-					if str, ok := val.(string); ok {
-						return str
-					}
-				}
-				// 大文字のキーも試す
-				if val, exists := msg[strings.ToUpper(key)]; exists && val != nil {
-					if str, ok := val.(string); ok {
-						return str
-					}
-				}
-				return ""
-			}
-
-			// 時刻の取得
-			var createdAt time.Time
-			if t, ok := msg["created_at"].(time.Time); ok {
-				createdAt = t
-			} else if t, ok := msg["CreatedAt"].(time.Time); ok {
-				createdAt = t
-			} else {
-				createdAt = time.Now() // デフォルト値
+			message := messageFromData(chatID, msg)
+			messages = append(messages, message)
+			if message.SenderID != user.ID && !message.IsRead {
+				unreadCount++
 			}
 
-			// 既読状態の取得
-			isRead := false
-			if r, ok := msg["is_read"].(bool); ok {
-				isRead = r
-			} else if r, ok := msg["IsRead"].(bool); ok {
-				isRead = r
+			// 最新のメッセージ時刻を更新
+			if message.CreatedAt.After(lastMessageTime) {
+				lastMessageTime = message.CreatedAt
 			}
+		}
 
-			message := domain.Message{
-				ID:         getString("id"),
-				Content:    getString("content"),
-				SenderID:   getString("sender_id"),
-				SenderName: getString("sender_name"),
-				CreatedAt:  createdAt,
-				IsRead:     isRead,
+		// チャット相手の情報を取得（相手が削除されている場合は削除されたユーザーとして表示する）
+		targetUser := &domain.User{ID: domain.DeletedUserID, Name: domain.DeletedUserName}
+		if targetUserID != domain.DeletedUserID {
+			targetUser, err = GetUserData(targetUserID)
+			if err != nil {
+				slog.Error("チャット相手の情報取得に失敗", "error", err, "target_user_id", targetUserID)
+				continue
 			}
-			messages = append(messages, message)
+		}
 
-			// 最新のメッセージ時刻を更新
-			if createdAt.After(lastMessageTime) {
-				lastMessageTime = createdAt
+		// エンドツーエンド暗号化の状態
+		isEncrypted, _ := chatData["encrypted"].(bool)
+
+		// チャットに追加されたボット
+		var bots []domain.Contact
+		botIDs, _ := chatData["bots"].([]interface{})
+		for _, b := range botIDs {
+			botID, _ := b.(string)
+			bot, err := GetUserData(botID)
+			if err != nil {
+				slog.Error("ボットの情報取得に失敗", "error", err, "bot_id", botID)
+				continue
 			}
+			bots = append(bots, domain.Contact{ID: bot.ID, Username: bot.Name, Icon: bot.Icon, IsBot: true})
 		}
 
-		// チャット相手の情報を取得
-		targetUser, err := GetUserData(targetUserID)
-		if err != nil {
-			log.Printf("チャット相手の情報取得に失敗: targetUserID=%s, error=%v", targetUserID, err)
-			continue
+		// ミュート中のチャットは未読数を表示しない
+		isMuted := user.IsChatMuted(chatID)
+		if isMuted {
+			unreadCount = 0
 		}
 
 		// チャット履歴に追加
 		chatHistory = append(chatHistory, domain.Chat{
-			ID: chatID,
+			ID:          chatID,
+			IsEncrypted: isEncrypted,
 			Contact: domain.Contact{
 				ID:       targetUser.ID,
 				Username: targetUser.Name,
 				Icon:     targetUser.Icon,
 				LastSeen: time.Now(),
-				IsOnline: targetUser.IsOnline,
+				IsOnline: targetUser.IsOnline && !blockerIDs[targetUser.ID],
 			},
-			Messages:  messages,
-			UpdatedAt: lastMessageTime,
+			Messages:    messages,
+			UpdatedAt:   lastMessageTime,
+			Bots:        bots,
+			IsMuted:     isMuted,
+			UnreadCount: unreadCount,
 		})
 	}
 
@@ -422,42 +400,38 @@ func NewChatController(chatUsecase domain.ChatUsecase) *ChatControllerImpl {
 }
 
 // Create チャットを作成する
-func (c *ChatControllerImpl) Create(w http.ResponseWriter, r *http.Request) {
+func (c *ChatControllerImpl) Create(w http.ResponseWriter, r *http.Request) error {
 	if r.Method != http.MethodPost {
-		log.Fatalf("メソッドが許可されていません")
-		return
+		return domain.NewMethodNotAllowedError()
 	}
 
 	user := r.FormValue("user")
 	message := r.FormValue("message")
 
 	if err := c.chatUsecase.CreateChat(user, message); err != nil {
-		log.Fatalf("チャットの作成に失敗: %v", err)
-		return
+		return domain.NewInternalError("チャットの作成に失敗しました", err)
 	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
 }
 
 // メッセージ送信ハンドラ
-func SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+func SendMessageHandler(w http.ResponseWriter, r *http.Request) error {
 	if r.Method != http.MethodPost {
-		log.Fatalf("メソッドが許可されていません")
-		return
+		return domain.NewMethodNotAllowedError()
 	}
 
 	// セッションの検証
 	session, err := middleware.ValidateSession(w, r)
 	if err != nil {
-		log.Fatalf("認証されていません: %v", err)
-		return
+		return domain.NewUnauthorizedError("ログインしてください", err)
 	}
 
 	// セッションからユーザー情報を取得
 	user, err := repository.GetUserByID(session.User.ID)
 	if err != nil {
-		log.Fatalf("ユーザー情報の取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("ユーザー情報の取得に失敗しました", err)
 	}
 
 	// フォームデータから情報を取得
@@ -465,8 +439,7 @@ func SendMessageHandler(w http.ResponseWriter, r *http.Request) {
 	content := r.FormValue("content")
 
 	if chatID == "" || content == "" {
-		log.Fatalf("チャットIDとメッセージ内容が必要です")
-		return
+		return domain.NewValidationError("チャットIDとメッセージ内容が必要です", nil)
 	}
 
 	// メッセージを作成
@@ -481,15 +454,234 @@ func SendMessageHandler(w http.ResponseWriter, r *http.Request) {
 	// メッセージを保存
 	err = firebase.AddChatMessage(chatID, message)
 	if err != nil {
-		log.Fatalf("メッセージの送信に失敗: %v", err)
-		return
+		return domain.NewInternalError("メッセージの送信に失敗しました", err)
 	}
 
 	// チャットページにリダイレクト
 	http.Redirect(w, r, fmt.Sprintf("/chat?chat_id=%s", chatID), http.StatusSeeOther)
+	return nil
 }
 
-// メッセージIDを生成する
-func generateMessageID() string {
-	return fmt.Sprintf("msg_%d", time.Now().UnixNano())
+// チャットの参加者であることを確認し、参加者の一覧を返す
+func requireChatParticipant(chatID string, userID string) ([]string, error) {
+	exists, err := firebase.CheckChatExists(chatID)
+	if err != nil || !exists {
+		return nil, domain.NewNotFoundError("チャットが見つかりません", err)
+	}
+	participants, err := firebase.GetChatParticipants(chatID)
+	if err != nil {
+		return nil, domain.NewInternalError("チャットの参加者情報の取得に失敗しました", err)
+	}
+	if !containsString(participants, userID) {
+		// チャットに追加されたボットも参加者として扱う
+		bots, err := firebase.GetChatBots(chatID)
+		if err != nil {
+			return nil, domain.NewInternalError("チャットの参加者情報の取得に失敗しました", err)
+		}
+		if !containsString(bots, userID) {
+			return nil, domain.NewForbiddenError("このチャットを閲覧する権限がありません", nil)
+		}
+	}
+	return participants, nil
+}
+
+// チャットにメッセージを送信する（画面・APIで共通の処理）
+// エンドツーエンド暗号化が有効なチャットには暗号文のみ保存する
+func sendChatMessage(ctx context.Context, user *domain.User, chatID string, content string) (*domain.Message, error) {
+	if chatID == "" || content == "" {
+		return nil, domain.NewValidationError("チャットIDとメッセージ内容が必要です", nil)
+	}
+	participants, err := requireChatParticipant(chatID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsBot() {
+		if err := requireNotBlockedInChat(user.ID, participants); err != nil {
+			return nil, err
+		}
+	}
+
+	input := chatMessageInput{SenderID: user.ID, SenderName: user.Name, Content: content}
+	if user.IsBot() {
+		input.SenderType = domain.UserTypeBot
+	}
+	return postChatMessage(ctx, chatID, participants, input)
+}
+
+// チャットに投稿するメッセージ
+type chatMessageInput struct {
+	SenderID    string              // 送信者のID
+	SenderName  string              // 送信者の名前
+	SenderType  string              // 送信者の種類（ユーザーの場合は空）
+	Content     string              // 本文
+	Attachments []domain.Attachment // 添付（リンク）
+}
+
+// メッセージを保存し、ボット・Webhookに届ける
+// 画面・API・受信用Webhookからの投稿で共通の処理（送信者がチャットに投稿できることは呼び出し元で確認する）
+func postChatMessage(ctx context.Context, chatID string, participants []string, input chatMessageInput) (*domain.Message, error) {
+	// 相手が削除されたチャットは履歴として残すのみで、新しいメッセージは送信できない
+	if containsString(participants, domain.DeletedUserID) {
+		return nil, domain.NewForbiddenError("相手のアカウントは削除されています", nil)
+	}
+	encrypted, err := repository.IsChatEncrypted(chatID)
+	if err != nil {
+		return nil, domain.NewInternalError("メッセージの送信に失敗しました", err)
+	}
+	if encrypted && !domain.IsEncryptedContent(input.Content) {
+		return nil, domain.NewValidationError("暗号化されていないメッセージは送信できません", nil)
+	}
+
+	// メッセージを作成
+	now := time.Now()
+	data := map[string]interface{}{
+		"sender_id":   input.SenderID,
+		"sender_name": input.SenderName,
+		"content":     input.Content,
+		"created_at":  now,
+		"is_read":     false,
+		"type":        "text",
+		"encrypted":   encrypted,
+	}
+	if input.SenderType != "" {
+		data["sender_type"] = input.SenderType
+	}
+	if len(input.Attachments) > 0 {
+		// 保存時暗号化の対象とするため、JSONの文字列として保存する
+		attachments, err := json.Marshal(input.Attachments)
+		if err != nil {
+			return nil, domain.NewInternalError("メッセージの送信に失敗しました", err)
+		}
+		data["attachments"] = string(attachments)
+	}
+
+	// メッセージを保存（メッセージIDは保存時に設定される）
+	if err := firebase.AddChatMessage(chatID, data); err != nil {
+		return nil, domain.NewInternalError("メッセージの送信に失敗しました", err)
+	}
+
+	// チャットの最終更新時刻を更新
+	if err := firebase.UpdateField("chats", chatID, "updated_at", now); err != nil {
+		slog.ErrorContext(ctx, "チャットの更新時刻の更新に失敗", "error", err, "chat_id", chatID)
+	}
+
+	message := messageFromData(chatID, data)
+
+	// チャットに追加されたボットと、参加者が登録したWebhookに届ける（チャットをミュートした参加者には通知しない）
+	notifyChatBots(ctx, chatID, message)
+	dispatchWebhookEvent(ctx, unmutedParticipants(ctx, chatID, participants), newMessageEvent(domain.EventMessageCreated, message))
+	return &message, nil
+}
+
+// 自分が送信したメッセージの本文を編集する（画面・APIで共通の処理）
+func editChatMessage(ctx context.Context, user *domain.User, chatID, messageID, content string) (*domain.Message, error) {
+	if content == "" {
+		return nil, domain.NewValidationError("メッセージ内容が必要です", nil)
+	}
+	participants, err := requireChatParticipant(chatID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := firebase.GetChatMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, domain.NewInternalError("メッセージの取得に失敗しました", err)
+	}
+	if data == nil {
+		return nil, domain.NewNotFoundError("メッセージが見つかりません", nil)
+	}
+	original := messageFromData(chatID, data)
+	if original.SenderID != user.ID {
+		return nil, domain.NewForbiddenError("自分が送信したメッセージのみ編集できます", nil)
+	}
+	if original.IsEncrypted && !domain.IsEncryptedContent(content) {
+		return nil, domain.NewValidationError("暗号化されていないメッセージは送信できません", nil)
+	}
+
+	edited, err := firebase.EditChatMessage(ctx, chatID, messageID, content, time.Now())
+	if err != nil {
+		return nil, domain.NewInternalError("メッセージの編集に失敗しました", err)
+	}
+	message := messageFromData(chatID, edited)
+
+	dispatchWebhookEvent(ctx, participants, newMessageEvent(domain.EventMessageEdited, message))
+	return &message, nil
+}
+
+// Firestoreのメッセージのデータをドメインの構造体に変換する
+func messageFromData(chatID string, msg map[string]interface{}) domain.Message {
+	// 各フィールドを安全に取得する関数
+	getString := func(key string) string {
+		if val, exists := msg[key]; exists && val != nil {
+			if str, ok := val.(string); ok {
+				return str
+			}
+		}
+		// 大文字のキーも試す
+		if val, exists := msg[strings.ToUpper(key)]; exists && val != nil {
+			if str, ok := val.(string); ok {
+				return str
+			}
+		}
+		return ""
+	}
+
+	// 時刻の取得
+	var createdAt time.Time
+	if t, ok := msg["created_at"].(time.Time); ok {
+		createdAt = t
+	} else if t, ok := msg["CreatedAt"].(time.Time); ok {
+		createdAt = t
+	} else {
+		createdAt = time.Now() // デフォルト値
+	}
+
+	// 既読状態の取得
+	isRead := false
+	if r, ok := msg["is_read"].(bool); ok {
+		isRead = r
+	} else if r, ok := msg["IsRead"].(bool); ok {
+		isRead = r
+	}
+	var readBy []string
+	if ids, ok := msg["read_by"].([]interface{}); ok {
+		for _, id := range ids {
+			if str, ok := id.(string); ok {
+				readBy = append(readBy, str)
+			}
+		}
+	}
+
+	// 編集日時の取得
+	editedAt, _ := msg["edited_at"].(time.Time)
+
+	// 暗号化状態の取得
+	isEncrypted, _ := msg["encrypted"].(bool)
+
+	// ボット・受信用Webhookからのメッセージかどうか
+	senderType, _ := msg["sender_type"].(string)
+
+	// 添付の取得
+	var attachments []domain.Attachment
+	if encoded := getString("attachments"); encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &attachments); err != nil {
+			slog.Warn("メッセージの添付の変換に失敗", "error", err, "chat_id", chatID)
+		}
+	}
+
+	return domain.Message{
+		ID:            getString("id"),
+		ChatID:        chatID,
+		Content:       getString("content"),
+		IsEncrypted:   isEncrypted,
+		SenderID:      getString("sender_id"),
+		SenderName:    getString("sender_name"),
+		IsBot:         senderType == domain.UserTypeBot,
+		IsIntegration: senderType == domain.SenderTypeIntegration,
+		Attachments:   attachments,
+		CreatedAt:     createdAt,
+		EditedAt:      editedAt,
+		IsRead:        isRead,
+		ReadBy:        readBy,
+	}
 }
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 連絡先ページのデータ構造体
+type ContactsPageData struct {
+	IsLoggedIn       bool                 // ログイン状態
+	User             *domain.User         // ユーザー情報
+	Contacts         []domain.Contact     // 連絡先
+	IncomingRequests []ContactRequestView // 届いた申請
+	OutgoingRequests []ContactRequestView // 送った申請
+}
+
+// 連絡先ページに表示する申請
+type ContactRequestView struct {
+	domain.ContactRequest
+	Contact domain.Contact // 申請の相手
+}
+
+// 連絡先ページのハンドラ
+// 連絡先の一覧はチャットのユースケースから取得する
+func ContactsHandler(chatUsecase domain.ChatUsecase) middleware.AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		// セッションの検証
+		session, err := middleware.ValidateSession(w, r)
+		if err != nil {
+			return domain.NewUnauthorizedError("ログインしてください", err)
+		}
+
+		contacts, err := chatUsecase.GetContacts(session.User)
+		if err != nil {
+			return domain.NewInternalError("連絡先の取得に失敗しました", err)
+		}
+		incoming, outgoing, err := repository.GetContactRequests(r.Context(), session.User.ID)
+		if err != nil {
+			return domain.NewInternalError("連絡先の申請の取得に失敗しました", err)
+		}
+
+		data := ContactsPageData{
+			IsLoggedIn:       true,
+			User:             session.User,
+			Contacts:         contacts,
+			IncomingRequests: newContactRequestViews(r, incoming, func(req domain.ContactRequest) string { return req.FromID }),
+			OutgoingRequests: newContactRequestViews(r, outgoing, func(req domain.ContactRequest) string { return req.ToID }),
+		}
+		return markup.GenerateHTML(w, data, "layout", "header", "contacts", "footer")
+	}
+}
+
+// 連絡先の申請（送信・承認・拒否・取り消し）と削除のハンドラ
+func ContactRequestHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+	user := session.User
+
+	switch r.URL.Path {
+	case "/contacts/requests":
+		if err := sendContactRequest(r, user, r.FormValue("user_id")); err != nil {
+			return err
+		}
+
+	case "/contacts/remove":
+		otherID := r.FormValue("user_id")
+		if err := repository.RemoveContact(user.ID, otherID); err != nil {
+			return domain.NewInternalError("連絡先の削除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "連絡先を削除", "user_id", user.ID, "contact_id", otherID)
+
+	default:
+		// 申請への操作（申請の相手は承認・拒否、申請者は取り消しのみ）
+		request, err := repository.GetContactRequest(r.FormValue("request_id"))
+		if err != nil {
+			return domain.NewInternalError("連絡先の申請の取得に失敗しました", err)
+		}
+		if request == nil {
+			return domain.NewNotFoundError("連絡先の申請が見つかりません", nil)
+		}
+
+		switch r.URL.Path {
+		case "/contacts/requests/accept":
+			if request.ToID != user.ID {
+				return domain.NewForbiddenError("この申請を承認する権限がありません", nil)
+			}
+			if err := repository.AcceptContactRequest(request.ID); err != nil {
+				if errors.Is(err, repository.ErrContactRequestNotFound) {
+					return domain.NewNotFoundError("連絡先の申請が見つかりません", err)
+				}
+				return domain.NewInternalError("連絡先の申請の承認に失敗しました", err)
+			}
+			slog.InfoContext(r.Context(), "連絡先の申請を承認", "user_id", user.ID, "contact_id", request.FromID)
+
+		case "/contacts/requests/decline":
+			if request.ToID != user.ID {
+				return domain.NewForbiddenError("この申請を拒否する権限がありません", nil)
+			}
+			if err := repository.DeleteContactRequest(request.ID); err != nil {
+				return domain.NewInternalError("連絡先の申請の拒否に失敗しました", err)
+			}
+			slog.InfoContext(r.Context(), "連絡先の申請を拒否", "user_id", user.ID, "contact_id", request.FromID)
+
+		case "/contacts/requests/cancel":
+			if request.FromID != user.ID {
+				return domain.NewForbiddenError("この申請を取り消す権限がありません", nil)
+			}
+			if err := repository.DeleteContactRequest(request.ID); err != nil {
+				return domain.NewInternalError("連絡先の申請の取り消しに失敗しました", err)
+			}
+			slog.InfoContext(r.Context(), "連絡先の申請を取り消し", "user_id", user.ID, "contact_id", request.ToID)
+
+		default:
+			return domain.NewNotFoundError("ページが見つかりません", nil)
+		}
+	}
+
+	http.Redirect(w, r, localReturnPath(r, "/contacts"), http.StatusSeeOther)
+	return nil
+}
+
+// 連絡先の申請を送る（相手から申請が届いている場合は承認する）
+func sendContactRequest(r *http.Request, user *domain.User, targetID string) error {
+	if targetID == "" {
+		return domain.NewValidationError("ユーザーIDが指定されていません", nil)
+	}
+	if targetID == user.ID {
+		return domain.NewValidationError("自分自身は連絡先に追加できません", nil)
+	}
+	target, err := GetUserData(targetID)
+	if err != nil {
+		return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+	}
+	if target.IsBot() {
+		return domain.NewValidationError("ボットは連絡先に追加できません", nil)
+	}
+	if err := requireNotBlocked(user.ID, target.ID); err != nil {
+		return err
+	}
+
+	accepted, err := repository.SendContactRequest(user.ID, target.ID)
+	if errors.Is(err, repository.ErrAlreadyContacts) {
+		return domain.NewConflictError("既に連絡先に追加されています", err)
+	}
+	if err != nil {
+		return domain.NewInternalError("連絡先の申請に失敗しました", err)
+	}
+	if accepted {
+		slog.InfoContext(r.Context(), "届いていた連絡先の申請を承認", "user_id", user.ID, "contact_id", target.ID)
+	} else {
+		slog.InfoContext(r.Context(), "連絡先を申請", "user_id", user.ID, "contact_id", target.ID)
+	}
+	return nil
+}
+
+// 申請を表示用に変換する（相手が削除されたユーザーの申請は表示しない）
+func newContactRequestViews(r *http.Request, requests []domain.ContactRequest, otherID func(domain.ContactRequest) string) []ContactRequestView {
+	views := make([]ContactRequestView, 0, len(requests))
+	for _, request := range requests {
+		other, err := GetUserData(otherID(request))
+		if err != nil {
+			slog.WarnContext(r.Context(), "連絡先の申請の相手の取得に失敗", "error", err, "request_id", request.ID)
+			continue
+		}
+		views = append(views, ContactRequestView{
+			ContactRequest: request,
+			Contact: domain.Contact{
+				ID:       other.ID,
+				Username: other.Name,
+				Icon:     other.Icon,
+				LastSeen: other.LastSeenAt,
+				IsOnline: other.IsOnline,
+			},
+		})
+	}
+	return views
+}
+
+// 操作後に戻るページ（フォームの return_to のうち同じサイト内のパスのみ受け付け、それ以外は fallback）
+func localReturnPath(r *http.Request, fallback string) string {
+	path := r.FormValue("return_to")
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, "\\") {
+		return fallback
+	}
+	return path
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// CSP違反レポートの最大サイズ
+const maxCSPReportSize = 64 * 1024
+
+// CSPReportHandler ブラウザから送信されたCSP違反レポートをログに記録する
+func CSPReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// application/csp-report 形式: {"csp-report": {...}}
+	var report struct {
+		CSPReport struct {
+			DocumentURI        string `json:"document-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			BlockedURI         string `json:"blocked-uri"`
+			SourceFile         string `json:"source-file"`
+			LineNumber         int    `json:"line-number"`
+			Disposition        string `json:"disposition"`
+		} `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		slog.WarnContext(r.Context(), "CSP違反レポートの解析に失敗", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rep := report.CSPReport
+	slog.WarnContext(r.Context(), "CSP違反",
+		"document", rep.DocumentURI,
+		"directive", rep.ViolatedDirective,
+		"effective", rep.EffectiveDirective,
+		"blocked", rep.BlockedURI,
+		"source", rep.SourceFile,
+		"line", rep.LineNumber,
+		"disposition", rep.Disposition)
+
+	w.WriteHeader(http.StatusNoContent)
+}
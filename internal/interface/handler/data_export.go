@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+)
+
+// エクスポートに含める監査ログの最大件数
+const dataExportAuditLimit = 10000
+
+// エクスポートするプロフィール（パスワードのハッシュなどの認証情報は含めない）
+type exportProfile struct {
+	ID                  string     `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Icon                string     `json:"icon,omitempty"`
+	Role                string     `json:"role,omitempty"`
+	ContactsOnly        bool       `json:"contacts_only"`
+	MutedChatIDs        []string   `json:"muted_chat_ids,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// エクスポートする連絡先・申請・ブロック
+type exportContacts struct {
+	Contacts         []exportUserRef `json:"contacts"`
+	IncomingRequests []exportUserRef `json:"incoming_requests"`
+	OutgoingRequests []exportUserRef `json:"outgoing_requests"`
+	Blocked          []exportUserRef `json:"blocked"`
+}
+
+// エクスポートする相手のユーザー（申請・ブロックの場合は日時を含む）
+type exportUserRef struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// エクスポートするチャット（メッセージを含む）
+type exportChat struct {
+	ID           string          `json:"id"`
+	IsEncrypted  bool            `json:"is_encrypted"`
+	CreatedAt    *time.Time      `json:"created_at,omitempty"`
+	Participants []exportUserRef `json:"participants"`
+	Bots         []exportUserRef `json:"bots,omitempty"`
+	Messages     []apiMessage    `json:"messages"`
+}
+
+// エクスポートするセッション（トークンは含めない）
+type exportSession struct {
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ExpiredAt     time.Time `json:"expired_at"`
+	IdleExpiredAt time.Time `json:"idle_expired_at"`
+}
+
+// エクスポートする監査ログ
+type exportAuditEvent struct {
+	Action    string            `json:"action"`
+	Label     string            `json:"label"`
+	Actor     string            `json:"actor"`
+	Target    string            `json:"target,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// 読みやすい形式の履歴（transcript.html）のデータ
+type transcriptData struct {
+	Profile     exportProfile
+	GeneratedAt time.Time
+	Chats       []transcriptChat
+}
+
+// 読みやすい形式の履歴のチャット
+type transcriptChat struct {
+	Title       string
+	IsEncrypted bool
+	Messages    []apiMessage
+}
+
+// 読みやすい形式の履歴のテンプレート（ZIPファイル単体で開けるよう、スタイルも含める）
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8" />
+<title>{{ .Profile.Name }} のチャット履歴</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 48em; color: #222; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: .25em; margin-top: 2em; }
+.meta { color: #666; font-size: .85em; }
+.message { margin: .75em 0; }
+.content { white-space: pre-wrap; word-break: break-word; }
+</style>
+</head>
+<body>
+<h1>{{ .Profile.Name }} のチャット履歴</h1>
+<p class="meta">{{ .Profile.Email }} ・ 作成: {{ .GeneratedAt.Format "2006-01-02 15:04" }}</p>
+{{ range .Chats }}
+<h2>{{ .Title }}</h2>
+{{ if .IsEncrypted }}<p class="meta">エンドツーエンド暗号化が有効なチャットです。暗号化されたメッセージの本文は、端末の鍵でのみ復号できます。</p>{{ end }}
+{{ range .Messages }}
+<div class="message">
+<div class="meta">{{ .CreatedAt.Format "2006-01-02 15:04" }} {{ .SenderName }}{{ if .EditedAt }}（編集済み）{{ end }}</div>
+<div class="content">{{ if .IsEncrypted }}（暗号化されたメッセージ）{{ else }}{{ .Content }}{{ end }}</div>
+{{ range .Attachments }}<div class="meta">添付: <a href="{{ .URL }}">{{ if .Title }}{{ .Title }}{{ else }}{{ .URL }}{{ end }}</a></div>{{ end }}
+</div>
+{{ else }}
+<p class="meta">メッセージはありません</p>
+{{ end }}
+{{ else }}
+<p>チャットはありません</p>
+{{ end }}
+</body>
+</html>
+`))
+
+// ユーザーのデータをZIPファイルにまとめる
+// JSON（profile / contacts / chats / sessions / audit_events）と、読みやすい形式の履歴（transcript.html）を含める
+func buildDataExport(ctx context.Context, user *domain.User) ([]byte, error) {
+	names := userNameResolver()
+
+	profile := exportProfile{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		Icon:         user.Icon,
+		Role:         user.Role,
+		ContactsOnly: user.ContactsOnly,
+		MutedChatIDs: user.MutedChatIDs,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+	if user.IsDeletionScheduled() {
+		profile.DeletionScheduledAt = &user.DeletionScheduledAt
+	}
+
+	contacts, err := getExportContacts(ctx, user.ID, names)
+	if err != nil {
+		return nil, err
+	}
+	chats, err := getExportChats(ctx, user.ID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := repository.GetUserSessions(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("セッションの取得に失敗: %v", err)
+	}
+	exportSessions := make([]exportSession, 0, len(sessions))
+	for _, session := range sessions {
+		exportSessions = append(exportSessions, exportSession{
+			CreatedAt:     session.CreatedAt,
+			UpdatedAt:     session.UpdatedAt,
+			ExpiredAt:     session.ExpiredAt,
+			IdleExpiredAt: session.IdleExpiredAt,
+		})
+	}
+
+	events, err := repository.GetUserAuditEvents(ctx, user.ID, dataExportAuditLimit)
+	if err != nil {
+		return nil, fmt.Errorf("監査ログの取得に失敗: %v", err)
+	}
+	exportEvents := make([]exportAuditEvent, 0, len(events))
+	for _, view := range newAuditEventViews(events, user, false) {
+		exportEvents = append(exportEvents, exportAuditEvent{
+			Action:    view.Action,
+			Label:     view.ActionLabel,
+			Actor:     view.ActorName,
+			Target:    view.TargetName,
+			IP:        view.IP,
+			Metadata:  view.Metadata,
+			CreatedAt: view.CreatedAt,
+		})
+	}
+
+	now := time.Now()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"contacts.json", contacts},
+		{"chats.json", chats},
+		{"sessions.json", exportSessions},
+		{"audit_events.json", exportEvents},
+	}
+	for _, file := range files {
+		body, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(zw, file.name, now, body); err != nil {
+			return nil, err
+		}
+	}
+
+	transcript := transcriptData{Profile: profile, GeneratedAt: now}
+	for _, chat := range chats {
+		var others []string
+		for _, participant := range chat.Participants {
+			if participant.ID != user.ID {
+				others = append(others, participant.Name)
+			}
+		}
+		title := "チャット"
+		if len(others) > 0 {
+			title = others[0] + " とのチャット"
+		}
+		transcript.Chats = append(transcript.Chats, transcriptChat{Title: title, IsEncrypted: chat.IsEncrypted, Messages: chat.Messages})
+	}
+	var html bytes.Buffer
+	if err := transcriptTemplate.Execute(&html, transcript); err != nil {
+		return nil, fmt.Errorf("履歴の作成に失敗: %v", err)
+	}
+	if err := writeZipFile(zw, "transcript.html", now, html.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 連絡先・申請・ブロックしたユーザーを取得する
+func getExportContacts(ctx context.Context, userID string, names func(string) string) (exportContacts, error) {
+	result := exportContacts{
+		Contacts:         []exportUserRef{},
+		IncomingRequests: []exportUserRef{},
+		OutgoingRequests: []exportUserRef{},
+		Blocked:          []exportUserRef{},
+	}
+
+	contactIDs, err := repository.GetContactIDs(ctx, userID)
+	if err != nil {
+		return result, fmt.Errorf("連絡先の取得に失敗: %v", err)
+	}
+	for id := range contactIDs {
+		result.Contacts = append(result.Contacts, exportUserRef{ID: id, Name: names(id)})
+	}
+	sort.Slice(result.Contacts, func(i, j int) bool {
+		return result.Contacts[i].Name < result.Contacts[j].Name
+	})
+
+	incoming, outgoing, err := repository.GetContactRequests(ctx, userID)
+	if err != nil {
+		return result, fmt.Errorf("連絡先の申請の取得に失敗: %v", err)
+	}
+	for _, request := range incoming {
+		result.IncomingRequests = append(result.IncomingRequests, exportUserRef{ID: request.FromID, Name: names(request.FromID), CreatedAt: &request.CreatedAt})
+	}
+	for _, request := range outgoing {
+		result.OutgoingRequests = append(result.OutgoingRequests, exportUserRef{ID: request.ToID, Name: names(request.ToID), CreatedAt: &request.CreatedAt})
+	}
+
+	blocks, err := repository.GetBlocksByBlocker(ctx, userID)
+	if err != nil {
+		return result, fmt.Errorf("ブロックの取得に失敗: %v", err)
+	}
+	for _, block := range blocks {
+		result.Blocked = append(result.Blocked, exportUserRef{ID: block.BlockedID, Name: names(block.BlockedID), CreatedAt: &block.CreatedAt})
+	}
+	return result, nil
+}
+
+// 参加しているチャットをメッセージ（古い順）とともに取得する（作成日時の古い順）
+// エンドツーエンド暗号化されたメッセージは暗号文のまま含める
+func getExportChats(ctx context.Context, userID string, names func(string) string) ([]exportChat, error) {
+	chats, err := firebase.GetAllChats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("チャットの取得に失敗: %v", err)
+	}
+
+	result := make([]exportChat, 0, len(chats))
+	for _, chatData := range chats {
+		chatID, _ := chatData["id"].(string)
+		chat := exportChat{ID: chatID, Messages: []apiMessage{}}
+		chat.IsEncrypted, _ = chatData["encrypted"].(bool)
+		if createdAt, ok := chatData["createdAt"].(time.Time); ok {
+			chat.CreatedAt = &createdAt
+		}
+		participants, _ := chatData["participants"].([]interface{})
+		for _, p := range participants {
+			if id, ok := p.(string); ok {
+				chat.Participants = append(chat.Participants, exportUserRef{ID: id, Name: names(id)})
+			}
+		}
+		bots, _ := chatData["bots"].([]interface{})
+		for _, b := range bots {
+			if id, ok := b.(string); ok {
+				chat.Bots = append(chat.Bots, exportUserRef{ID: id, Name: names(id)})
+			}
+		}
+
+		messages, err := firebase.GetChatMessages(ctx, chatID)
+		if err != nil {
+			return nil, fmt.Errorf("メッセージの取得に失敗: %v", err)
+		}
+		for _, msg := range messages {
+			chat.Messages = append(chat.Messages, toAPIMessage(messageFromData(chatID, msg)))
+		}
+		result = append(result, chat)
+	}
+	createdAt := func(chat exportChat) time.Time {
+		if chat.CreatedAt == nil {
+			return time.Time{}
+		}
+		return *chat.CreatedAt
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return createdAt(result[i]).Before(createdAt(result[j]))
+	})
+	return result, nil
+}
+
+// ZIPファイルにファイルを追加する
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
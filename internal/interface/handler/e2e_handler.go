@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 端末の公開鍵の登録リクエスト
+type deviceKeyRequest struct {
+	DeviceName string `json:"deviceName"`
+	PublicKey  string `json:"publicKey"`
+}
+
+// 端末の公開鍵のレスポンス
+type deviceKeyResponse struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userId"`
+	DeviceName  string `json:"deviceName"`
+	PublicKey   string `json:"publicKey"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// 暗号化されたチャット鍵のやりとりに使う構造体
+type wrappedChatKeyPayload struct {
+	DeviceID           string `json:"deviceId"`
+	EphemeralPublicKey string `json:"ephemeralPublicKey"`
+	IV                 string `json:"iv"`
+	WrappedKey         string `json:"wrappedKey"`
+}
+
+// チャット鍵の保存リクエスト
+type chatKeysRequest struct {
+	Enable bool                    `json:"enable"` // 暗号化を有効にするかどうか
+	Keys   []wrappedChatKeyPayload `json:"keys"`
+}
+
+// 相手の端末の公開鍵を取得できるかどうか
+// 自分自身・チャットの参加者同士・ブロックの関係にない連絡先のみ取得できる
+func canViewDeviceKeys(ctx context.Context, userID, otherID string) (bool, error) {
+	if userID == otherID {
+		return true, nil
+	}
+	chatID, err := findChatWith(ctx, userID, otherID)
+	if err != nil {
+		return false, err
+	}
+	if chatID != "" {
+		return true, nil
+	}
+
+	contactIDs, err := repository.GetContactIDs(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !contactIDs[otherID] {
+		return false, nil
+	}
+	blocking, blockedBy, err := repository.GetBlockState(userID, otherID)
+	if err != nil {
+		return false, err
+	}
+	return !blocking && !blockedBy, nil
+}
+
+// 端末の公開鍵の登録・一覧ハンドラ
+func DeviceKeyHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "認証されていません")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// 指定がない場合は自分の端末の一覧
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			userID = session.User.ID
+		}
+		allowed, err := canViewDeviceKeys(r.Context(), session.User.ID, userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "公開鍵の取得権限の確認に失敗", "error", err, "user_id", userID)
+			writeJSONError(w, http.StatusInternalServerError, "公開鍵の取得に失敗しました")
+			return
+		}
+		if !allowed {
+			writeJSONError(w, http.StatusForbidden, "このユーザーの公開鍵は取得できません")
+			return
+		}
+		keys, err := repository.GetDeviceKeysByUser(userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "端末の公開鍵の取得に失敗", "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "公開鍵の取得に失敗しました")
+			return
+		}
+		writeJSON(w, http.StatusOK, toDeviceKeyResponses(keys))
+
+	case http.MethodPost:
+		var req deviceKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "リクエストの形式が不正です")
+			return
+		}
+		fingerprint, err := domain.ParseDevicePublicKey(req.PublicKey)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		deviceName := strings.TrimSpace(req.DeviceName)
+		if deviceName == "" || len(deviceName) > 100 {
+			deviceName = "不明な端末"
+		}
+
+		key, err := repository.RegisterDeviceKey(session.User.ID, deviceName, req.PublicKey, fingerprint)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "公開鍵の登録に失敗しました")
+			return
+		}
+		writeJSON(w, http.StatusCreated, toDeviceKeyResponses([]domain.DeviceKey{*key})[0])
+
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "メソッドが許可されていません")
+	}
+}
+
+// チャット鍵の取得・保存ハンドラ
+func ChatKeyHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "認証されていません")
+		return
+	}
+
+	chatID := r.URL.Query().Get("chat_id")
+	participants, err := firebase.GetChatParticipants(chatID)
+	if chatID == "" || err != nil || !containsString(participants, session.User.ID) {
+		writeJSONError(w, http.StatusNotFound, "チャットが見つかりません")
+		return
+	}
+
+	// 参加者の端末の公開鍵
+	var devices []domain.DeviceKey
+	for _, participantID := range participants {
+		keys, err := repository.GetDeviceKeysByUser(participantID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "参加者の公開鍵の取得に失敗", "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "公開鍵の取得に失敗しました")
+			return
+		}
+		devices = append(devices, keys...)
+	}
+
+	encrypted, err := repository.IsChatEncrypted(chatID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "チャットの取得に失敗しました")
+		return
+	}
+	wrappedKeys, err := repository.GetWrappedChatKeys(chatID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "チャット鍵の取得に失敗しました")
+		return
+	}
+
+	// まだチャット鍵を持たない参加者の端末
+	hasKey := make(map[string]bool)
+	for _, key := range wrappedKeys {
+		hasKey[key.DeviceID] = true
+	}
+	missing := make(map[string]domain.DeviceKey)
+	var missingDevices []domain.DeviceKey
+	for _, device := range devices {
+		if !hasKey[device.ID] {
+			missing[device.ID] = device
+			missingDevices = append(missingDevices, device)
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// 自分の端末のチャット鍵と、まだ鍵を持たない端末を返す
+		deviceID := r.URL.Query().Get("device_id")
+		var ownKey *wrappedChatKeyPayload
+		for _, key := range wrappedKeys {
+			if key.DeviceID == deviceID && key.UserID == session.User.ID {
+				ownKey = &wrappedChatKeyPayload{
+					DeviceID:           key.DeviceID,
+					EphemeralPublicKey: key.EphemeralPublicKey,
+					IV:                 key.IV,
+					WrappedKey:         key.WrappedKey,
+				}
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"encrypted":      encrypted,
+			"key":            ownKey,
+			"devices":        toDeviceKeyResponses(devices),
+			"missingDevices": toDeviceKeyResponses(missingDevices),
+		})
+
+	case http.MethodPost:
+		var req chatKeysRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 256<<10)).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "リクエストの形式が不正です")
+			return
+		}
+
+		// 有効にするのは暗号化されていないチャットのみ、鍵の共有は暗号化されたチャットのみ
+		if req.Enable && encrypted {
+			writeJSONError(w, http.StatusConflict, "既に暗号化されています")
+			return
+		}
+		if !req.Enable && !encrypted {
+			writeJSONError(w, http.StatusBadRequest, "チャットは暗号化されていません")
+			return
+		}
+
+		// まだ鍵を持たない参加者の端末宛ての鍵のみ受け付ける（保存済みの鍵は他の参加者が差し替えられないようにする）
+		var keys []domain.WrappedChatKey
+		received := make(map[string]bool)
+		for _, key := range req.Keys {
+			device, ok := missing[key.DeviceID]
+			if !ok || received[key.DeviceID] || key.EphemeralPublicKey == "" || key.IV == "" || key.WrappedKey == "" {
+				writeJSONError(w, http.StatusBadRequest, "チャット鍵の形式が不正です")
+				return
+			}
+			received[key.DeviceID] = true
+			keys = append(keys, domain.WrappedChatKey{
+				DeviceID:           key.DeviceID,
+				UserID:             device.UserID,
+				EphemeralPublicKey: key.EphemeralPublicKey,
+				IV:                 key.IV,
+				WrappedKey:         key.WrappedKey,
+			})
+		}
+		if len(keys) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "チャット鍵がありません")
+			return
+		}
+
+		if req.Enable {
+			// ボットは鍵を持たないため、ボットが追加されたチャットは暗号化できない
+			bots, err := firebase.GetChatBots(chatID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "チャットのボットの取得に失敗", "error", err, "chat_id", chatID)
+				writeJSONError(w, http.StatusInternalServerError, "チャットの取得に失敗しました")
+				return
+			}
+			if len(bots) > 0 {
+				writeJSONError(w, http.StatusBadRequest, "ボットが追加されたチャットは暗号化できません。先にボットを外してください")
+				return
+			}
+
+			// 全ての参加者が少なくとも1台の端末で復号できる必要がある
+			for _, participantID := range participants {
+				if !hasKeyForUser(keys, participantID) {
+					writeJSONError(w, http.StatusBadRequest, "暗号化に対応していない参加者がいます")
+					return
+				}
+			}
+			err = repository.EnableChatEncryption(chatID, keys)
+		} else {
+			err = repository.SaveWrappedChatKeys(chatID, keys)
+		}
+		if errors.Is(err, repository.ErrChatKeyExists) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "チャット鍵の保存に失敗しました")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"saved": len(keys)})
+
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "メソッドが許可されていません")
+	}
+}
+
+// レスポンス用に変換する
+func toDeviceKeyResponses(keys []domain.DeviceKey) []deviceKeyResponse {
+	responses := []deviceKeyResponse{}
+	for _, key := range keys {
+		responses = append(responses, deviceKeyResponse{
+			ID:          key.ID,
+			UserID:      key.UserID,
+			DeviceName:  key.DeviceName,
+			PublicKey:   key.PublicKey,
+			Fingerprint: key.Fingerprint,
+		})
+	}
+	return responses
+}
+
+// 指定したユーザーの端末宛ての鍵が含まれるか
+func hasKeyForUser(keys []domain.WrappedChatKey, userID string) bool {
+	for _, key := range keys {
+		if key.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// スライスに文字列が含まれるか
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/utils/buildinfo"
+)
+
+// 依存サービスの確認のタイムアウト
+const readinessTimeout = 5 * time.Second
+
+// 依存サービスの確認結果
+type dependencyStatus struct {
+	Status    string `json:"status"`     // "ok" または "error"（失敗の理由はログにのみ出力する）
+	LatencyMs int64  `json:"latency_ms"` // 確認にかかった時間（ミリ秒）
+}
+
+// 準備完了の確認対象
+var readinessChecks = map[string]func(ctx context.Context) error{
+	"datastore": firebase.CheckFirestore,
+	"storage":   firebase.CheckStorage,
+	"templates": func(ctx context.Context) error {
+		return markup.CheckTemplates()
+	},
+}
+
+// HealthzHandler プロセスが応答できるかを返す（依存サービスは確認しない）
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler 依存サービスごとの状態を確認し、すべて利用可能な場合のみ200を返す
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]dependencyStatus, len(readinessChecks))
+	for name, check := range readinessChecks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			result := dependencyStatus{
+				Status:    "ok",
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "準備状態の確認に失敗", "check", name, "error", err)
+				result.Status = "error"
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := "ok"
+	code := http.StatusOK
+	for _, result := range results {
+		if result.Status != "ok" {
+			status = "unavailable"
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// VersionHandler ビルド時に埋め込まれたバージョン情報を返す
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 受信用Webhookで投稿できる内容の上限
+const (
+	maxIncomingTextLength      = 4000
+	maxIncomingAttachments     = 10
+	maxIncomingAttachmentTitle = 100
+	maxIncomingAttachmentURL   = 2048
+)
+
+// 受信用Webhookへの投稿の頻度の制限（接続元ごと・Webhookごと）
+// 接続元ごとの制限はトークンの照合より前に行い、トークンの総当たりも抑える
+var (
+	incomingClientLimiter  = middleware.NewRateLimiter(config.Config.IncomingRateLimit)
+	incomingWebhookLimiter = middleware.NewRateLimiter(config.Config.IncomingRateLimit)
+)
+
+// apiIncomingWebhookRequest 受信用Webhookに投稿するメッセージ
+type apiIncomingWebhookRequest struct {
+	Text        string          `json:"text" doc:"本文（添付がない場合は必須、4000文字まで）"`
+	Username    string          `json:"username,omitempty" doc:"送信者として表示する名前（省略時は受信用Webhookの名前、2〜20文字）"`
+	Attachments []apiAttachment `json:"attachments,omitempty" doc:"添付（http / https のリンク、10件まで）"`
+}
+
+// 受信用Webhookに投稿されたメッセージをチャットに追加する
+// 認証はURLに含まれるトークンのみで行い、メッセージは受信用Webhookを送信者として保存する
+func apiPostIncomingWebhook(w http.ResponseWriter, r *http.Request) error {
+	if err := incomingClientLimiter.Check(w, middleware.ClientKey(r)); err != nil {
+		return err
+	}
+
+	token := r.PathValue("token")
+	if !domain.IsIncomingWebhookToken(token) {
+		return domain.NewNotFoundError("受信用Webhookが見つかりません", nil)
+	}
+	hook, err := repository.GetIncomingWebhookByToken(r.Context(), token)
+	if err != nil {
+		return domain.NewInternalError("受信用Webhookの取得に失敗しました", err)
+	}
+	if hook == nil {
+		return domain.NewNotFoundError("受信用Webhookが見つかりません", nil)
+	}
+	if err := incomingWebhookLimiter.Check(w, hook.ID); err != nil {
+		return err
+	}
+
+	var req apiIncomingWebhookRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		return err
+	}
+	input, err := newIncomingMessage(hook, req)
+	if err != nil {
+		return err
+	}
+
+	// 作成したユーザーがチャットの参加者でなくなった場合や、相手とブロックの関係にある場合は投稿できない
+	participants, err := firebase.GetChatParticipants(hook.ChatID)
+	if err != nil {
+		return domain.NewNotFoundError("チャットが見つかりません", err)
+	}
+	if !containsString(participants, hook.CreatorID) {
+		return domain.NewForbiddenError("このチャットに投稿する権限がありません", nil)
+	}
+	if err := requireNotBlockedInChat(hook.CreatorID, participants); err != nil {
+		return err
+	}
+
+	message, err := postChatMessage(r.Context(), hook.ChatID, participants, input)
+	if err != nil {
+		return err
+	}
+	if err := repository.TouchIncomingWebhook(hook.ID, message.CreatedAt); err != nil {
+		slog.WarnContext(r.Context(), "受信用Webhookの最終利用日時の更新に失敗", "error", err, "incoming_webhook_id", hook.ID)
+	}
+	slog.InfoContext(r.Context(), "受信用Webhookからメッセージを投稿", "incoming_webhook_id", hook.ID, "chat_id", hook.ChatID, "message_id", message.ID)
+	return writeAPIResponse(w, http.StatusCreated, toAPIMessage(*message))
+}
+
+// 受信用Webhookへの投稿を検証し、チャットに投稿するメッセージに変換する
+func newIncomingMessage(hook *domain.IncomingWebhook, req apiIncomingWebhookRequest) (chatMessageInput, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" && len(req.Attachments) == 0 {
+		return chatMessageInput{}, domain.NewValidationError("text または attachments を指定してください", nil)
+	}
+	if len([]rune(text)) > maxIncomingTextLength {
+		return chatMessageInput{}, domain.NewValidationError("text は4000文字以下で指定してください", nil)
+	}
+
+	name := hook.Name
+	if username := strings.TrimSpace(req.Username); username != "" {
+		if len([]rune(username)) < 2 || len([]rune(username)) > 20 {
+			return chatMessageInput{}, domain.NewValidationError("username は2〜20文字で指定してください", nil)
+		}
+		name = username
+	}
+
+	if len(req.Attachments) > maxIncomingAttachments {
+		return chatMessageInput{}, domain.NewValidationError("attachments は10件まで指定できます", nil)
+	}
+	attachments := make([]domain.Attachment, 0, len(req.Attachments))
+	for _, attachment := range req.Attachments {
+		if !isLinkURL(attachment.URL) || len(attachment.URL) > maxIncomingAttachmentURL {
+			return chatMessageInput{}, domain.NewValidationError("attachments の url には http または https のURLを指定してください", nil)
+		}
+		if len([]rune(attachment.Title)) > maxIncomingAttachmentTitle {
+			return chatMessageInput{}, domain.NewValidationError("attachments の title は100文字以下で指定してください", nil)
+		}
+		attachments = append(attachments, domain.Attachment{Title: strings.TrimSpace(attachment.Title), URL: attachment.URL})
+	}
+
+	return chatMessageInput{
+		SenderID:    domain.SenderTypeIntegration + ":" + hook.ID,
+		SenderName:  name,
+		SenderType:  domain.SenderTypeIntegration,
+		Content:     text,
+		Attachments: attachments,
+	}, nil
+}
+
+// 添付として表示できるリンク（http / https の絶対URL）かどうか
+func isLinkURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// 受信用WebhookのURL（作成・再発行の直後に表示する）
+func incomingWebhookURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || config.Config.UseSecureCookie() {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + APIPrefix + "/hooks/" + token
+}
@@ -1,10 +1,11 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/metrics"
 	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/markup"
 	"security_chat_app/internal/interface/middleware"
@@ -12,15 +13,14 @@ import (
 )
 
 // ログイン処理
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func LoginHandler(w http.ResponseWriter, r *http.Request) error {
 	// ログイン画面の表示
 	if r.Method == http.MethodGet {
 		data := domain.TemplateData{
 			LoginForm: domain.LoginForm{},
 			Success:   r.URL.Query().Get("success") == "true",
 		}
-		markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
-		return
+		return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
 	}
 
 	// ログイン処理
@@ -41,57 +41,88 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(validationErrors) > 0 {
+			metrics.LoginFailed(metrics.LoginFailureValidation)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				LoginForm:        domain.LoginForm{Email: form.Email, Password: form.Password},
 				ValidationErrors: validationErrors,
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
 		}
 
 		// ユーザー認証
 		user, err := repository.GetUserByEmail(form.Email)
 		if err != nil {
-			log.Printf("ユーザー認証エラー: %v", err)
+			slog.ErrorContext(r.Context(), "ユーザー認証エラー", "error", err)
+			metrics.LoginFailed(metrics.LoginFailureError)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				LoginForm:        domain.LoginForm{Email: form.Email, Password: form.Password},
 				ValidationErrors: []string{"認証エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
 		}
 
 		if user == nil || !uuid.VerifyPassword(user.Password, form.Password) {
+			metrics.LoginFailed(metrics.LoginFailureCredentials)
+			recordLoginFailure(r, user, form.Email, metrics.LoginFailureCredentials)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				LoginForm:        domain.LoginForm{Email: form.Email, Password: form.Password},
 				ValidationErrors: []string{"メールアドレスまたはパスワードが誤っています"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
+		}
+		if user.Suspended {
+			metrics.LoginFailed(metrics.LoginFailureSuspended)
+			recordLoginFailure(r, user, form.Email, metrics.LoginFailureSuspended)
+			data := domain.TemplateData{
+				IsLoggedIn:       false,
+				LoginForm:        domain.LoginForm{Email: form.Email},
+				ValidationErrors: []string{"このアカウントは停止されています"},
+			}
+			return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
+		}
+		if user.PasswordResetRequired {
+			// パスワードを再設定するまでセッションは作成しない
+			metrics.LoginFailed(metrics.LoginFailurePasswordReset)
+			recordLoginFailure(r, user, form.Email, metrics.LoginFailurePasswordReset)
+			data := domain.TemplateData{
+				IsLoggedIn:       false,
+				ResetForm:        domain.ResetForm{Email: form.Email},
+				ValidationErrors: []string{"管理者によりパスワードの再設定が求められています。新しいパスワードを設定してください"},
+			}
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
-		// セッションの作成
-		session, err := middleware.CreateSession(user)
+		// セッションの作成（既存のセッションIDは破棄してローテーションする）
+		_, err = middleware.RotateSession(w, r, user)
 		if err != nil {
-			log.Printf("セッション作成エラー: %v", err)
+			slog.ErrorContext(r.Context(), "セッション作成エラー", "error", err)
+			metrics.LoginFailed(metrics.LoginFailureError)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				LoginForm:        domain.LoginForm{Email: form.Email, Password: form.Password},
 				ValidationErrors: []string{"セッション作成エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "login", "footer")
 		}
 
-		// セッションクッキーの設定
-		middleware.SetSessionCookie(w, session)
+		recordAudit(r, domain.AuditLoginSucceeded, user.ID, user.ID, nil)
+
 		http.Redirect(w, r, "/profile", http.StatusSeeOther)
-		return
+		return nil
 	}
 
 	// その他のHTTPメソッドは許可しない
-	log.Fatalf("メソッドが許可されていません")
+	return domain.NewMethodNotAllowedError()
+}
+
+// ログインの失敗を監査ログに記録する（メールアドレスに該当するユーザーがいる場合はそのユーザーを対象にする）
+func recordLoginFailure(r *http.Request, user *domain.User, email, reason string) {
+	targetID := ""
+	if user != nil {
+		targetID = user.ID
+	}
+	recordAudit(r, domain.AuditLoginFailed, "", targetID, map[string]string{"email": email, "reason": reason})
 }
@@ -1,28 +1,34 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
-	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/middleware"
 )
 
 // ログアウト処理を実行
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodPost {
-		session, err := middleware.ValidateSession(w, r)
-		if err == nil && session != nil && session.User != nil {
-			if err := firebase.UpdateField("users", session.User.ID, "IsOnline", false); err != nil {
-				log.Printf("ユーザー状態の更新に失敗: %v", err)
-			}
-		}
-		
-		err = middleware.DeleteSession(w, r)
-		if err != nil {
-			log.Fatalf("ログアウトエラー: %v", err)
-			return
+func LogoutHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	session, err := middleware.ValidateSession(w, r)
+	if err == nil && session != nil && session.User != nil {
+		if err := repository.MarkUserOffline(session.User.ID); err != nil {
+			slog.ErrorContext(r.Context(), "ユーザー状態の更新に失敗", "error", err)
 		}
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
 	}
+
+	err = middleware.DeleteSession(w, r)
+	if err != nil {
+		return domain.NewInternalError("ログアウトに失敗しました", err)
+	}
+	if session != nil && session.User != nil {
+		recordAudit(r, domain.AuditLogout, session.User.ID, session.User.ID, nil)
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	return nil
 }
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler Prometheus形式のメトリクスを返すハンドラを作成する
+// metricsToken が設定されている場合は Authorization: Bearer <token> を要求する
+func MetricsHandler() http.Handler {
+	promHandler := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := config.Config.MetricsToken; token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				writeJSONError(w, http.StatusUnauthorized, "認証が必要です")
+				return
+			}
+		}
+		promHandler.ServeHTTP(w, r)
+	})
+}
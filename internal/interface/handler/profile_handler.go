@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,34 +27,37 @@ type ProfileData struct {
 	IsLoggedIn     bool
 	LoggedInUserID string
 	User           *domain.User
+	DeviceKeys     []domain.DeviceKey // 表示中のユーザーの端末の公開鍵
+	SafetyNumber   string             // ログインユーザーとの安全番号（他ユーザーの場合のみ）
+	IsBlocked      bool               // ログインユーザーがブロックしているかどうか（他ユーザーの場合のみ）
+	ChatID         string             // ログインユーザーとのチャットのID（他ユーザーの場合のみ、チャットがない場合は空）
+	IsChatMuted    bool               // ログインユーザーがチャットをミュートしているかどうか
 }
 
 // プロフィールページの表示
-func ProfileHandler(w http.ResponseWriter, r *http.Request) {
-	session, err := middleware.ValidateSession(w, r)
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+func ProfileHandler(w http.ResponseWriter, r *http.Request) error {
+	// セッション（またはAPIトークン）の検証はミドルウェアで行う
+	sessionUser := middleware.SessionUser(r)
+	if sessionUser == nil {
+		return domain.NewUnauthorizedError("ログインしてください", nil)
 	}
 
 	// URLからユーザーIDを取得
 	path := r.URL.Path
 	var targetUserID string
 	if path == "/profile" || path == "/profile/" {
-		targetUserID = session.User.ID
+		targetUserID = sessionUser.ID
 	} else {
 		targetUserID = path[len("/profile/"):]
 		if targetUserID == "" {
-			log.Fatalf("ユーザーIDが指定されていません")
-			return
+			return domain.NewValidationError("ユーザーIDが指定されていません", nil)
 		}
 	}
 
 	// ユーザー情報の取得
 	user, err := repository.GetUserByID(targetUserID)
 	if err != nil {
-		log.Fatalf("ユーザー情報の取得に失敗: %v", err)
-		return
+		return domain.NewNotFoundError("ユーザーが見つかりません", err)
 	}
 
 	// アイコンが設定されていない場合はデフォルトアイコンを設定
@@ -60,46 +66,95 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		defaultIconPath := fmt.Sprintf(icons.DefaultIconPath+"/default_icon_%s.png", icons.DefaultIconNames[randomNum])
 		iconURL, er := firebase.GetDefaultIconURL(defaultIconPath)
 		if er != nil {
-			log.Fatalf("デフォルトアイコンの取得に失敗: %v", er)
-			return
+			return domain.NewInternalError("デフォルトアイコンの取得に失敗しました", er)
 		}
 
 		// ユーザーのIconURLを更新
 		user.Icon = iconURL
-		err = firebase.UpdateField("users", user.ID, "Icon", iconURL)
+		err = repository.UpdateUserField(user.ID, "Icon", iconURL)
 		if err != nil {
-			log.Fatalf("アイコンURLの更新に失敗: %v", err)
-			return
+			return domain.NewInternalError("アイコンURLの更新に失敗しました", err)
 		}
 	}
 
 	// 最終更新日時を現在時刻に更新 (自分のプロフィールの場合のみ更新すべきか検討)
-	if targetUserID == session.User.ID {
+	if targetUserID == sessionUser.ID {
 		user.UpdatedAt = time.Now()
-		err = firebase.UpdateField("users", user.ID, "UpdatedAt", user.UpdatedAt)
+		err = repository.UpdateUserField(user.ID, "UpdatedAt", user.UpdatedAt)
 		if err != nil {
-			log.Fatalf("最終更新日時の更新に失敗: %v", err)
-			return
+			return domain.NewInternalError("最終更新日時の更新に失敗しました", err)
+		}
+	}
+
+	// エンドツーエンド暗号化の公開鍵と安全番号
+	deviceKeys, err := repository.GetDeviceKeysByUser(user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "端末の公開鍵の取得に失敗", "error", err)
+	}
+	var safetyNumber string
+	if user.ID != sessionUser.ID && len(deviceKeys) > 0 {
+		ownKeys, err := repository.GetDeviceKeysByUser(sessionUser.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "端末の公開鍵の取得に失敗", "error", err)
+		} else if len(ownKeys) > 0 {
+			safetyNumber = domain.SafetyNumber(sessionUser.ID, ownKeys, user.ID, deviceKeys)
 		}
 	}
 
 	// プロフィールデータの作成
 	data := ProfileData{
 		IsLoggedIn:     true,
-		LoggedInUserID: session.User.ID,
+		LoggedInUserID: sessionUser.ID,
 		User:           user,
+		DeviceKeys:     deviceKeys,
+		SafetyNumber:   safetyNumber,
+	}
+
+	// 他ユーザーの場合は、ブロック・ミュートの状態を表示する
+	if user.ID != sessionUser.ID && !user.IsBot() {
+		data.IsBlocked, _, err = repository.GetBlockState(sessionUser.ID, user.ID)
+		if err != nil {
+			return domain.NewInternalError("ブロックの確認に失敗しました", err)
+		}
+		data.ChatID, err = findChatWith(r.Context(), sessionUser.ID, user.ID)
+		if err != nil {
+			return domain.NewInternalError("チャットの取得に失敗しました", err)
+		}
+		data.IsChatMuted = data.ChatID != "" && sessionUser.IsChatMuted(data.ChatID)
 	}
 
 	// テンプレートを描画
-	markup.GenerateHTML(w, data, "layout", "header", "profile", "footer")
+	return markup.GenerateHTML(w, data, "layout", "header", "profile", "footer")
 }
 
-// アイコンアップロードハンドラ
-func ProfileIconHandler(w http.ResponseWriter, r *http.Request) {
-	session, err := middleware.ValidateSession(w, r)
+// 2人のユーザーのチャットのIDを返す（チャットがない場合は空文字列）
+func findChatWith(ctx context.Context, userID, otherID string) (string, error) {
+	chats, err := firebase.GetAllChats(ctx, userID)
 	if err != nil {
-		log.Fatalf("セッションが無効: %v", err)
-		return
+		return "", err
+	}
+	for _, chat := range chats {
+		participants, _ := chat["participants"].([]interface{})
+		for _, p := range participants {
+			if p == otherID {
+				chatID, _ := chat["id"].(string)
+				return chatID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// アイコンアップロードハンドラ
+func ProfileIconHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッション（またはAPIトークン）の検証はミドルウェアで行う
+	sessionUser := middleware.SessionUser(r)
+	if sessionUser == nil {
+		return domain.NewUnauthorizedError("ログインしてください", nil)
 	}
 
 	// URLからユーザーIDを取得
@@ -111,31 +166,46 @@ func ProfileIconHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 自分のプロフィール以外での変更を防止
-	if targetUserID != "" && targetUserID != session.User.ID {
-		log.Fatalf("他のユーザーのアイコンは変更できません")
-		return
+	if targetUserID != "" && targetUserID != sessionUser.ID {
+		return domain.NewForbiddenError("他のユーザーのアイコンは変更できません", nil)
 	}
 
 	// マルチパートフォームの解析
-	err = r.ParseMultipartForm(10 << 20)
+	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		log.Fatalf("フォームの解析に失敗: %v", err)
-		return
+		return domain.NewValidationError("フォームの解析に失敗しました", err)
 	}
 
 	// アイコンファイルを取得
 	file, header, err := r.FormFile("icon")
 	if err != nil {
-		log.Fatalf("アイコンファイルの取得に失敗: %v", err)
-		return
+		return domain.NewValidationError("アイコンファイルを選択してください", err)
 	}
 	defer file.Close()
 
+	// アイコンを保存し、失敗した場合はプロフィールページにエラーを表示する
+	icon, err := saveProfileIcon(r.Context(), sessionUser.ID, file, header)
+	if err != nil {
+		appErr := domain.AsAppError(err)
+		if appErr.Kind == domain.ErrorKindInternal {
+			slog.ErrorContext(r.Context(), "アイコンの更新に失敗", "error", appErr)
+		}
+		http.Redirect(w, r, "/profile?error="+url.QueryEscape(appErr.Message), http.StatusSeeOther)
+		return nil
+	}
+	recordAudit(r, domain.AuditIconChanged, sessionUser.ID, sessionUser.ID, map[string]string{"icon": icon})
+
+	// プロフィールページにリダイレクト
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	return nil
+}
+
+// アイコン画像を検証してストレージに保存し、ユーザーのアイコンURLを更新する（画面・APIで共通の処理）
+func saveProfileIcon(ctx context.Context, userID string, file multipart.File, header *multipart.FileHeader) (string, error) {
 	// ファイルサイズの制限（5MB）
 	const maxFileSize = 5 * 1024 * 1024
 	if header.Size > maxFileSize {
-		log.Fatalf("ファイルサイズは5MB以下にしてください: %v", err)
-		return
+		return "", domain.NewValidationError("ファイルサイズは5MB以下にしてください", nil)
 	}
 
 	// ファイルの拡張子を取得と検証
@@ -146,63 +216,46 @@ func ProfileIconHandler(w http.ResponseWriter, r *http.Request) {
 		".png":  true,
 	}
 	if !allowedExts[ext] {
-		http.Redirect(w, r, "/profile?error=アップロードできるファイル形式は.jpg、.jpeg、.pngのみです", http.StatusSeeOther)
-		return
+		return "", domain.NewValidationError("アップロードできるファイル形式は.jpg、.jpeg、.pngのみです", nil)
 	}
 
 	// 画像ファイルの検証
 	buff := make([]byte, 512)
-	_, err = file.Read(buff)
+	_, err := file.Read(buff)
 	if err != nil {
-		log.Fatalf("ファイルの読み込みに失敗: %v", err)
-		http.Redirect(w, r, "/profile?error=ファイルの読み込みに失敗しました", http.StatusSeeOther)
-		return
+		slog.WarnContext(ctx, "ファイルの読み込みに失敗", "error", err)
+		return "", domain.NewValidationError("ファイルの読み込みに失敗しました", err)
 	}
 	filetype := http.DetectContentType(buff)
 	if !strings.HasPrefix(filetype, "image/") {
-		log.Fatalf("画像ファイルのみアップロード可能: %v", err)
-		http.Redirect(w, r, "/profile?error=画像ファイルのみアップロード可能です", http.StatusSeeOther)
-		return
+		return "", domain.NewValidationError("画像ファイルのみアップロード可能です", nil)
 	}
 	file.Seek(0, 0)
 
 	// 一時ファイルを作成
 	tempFile, err := os.CreateTemp("", "icon-*"+ext)
 	if err != nil {
-		log.Fatalf("一時ファイルの作成に失敗: %v", err)
-		return
+		return "", domain.NewInternalError("アイコンの保存に失敗しました", err)
 	}
+	defer os.Remove(tempFile.Name())
 	defer tempFile.Close()
 
 	// ファイルをコピー
 	_, err = io.Copy(tempFile, file)
 	if err != nil {
-		log.Fatalf("ファイルの保存に失敗: %v", err)
-		return
+		return "", domain.NewInternalError("アイコンの保存に失敗しました", err)
 	}
 
-	// 一時ファイルのパスを取得
-	tempFilePath := tempFile.Name()
-
 	// Firebase Storageにアップロード
-	iconURL, err := firebase.UploadIcon(session.User.ID, tempFilePath)
+	iconURL, err := firebase.UploadIcon(userID, tempFile.Name())
 	if err != nil {
-		log.Fatalf("アイコンのアップロードに失敗: %v", err)
-		http.Redirect(w, r, "/profile?error=アイコンのアップロードに失敗しました", http.StatusSeeOther)
-		return
+		return "", domain.NewInternalError("アイコンのアップロードに失敗しました", err)
 	}
 
-	// 一時ファイルを削除
-	os.Remove(tempFilePath)
-
 	// ユーザードキュメントを更新
-	err = firebase.UpdateField("users", session.User.ID, "Icon", iconURL)
+	err = repository.UpdateUserField(userID, "Icon", iconURL)
 	if err != nil {
-		log.Fatalf("ユーザー情報の更新に失敗: %v", err)
-		http.Redirect(w, r, "/profile?error=ユーザー情報の更新に失敗しました", http.StatusSeeOther)
-		return
+		return "", domain.NewInternalError("ユーザー情報の更新に失敗しました", err)
 	}
-
-	// プロフィールページにリダイレクト
-	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	return iconURL, nil
 }
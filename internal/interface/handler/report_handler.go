@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 通報に保存する、通報されたメッセージの前後のメッセージの件数（それぞれ）
+const reportContextSize = 3
+
+// 通報の詳細の最大文字数
+const maxReportDetailsLength = 1000
+
+// 通報ページのデータ構造体
+type ReportPageData struct {
+	IsLoggedIn       bool                  // ログイン状態
+	User             *domain.User          // ユーザー情報
+	Form             ReportForm            // 入力内容
+	Reasons          []domain.ReportReason // 選択できる通報の理由
+	TargetUser       domain.Contact        // 通報の対象のユーザー（メッセージの場合は送信者）
+	Message          *domain.Message       // 通報の対象のメッセージ（ユーザーの場合は nil）
+	ValidationErrors []string              // バリデーションエラー
+	Submitted        bool                  // 通報を受け付けたかどうか
+}
+
+// 通報フォーム
+type ReportForm struct {
+	TargetType string // 通報の対象の種類
+	ChatID     string // 通報するメッセージのチャットのID
+	MessageID  string // 通報するメッセージのID
+	UserID     string // 通報するユーザーのID
+	Reason     string // 通報の理由
+	Details    string // 詳細
+}
+
+// メッセージ・ユーザーの通報のハンドラ
+// メッセージは chat_id と message_id、ユーザーは user_id で対象を指定する
+func ReportHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	data := ReportPageData{
+		IsLoggedIn: true,
+		User:       session.User,
+		Reasons:    domain.ReportReasons,
+		Form: ReportForm{
+			ChatID:    r.FormValue("chat_id"),
+			MessageID: r.FormValue("message_id"),
+			UserID:    r.FormValue("user_id"),
+			Reason:    r.FormValue("reason"),
+			Details:   strings.TrimSpace(r.FormValue("details")),
+		},
+	}
+	if err := loadReportTarget(r, session.User, &data); err != nil {
+		return err
+	}
+
+	if r.Method == http.MethodGet {
+		return markup.GenerateHTML(w, data, "layout", "header", "report", "footer")
+	}
+
+	data.ValidationErrors = validateReportForm(data.Form)
+	if len(data.ValidationErrors) > 0 {
+		return markup.GenerateHTML(w, data, "layout", "header", "report", "footer")
+	}
+
+	report := &domain.Report{
+		ReporterID:   session.User.ID,
+		TargetType:   data.Form.TargetType,
+		TargetUserID: data.TargetUser.ID,
+		Reason:       data.Form.Reason,
+		Details:      data.Form.Details,
+	}
+	if data.Message != nil {
+		report.ChatID = data.Message.ChatID
+		report.MessageID = data.Message.ID
+	}
+	if err := repository.CreateReport(report); err != nil {
+		return domain.NewInternalError("通報の送信に失敗しました", err)
+	}
+	if data.Message != nil {
+		// 前後のメッセージは確認の参考のため、保存に失敗しても通報は受け付ける
+		if err := saveReportContext(r, report); err != nil {
+			slog.ErrorContext(r.Context(), "通報の前後のメッセージの保存に失敗", "error", err, "report_id", report.ID)
+		}
+	}
+	slog.InfoContext(r.Context(), "通報を受け付け", "user_id", session.User.ID, "report_id", report.ID, "target_type", report.TargetType, "target_user_id", report.TargetUserID)
+
+	data.Submitted = true
+	return markup.GenerateHTML(w, data, "layout", "header", "report", "footer")
+}
+
+// 通報の対象（メッセージ・ユーザー）を取得する
+// メッセージは参加しているチャットの他のユーザーのもののみ、ユーザーは自分以外のみ通報できる
+func loadReportTarget(r *http.Request, user *domain.User, data *ReportPageData) error {
+	targetUserID := data.Form.UserID
+	if data.Form.MessageID != "" {
+		data.Form.TargetType = domain.ReportTargetMessage
+		if _, err := requireChatParticipant(data.Form.ChatID, user.ID); err != nil {
+			return err
+		}
+		msg, err := firebase.GetChatMessage(r.Context(), data.Form.ChatID, data.Form.MessageID)
+		if err != nil {
+			return domain.NewInternalError("メッセージの取得に失敗しました", err)
+		}
+		if msg == nil {
+			return domain.NewNotFoundError("メッセージが見つかりません", nil)
+		}
+		message := messageFromData(data.Form.ChatID, msg)
+		if message.SenderID == user.ID {
+			return domain.NewValidationError("自分のメッセージは通報できません", nil)
+		}
+		data.Message = &message
+		targetUserID = message.SenderID
+	} else {
+		data.Form.TargetType = domain.ReportTargetUser
+		if targetUserID == "" {
+			return domain.NewValidationError("通報の対象が指定されていません", nil)
+		}
+		if targetUserID == user.ID {
+			return domain.NewValidationError("自分自身は通報できません", nil)
+		}
+	}
+
+	target, err := GetUserData(targetUserID)
+	if err != nil {
+		// 受信用Webhookのメッセージなど、送信者がユーザーでない場合はメッセージの表示名を使う
+		if data.Message == nil {
+			return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+		}
+		data.TargetUser = domain.Contact{ID: targetUserID, Username: data.Message.SenderName}
+		return nil
+	}
+	data.TargetUser = domain.Contact{ID: target.ID, Username: target.Name, Icon: target.Icon}
+	return nil
+}
+
+// 通報フォームのバリデーション
+func validateReportForm(form ReportForm) []string {
+	var validationErrors []string
+	if !domain.IsReportReason(form.Reason) {
+		validationErrors = append(validationErrors, "通報の理由を選択してください")
+	}
+	if form.Reason == domain.ReportReasonOther && form.Details == "" {
+		validationErrors = append(validationErrors, "「その他」を選択した場合は詳細を入力してください")
+	}
+	if len([]rune(form.Details)) > maxReportDetailsLength {
+		validationErrors = append(validationErrors, "詳細は1000文字以内で入力してください")
+	}
+	return validationErrors
+}
+
+// 通報されたメッセージと前後のメッセージを、通報時点の内容として保存する
+// 後からメッセージが編集・削除されても、管理者は通報時点の内容を確認できる
+func saveReportContext(r *http.Request, report *domain.Report) error {
+	messages, err := firebase.GetChatMessages(r.Context(), report.ChatID)
+	if err != nil {
+		return err
+	}
+	index := -1
+	for i, msg := range messages {
+		if id, _ := msg["id"].(string); id == report.MessageID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+	start := max(index-reportContextSize, 0)
+	end := min(index+reportContextSize+1, len(messages))
+	return firebase.SaveReportContext(r.Context(), report.ID, report.ChatID, messages[start:end])
+}
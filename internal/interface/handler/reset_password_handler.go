@@ -1,30 +1,30 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/markup"
 	utils "security_chat_app/internal/utils/uuid"
 )
 
 // パスワード再設定処理を実行
-func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) error {
 	if r.Method == http.MethodGet {
 		data := domain.TemplateData{
 			IsLoggedIn: false,
 			ResetForm:  domain.ResetForm{},
 		}
-		markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-		return
+		return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 	}
 
 	if r.Method == http.MethodPost {
 		r.ParseForm()
 		form := domain.ResetForm{
-			Email: r.FormValue("email"),
+			Email: domain.NormalizeEmail(r.FormValue("email")),
 		}
 		password := r.FormValue("password")
 		passwordConfirm := r.FormValue("password_confirm")
@@ -44,72 +44,69 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(validationErrors) > 0 {
-			log.Printf("バリデーションエラー: %v", validationErrors)
+			slog.InfoContext(r.Context(), "バリデーションエラー", "errors", validationErrors)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				ResetForm:        form,
 				ValidationErrors: validationErrors,
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
 		// ユーザー検索
 		users, err := firebase.GetDataByQuery("users", "Email", "==", form.Email)
 		if err != nil {
-			log.Printf("ユーザー検索エラー: %v", err)
+			slog.ErrorContext(r.Context(), "ユーザー検索エラー", "error", err)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				ResetForm:        form,
 				ValidationErrors: []string{"ユーザー検索エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
 		if len(users) == 0 {
-			log.Printf("ユーザーが見つかりません: %s", form.Email)
+			slog.InfoContext(r.Context(), "ユーザーが見つかりません", "email", form.Email)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				ResetForm:        form,
 				ValidationErrors: []string{"該当するユーザーが見つかりません"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
 		// パスワードのハッシュ化
 		hashedPassword, err := utils.HashPassword(password)
 		if err != nil {
-			log.Printf("パスワードハッシュ化エラー: %v", err)
+			slog.ErrorContext(r.Context(), "パスワードハッシュ化エラー", "error", err)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				ResetForm:        form,
 				ValidationErrors: []string{"パスワード更新エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
-		// パスワード更新
+		// パスワード更新（既存のセッションはすべて無効になる）
 		userID := users[0]["ID"].(string)
-		err = firebase.UpdateField("users", userID, "Password", hashedPassword)
+		err = repository.UpdateUserCredential(userID, "Password", hashedPassword)
 		if err != nil {
-			log.Printf("パスワード更新エラー: %v", err)
+			slog.ErrorContext(r.Context(), "パスワード更新エラー", "error", err)
 			data := domain.TemplateData{
 				IsLoggedIn:       false,
 				ResetForm:        form,
 				ValidationErrors: []string{"パスワード更新エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "reset-password", "footer")
 		}
 
+		recordAudit(r, domain.AuditPasswordReset, "", userID, nil)
+
 		// 成功時はログインページにリダイレクト
 		http.Redirect(w, r, "/login?success=パスワードを再設定しました", http.StatusSeeOther)
-		return
+		return nil
 	}
 
 	// その他のHTTPメソッドは許可しない
-	log.Fatalf("メソッドが許可されていません")
+	return domain.NewMethodNotAllowedError()
 }
@@ -2,13 +2,14 @@ package handler
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sort"
 	"time"
 
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/markup"
 	"security_chat_app/internal/interface/middleware"
 )
@@ -22,23 +23,21 @@ type SearchPageData struct {
 }
 
 // 検索ハンドラ
-func SearchHandler(w http.ResponseWriter, r *http.Request) {
-	// セッションの検証
-	session, err := middleware.ValidateSession(w, r)
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+func SearchHandler(w http.ResponseWriter, r *http.Request) error {
+	// セッション（またはAPIトークン）の検証はミドルウェアで行う
+	user := middleware.SessionUser(r)
+	if user == nil {
+		return domain.NewUnauthorizedError("ログインしてください", nil)
 	}
 
 	// 検索ページのデータを取得
-	data, err := getSearchPageData(session.User, r)
+	data, err := getSearchPageData(user, r)
 	if err != nil {
-		log.Fatalf("検索データの取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("検索データの取得に失敗しました", err)
 	}
 
 	// テンプレートのレンダリング
-	markup.GenerateHTML(w, data, "layout", "header", "search", "footer")
+	return markup.GenerateHTML(w, data, "layout", "header", "search", "footer")
 }
 
 // 検索ページのデータを取得
@@ -69,8 +68,8 @@ func getSearchPageData(user *domain.User, r *http.Request) (SearchPageData, erro
 		return SearchPageData{}, fmt.Errorf("チャット履歴の取得に失敗しました: %v", err)
 	}
 
-	// チャット履歴のあるユーザーIDを集める
-	chattedUsers := make(map[string]bool)
+	// チャット履歴のあるユーザーIDと、そのチャットのIDを集める
+	chattedUsers := make(map[string]string)
 	for _, chatData := range chats {
 		participants, ok := chatData["participants"].([]interface{})
 		if !ok {
@@ -89,15 +88,40 @@ func getSearchPageData(user *domain.User, r *http.Request) (SearchPageData, erro
 		// 現在のユーザーが参加しているチャットの場合のみ、
 		// 相手のユーザーIDをchattedUsersに追加
 		if isUserChat {
+			chatID, _ := chatData["id"].(string)
 			for _, p := range participants {
 				if participantID, ok := p.(string); ok && participantID != user.ID {
-					chattedUsers[participantID] = true
+					chattedUsers[participantID] = chatID
 				}
 			}
 		}
 	}
 
-	// 自分以外かつチャット履歴のないユーザーをフィルタリング
+	// 連絡先と、やり取り中の連絡先の申請を取得
+	contactIDs, err := repository.GetContactIDs(r.Context(), user.ID)
+	if err != nil {
+		return SearchPageData{}, fmt.Errorf("連絡先の取得に失敗しました: %v", err)
+	}
+	incoming, outgoing, err := repository.GetContactRequests(r.Context(), user.ID)
+	if err != nil {
+		return SearchPageData{}, fmt.Errorf("連絡先の申請の取得に失敗しました: %v", err)
+	}
+
+	// 自分をブロックしているユーザーは表示せず、自分がブロックしているユーザーはブロック中として表示する
+	blockerIDs, err := repository.GetBlockerIDs(user.ID)
+	if err != nil {
+		return SearchPageData{}, fmt.Errorf("ブロックの取得に失敗しました: %v", err)
+	}
+	blocks, err := repository.GetBlocksByBlocker(r.Context(), user.ID)
+	if err != nil {
+		return SearchPageData{}, fmt.Errorf("ブロックの取得に失敗しました: %v", err)
+	}
+	blockedIDs := make(map[string]bool, len(blocks))
+	for _, block := range blocks {
+		blockedIDs[block.BlockedID] = true
+	}
+
+	// 自分とボット以外のユーザーに、自分との関係を付けて表示する
 	var filteredUsers []map[string]interface{}
 	for _, u := range users {
 		var userID string
@@ -109,27 +133,49 @@ func getSearchPageData(user *domain.User, r *http.Request) (SearchPageData, erro
 			// 大文字が失敗したら小文字の「id」を試す
 			userID, ok = u["id"].(string)
 			if !ok {
-				log.Printf("ユーザーIDの取得に失敗: %+v", u)
+				slog.WarnContext(r.Context(), "ユーザーIDの取得に失敗")
 				continue
 			}
 		}
 
-		// 自分自身は除外
-		if userID == user.ID {
+		// 自分自身とボット、自分をブロックしているユーザーは除外
+		if userID == user.ID || u["Type"] == domain.UserTypeBot || blockerIDs[userID] {
 			continue
 		}
 
-		// チャット履歴のないユーザーのみを追加
-		if !chattedUsers[userID] {
-			// テンプレートで使用するフィールド名に合わせてデータを整形
-			userData := map[string]interface{}{
-				"id":       userID,
-				"name":     u["Name"],
-				"icon":     u["Icon"],
-				"IsOnline": u["IsOnline"],
-			}
-			filteredUsers = append(filteredUsers, userData)
+		// 自分との関係（連絡先・申請中・申請が届いている）
+		relation := domain.RelationNone
+		requestID := ""
+		if contactIDs[userID] {
+			relation = domain.RelationContact
+		} else if request := findContactRequest(incoming, userID, user.ID); request != nil {
+			relation = domain.RelationRequestReceived
+			requestID = request.ID
+		} else if request := findContactRequest(outgoing, user.ID, userID); request != nil {
+			relation = domain.RelationRequestSent
+			requestID = request.ID
+		}
+
+		// 連絡先のみに制限しているユーザーとは、連絡先になるまで新しいチャットを開始できない
+		chatID, hasChat := chattedUsers[userID]
+		contactsOnly, _ := u["ContactsOnly"].(bool)
+		canChat := hasChat || !contactsOnly || relation == domain.RelationContact
+		blocked := blockedIDs[userID]
+
+		// テンプレートで使用するフィールド名に合わせてデータを整形
+		userData := map[string]interface{}{
+			"id":        userID,
+			"name":      u["Name"],
+			"icon":      u["Icon"],
+			"IsOnline":  u["IsOnline"],
+			"CreatedAt": u["CreatedAt"],
+			"relation":  string(relation),
+			"requestID": requestID,
+			"chatID":    chatID,
+			"canChat":   canChat && !blocked,
+			"blocked":   blocked,
 		}
+		filteredUsers = append(filteredUsers, userData)
 	}
 
 	// ユーザーをcreated_atで降順にソート
@@ -159,6 +205,16 @@ func getSearchPageData(user *domain.User, r *http.Request) (SearchPageData, erro
 	return data, nil
 }
 
+// 申請の一覧から、指定したユーザー間の申請を探す
+func findContactRequest(requests []domain.ContactRequest, fromID string, toID string) *domain.ContactRequest {
+	for i := range requests {
+		if requests[i].FromID == fromID && requests[i].ToID == toID {
+			return &requests[i]
+		}
+	}
+	return nil
+}
+
 // ユーザーを検索
 func SearchUsers(query string) ([]map[string]interface{}, error) {
 	users, err := firebase.SearchUser(query)
@@ -193,8 +249,11 @@ func GetUserData(userID string) (*domain.User, error) {
 		return nil, fmt.Errorf("ユーザー名の取得に失敗しました")
 	}
 
+	// ユーザーの種類を取得（ボットはメールアドレスを持たない）
+	userType, _ := userData["Type"].(string)
+
 	email, ok := userData["Email"].(string)
-	if !ok {
+	if !ok && userType != domain.UserTypeBot {
 		return nil, fmt.Errorf("メールアドレスの取得に失敗しました")
 	}
 
@@ -204,17 +263,28 @@ func GetUserData(userID string) (*domain.User, error) {
 		iconURL = icon
 	}
 
+	// ボットの作成者を取得（ボット以外は空文字列）
+	ownerID, _ := userData["OwnerID"].(string)
+
 	// オンラインステータスを取得（存在しない場合はfalse）
 	isOnline := false
 	if online, ok := userData["IsOnline"].(bool); ok {
 		isOnline = online
 	}
+	lastSeenAt, _ := userData["LastSeenAt"].(time.Time)
+
+	// チャットの開始を連絡先のみに制限しているか（存在しない場合はfalse）
+	contactsOnly, _ := userData["ContactsOnly"].(bool)
 
 	return &domain.User{
-		ID:       id,
-		Name:     name,
-		Email:    email,
-		Icon:     iconURL,
-		IsOnline: isOnline,
+		ID:           id,
+		Name:         name,
+		Email:        email,
+		Icon:         iconURL,
+		IsOnline:     isOnline,
+		LastSeenAt:   lastSeenAt,
+		ContactsOnly: contactsOnly,
+		Type:         userType,
+		OwnerID:      ownerID,
 	}, nil
 }
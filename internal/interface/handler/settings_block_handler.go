@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 設定ページに表示するミュート中のチャット
+type MutedChatView struct {
+	ID   string // チャットのID
+	Name string // チャットの相手の名前
+}
+
+// ブロック・ミュートの管理（ブロック・ブロック解除・ミュート・ミュート解除）のハンドラ
+// プロフィールページからの操作は return_to で元のページに戻る
+func BlockSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+	user := session.User
+
+	switch r.URL.Path {
+	case "/settings/blocks":
+		targetID := r.FormValue("user_id")
+		if targetID == "" {
+			return domain.NewValidationError("ユーザーIDが指定されていません", nil)
+		}
+		if targetID == user.ID {
+			return domain.NewValidationError("自分自身はブロックできません", nil)
+		}
+		if _, err := GetUserData(targetID); err != nil {
+			return domain.NewNotFoundError("対象ユーザーが見つかりません", err)
+		}
+		if err := repository.BlockUser(user.ID, targetID); err != nil {
+			return domain.NewInternalError("ユーザーのブロックに失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "ユーザーをブロック", "user_id", user.ID, "blocked_id", targetID)
+
+	case "/settings/blocks/remove":
+		targetID := r.FormValue("user_id")
+		if err := repository.UnblockUser(user.ID, targetID); err != nil {
+			return domain.NewInternalError("ブロックの解除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "ユーザーのブロックを解除", "user_id", user.ID, "blocked_id", targetID)
+
+	case "/settings/mutes":
+		chatID := r.FormValue("chat_id")
+		if _, err := requireChatParticipant(chatID, user.ID); err != nil {
+			return err
+		}
+		if err := repository.MuteChat(user.ID, chatID); err != nil {
+			return domain.NewInternalError("チャットのミュートに失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "チャットをミュート", "user_id", user.ID, "chat_id", chatID)
+
+	case "/settings/mutes/remove":
+		chatID := r.FormValue("chat_id")
+		if err := repository.UnmuteChat(user.ID, chatID); err != nil {
+			return domain.NewInternalError("チャットのミュートの解除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "チャットのミュートを解除", "user_id", user.ID, "chat_id", chatID)
+
+	default:
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+
+	http.Redirect(w, r, localReturnPath(r, "/settings"), http.StatusSeeOther)
+	return nil
+}
+
+// ブロックの関係にある相手とのやり取り（チャットの開始・メッセージの送信・連絡先の申請）を拒否する
+func requireNotBlocked(userID, otherID string) error {
+	blocking, blockedBy, err := repository.GetBlockState(userID, otherID)
+	if err != nil {
+		return domain.NewInternalError("ブロックの確認に失敗しました", err)
+	}
+	if blocking {
+		return domain.NewForbiddenError("ブロック中のユーザーです。ブロックを解除してから操作してください", nil)
+	}
+	if blockedBy {
+		return domain.NewForbiddenError("このユーザーとはやり取りできません", nil)
+	}
+	return nil
+}
+
+// ブロックしたユーザーを設定ページの表示用に取得する（削除されたユーザーは表示しない）
+func getBlockedUsers(ctx context.Context, userID string) ([]domain.Contact, error) {
+	blocks, err := repository.GetBlocksByBlocker(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]domain.Contact, 0, len(blocks))
+	for _, block := range blocks {
+		blocked, err := GetUserData(block.BlockedID)
+		if err != nil {
+			slog.WarnContext(ctx, "ブロックしたユーザーの取得に失敗", "error", err, "blocked_id", block.BlockedID)
+			continue
+		}
+		users = append(users, domain.Contact{ID: blocked.ID, Username: blocked.Name, Icon: blocked.Icon})
+	}
+	return users, nil
+}
+
+// ミュート中のチャットを設定ページの表示用に取得する
+func getMutedChatViews(user *domain.User, chats []WebhookChatOption) []MutedChatView {
+	var views []MutedChatView
+	for _, chat := range chats {
+		if user.IsChatMuted(chat.ID) {
+			views = append(views, MutedChatView{ID: chat.ID, Name: chat.Name})
+		}
+	}
+	return views
+}
+
+// チャットをミュートしていない参加者に絞り込む（ミュート中の参加者には新しいメッセージを通知しない）
+func unmutedParticipants(ctx context.Context, chatID string, participants []string) []string {
+	result := make([]string, 0, len(participants))
+	for _, id := range participants {
+		participant, err := repository.GetUserByID(id)
+		if err != nil {
+			slog.WarnContext(ctx, "参加者の情報取得に失敗", "error", err, "user_id", id)
+			result = append(result, id)
+			continue
+		}
+		if !participant.IsChatMuted(chatID) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// チャットの他の参加者とブロックの関係にある場合は、メッセージの送信を拒否する
+func requireNotBlockedInChat(senderID string, participants []string) error {
+	for _, id := range participants {
+		if id == senderID {
+			continue
+		}
+		if err := requireNotBlocked(senderID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/infrastructure/webhook"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 1ユーザーが作成できるボットの上限
+const maxBotsPerUser = 10
+
+// ボット作成フォーム
+type BotForm struct {
+	Name       string // ボットの名前
+	WebhookURL string // メッセージを受け取るWebhookのURL（任意）
+}
+
+// 作成・再発行した直後にのみ表示するボットの認証情報
+type BotCredentials struct {
+	BotID         string // ボットのID
+	Token         string // APIトークン
+	WebhookSecret string // Webhookの署名の秘密鍵
+}
+
+// ボットの管理（作成・トークンの再発行・WebhookのURLの変更・削除）のハンドラ
+func BotSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	if r.URL.Path == "/settings/bots" {
+		return createBot(w, r, session.User)
+	}
+
+	// 既存のボットの操作（自分が作成したボットのみ）
+	bot, err := repository.GetOwnedBot(session.User.ID, r.FormValue("bot_id"))
+	if err != nil {
+		return domain.NewNotFoundError("ボットが見つかりません", err)
+	}
+
+	switch r.URL.Path {
+	case "/settings/bots/token":
+		token, err := repository.RegenerateBotToken(bot)
+		if err != nil {
+			return domain.NewInternalError("ボットのトークンの再発行に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "ボットのトークンを再発行", "user_id", session.User.ID, "bot_id", bot.ID)
+		return renderBotCredentials(w, r, session.User, BotCredentials{BotID: bot.ID, Token: token})
+
+	case "/settings/bots/webhook":
+		webhookURL := strings.TrimSpace(r.FormValue("webhook_url"))
+		if webhookURL != "" {
+			if err := webhook.ValidateURL(webhookURL); err != nil {
+				return domain.NewValidationError("WebhookのURLが不正です: "+err.Error(), err)
+			}
+		}
+		secret, err := repository.UpdateBotWebhook(bot.ID, webhookURL)
+		if err != nil {
+			return domain.NewInternalError("WebhookのURLの変更に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "ボットのWebhookを変更", "user_id", session.User.ID, "bot_id", bot.ID)
+		if webhookURL == "" {
+			http.Redirect(w, r, "/settings", http.StatusSeeOther)
+			return nil
+		}
+		return renderBotCredentials(w, r, session.User, BotCredentials{BotID: bot.ID, WebhookSecret: secret})
+
+	case "/settings/bots/delete":
+		if err := repository.DeleteBot(bot); err != nil {
+			return domain.NewInternalError("ボットの削除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "ボットを削除", "user_id", session.User.ID, "bot_id", bot.ID)
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return nil
+	}
+	return domain.NewNotFoundError("ページが見つかりません", nil)
+}
+
+// ボットを作成し、トークンとWebhookの署名の秘密鍵を表示する
+func createBot(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	form := BotForm{
+		Name:       strings.TrimSpace(r.FormValue("bot_name")),
+		WebhookURL: strings.TrimSpace(r.FormValue("bot_webhook_url")),
+	}
+
+	bots, err := repository.GetBotsByOwner(user.ID)
+	if err != nil {
+		return domain.NewInternalError("ボットの取得に失敗しました", err)
+	}
+	validationErrors := validateBotForm(form)
+	if len(bots) >= maxBotsPerUser {
+		validationErrors = append(validationErrors, "ボットは"+strconv.Itoa(maxBotsPerUser)+"個まで作成できます")
+	}
+	if len(validationErrors) > 0 {
+		data, err := getSettingsPageData(user, r)
+		if err != nil {
+			return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+		}
+		data.ShowBotForm = true
+		data.BotForm = form
+		data.BotValidationErrors = validationErrors
+		return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+	}
+
+	bot, err := repository.CreateBot(user.ID, form.Name, form.WebhookURL)
+	if err != nil {
+		return domain.NewInternalError("ボットの作成に失敗しました", err)
+	}
+	token, err := repository.RegenerateBotToken(bot)
+	if err != nil {
+		return domain.NewInternalError("ボットのトークンの発行に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "ボットを作成", "user_id", user.ID, "bot_id", bot.ID)
+
+	credentials := BotCredentials{BotID: bot.ID, Token: token}
+	if bot.WebhookURL != "" {
+		credentials.WebhookSecret = bot.WebhookSecret
+	}
+	return renderBotCredentials(w, r, user, credentials)
+}
+
+// トークンと秘密鍵は再表示できないため、リダイレクトせずにこのレスポンスでのみ表示する
+func renderBotCredentials(w http.ResponseWriter, r *http.Request, user *domain.User, credentials BotCredentials) error {
+	data, err := getSettingsPageData(user, r)
+	if err != nil {
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+	}
+	data.NewBotCredentials = &credentials
+	w.Header().Set("Cache-Control", "no-store")
+	return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+}
+
+// ボット作成フォームのバリデーション
+func validateBotForm(form BotForm) []string {
+	var validationErrors []string
+	if form.Name == "" {
+		validationErrors = append(validationErrors, "ボットの名前を入力してください")
+	} else if len([]rune(form.Name)) < 2 || len([]rune(form.Name)) > 20 {
+		validationErrors = append(validationErrors, "ボットの名前は2〜20文字で入力してください")
+	}
+	if form.WebhookURL != "" {
+		if err := webhook.ValidateURL(form.WebhookURL); err != nil {
+			validationErrors = append(validationErrors, "WebhookのURLが不正です: "+err.Error())
+		}
+	}
+	return validationErrors
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+	"security_chat_app/internal/utils/uuid"
+)
+
+// アカウントの削除ページのデータ構造体
+type AccountDeletionPageData struct {
+	IsLoggedIn       bool         // ログイン状態
+	User             *domain.User // ユーザー情報
+	GracePeriod      string       // 申請から削除までの猶予期間（表示用）
+	ScheduledAt      time.Time    // 今申請した場合に削除される日時
+	DeleteMessages   bool         // 送信したメッセージも削除するかどうか（入力値）
+	ValidationErrors []string     // バリデーションエラー
+}
+
+// アカウントの削除（予約・取り消し）のハンドラ
+// 申請から猶予期間が過ぎると、repository.RunAccountMaintenance がアカウントと紐づくデータを削除する
+func AccountDeletionHandler(w http.ResponseWriter, r *http.Request) error {
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	user := session.User
+
+	switch r.URL.Path {
+	case "/settings/delete":
+		switch r.Method {
+		case http.MethodGet:
+			if user.IsDeletionScheduled() {
+				http.Redirect(w, r, "/settings", http.StatusSeeOther)
+				return nil
+			}
+			return renderAccountDeletion(w, user, false, nil)
+		case http.MethodPost:
+			return scheduleAccountDeletion(w, r, user)
+		}
+		return domain.NewMethodNotAllowedError()
+
+	case "/settings/delete/cancel":
+		if r.Method != http.MethodPost {
+			return domain.NewMethodNotAllowedError()
+		}
+		if !user.IsDeletionScheduled() {
+			http.Redirect(w, r, "/settings", http.StatusSeeOther)
+			return nil
+		}
+		if err := repository.CancelAccountDeletion(user.ID); err != nil {
+			return domain.NewInternalError("アカウントの削除の取り消しに失敗しました", err)
+		}
+		recordAudit(r, domain.AuditDeletionCanceled, user.ID, user.ID, nil)
+		slog.InfoContext(r.Context(), "アカウントの削除を取り消し", "user_id", user.ID)
+		http.Redirect(w, r, "/settings?success=アカウントの削除を取り消しました", http.StatusSeeOther)
+		return nil
+	}
+	return domain.NewNotFoundError("ページが見つかりません", nil)
+}
+
+// パスワードを確認し、猶予期間の後にアカウントを削除するよう予約する
+func scheduleAccountDeletion(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	if user.IsDeletionScheduled() {
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return nil
+	}
+	r.ParseForm()
+	deleteMessages := r.FormValue("messages") == "delete"
+
+	if !uuid.VerifyPassword(user.Password, r.FormValue("password")) {
+		slog.InfoContext(r.Context(), "アカウントの削除のパスワードが不一致", "user_id", user.ID)
+		return renderAccountDeletion(w, user, deleteMessages, []string{"パスワードが正しくありません"})
+	}
+
+	scheduledAt := time.Now().Add(config.Config.AccountDeletionGracePeriod)
+	if err := repository.ScheduleAccountDeletion(user.ID, scheduledAt, deleteMessages); err != nil {
+		return domain.NewInternalError("アカウントの削除の予約に失敗しました", err)
+	}
+
+	messages := "anonymized"
+	if deleteMessages {
+		messages = "deleted"
+	}
+	recordAudit(r, domain.AuditDeletionScheduled, user.ID, user.ID, map[string]string{
+		"scheduled_at": scheduledAt.Format(time.RFC3339),
+		"messages":     messages,
+	})
+	slog.InfoContext(r.Context(), "アカウントの削除を予約", "user_id", user.ID, "scheduled_at", scheduledAt, "delete_messages", deleteMessages)
+
+	http.Redirect(w, r, "/settings?success=アカウントの削除を予約しました", http.StatusSeeOther)
+	return nil
+}
+
+// アカウントの削除ページを表示する
+func renderAccountDeletion(w http.ResponseWriter, user *domain.User, deleteMessages bool, validationErrors []string) error {
+	gracePeriod := config.Config.AccountDeletionGracePeriod
+	data := AccountDeletionPageData{
+		IsLoggedIn:       true,
+		User:             user,
+		GracePeriod:      formatPeriod(gracePeriod),
+		ScheduledAt:      time.Now().Add(gracePeriod),
+		DeleteMessages:   deleteMessages,
+		ValidationErrors: validationErrors,
+	}
+	return markup.GenerateHTML(w, data, "layout", "header", "account_delete", "footer")
+}
+
+// 期間を表示用の文字列にする（日単位で割り切れる場合は日数）
+func formatPeriod(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%d日", d/day)
+	}
+	return d.String()
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"security_chat_app/internal/config"
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// データのエクスポートの作成を待つ最大時間（過ぎても作成中のものは失敗として扱う）
+const dataExportTimeout = 30 * time.Minute
+
+// 設定ページに表示するデータのエクスポート
+type DataExportView struct {
+	domain.DataExport
+	StatusLabel string // 状態の表示名
+	CanDownload bool   // ダウンロードできるかどうか
+}
+
+// データのエクスポート（申請・ダウンロード）のハンドラ
+// 申請すると非同期でZIPファイルを作成し、設定ページから期限付きでダウンロードできるようにする
+func DataExportHandler(w http.ResponseWriter, r *http.Request) error {
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	user := session.User
+
+	switch r.URL.Path {
+	case "/settings/export":
+		if r.Method != http.MethodPost {
+			return domain.NewMethodNotAllowedError()
+		}
+		return requestDataExport(w, r, user)
+
+	case "/settings/export/download":
+		if r.Method != http.MethodGet {
+			return domain.NewMethodNotAllowedError()
+		}
+		return downloadDataExport(w, r, user)
+	}
+	return domain.NewNotFoundError("ページが見つかりません", nil)
+}
+
+// データのエクスポートを申請し、ZIPファイルの作成を始める（作成中のものがある場合は申請できない）
+func requestDataExport(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	exports, err := repository.GetUserDataExports(r.Context(), user.ID)
+	if err != nil {
+		return domain.NewInternalError("エクスポートの取得に失敗しました", err)
+	}
+	for _, export := range exports {
+		if isDataExportInProgress(export) {
+			return domain.NewConflictError("データのエクスポートを作成中です。完了するまでお待ちください", nil)
+		}
+	}
+
+	export, err := repository.CreateDataExport(r.Context(), user.ID, config.Config.DataExportExpiry)
+	if err != nil {
+		return domain.NewInternalError("データのエクスポートの申請に失敗しました", err)
+	}
+	recordAudit(r, domain.AuditExportRequested, user.ID, user.ID, map[string]string{"export_id": export.ID})
+	slog.InfoContext(r.Context(), "データのエクスポートを申請", "user_id", user.ID, "export_id", export.ID)
+
+	generateDataExport(r.Context(), user, export)
+
+	http.Redirect(w, r, "/settings?success=データのエクスポートを申請しました", http.StatusSeeOther)
+	return nil
+}
+
+// エクスポートしたデータ（ZIPファイル）をダウンロードする
+// 本人のセッションでのみ、ダウンロードの期限まで取得できる
+func downloadDataExport(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	export, err := repository.GetDataExport(r.Context(), r.URL.Query().Get("id"))
+	if err != nil {
+		return domain.NewInternalError("エクスポートの取得に失敗しました", err)
+	}
+	if export == nil || export.UserID != user.ID {
+		return domain.NewNotFoundError("エクスポートが見つかりません", nil)
+	}
+	if !export.IsDownloadable(time.Now()) {
+		return domain.NewNotFoundError("ダウンロードの期限が切れたか、まだ作成中です", nil)
+	}
+
+	reader, err := firebase.OpenExport(r.Context(), export.ObjectPath)
+	if err != nil {
+		return domain.NewInternalError("エクスポートの読み込みに失敗しました", err)
+	}
+	defer reader.Close()
+
+	recordAudit(r, domain.AuditExportDownloaded, user.ID, user.ID, map[string]string{"export_id": export.ID})
+
+	filename := fmt.Sprintf("chat-export-%s.zip", export.CompletedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := io.Copy(w, reader); err != nil {
+		// ヘッダーを送信済みのため、エラーページは返さずに記録のみ行う
+		slog.ErrorContext(r.Context(), "エクスポートの送信に失敗", "error", err, "export_id", export.ID)
+	}
+	return nil
+}
+
+// ZIPファイルを作成して Storage に保存する
+// 申請のレスポンスを遅らせないよう、非同期で行う
+func generateDataExport(ctx context.Context, user *domain.User, export *domain.DataExport) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		timeoutCtx, cancel := context.WithTimeout(ctx, dataExportTimeout)
+		defer cancel()
+
+		data, err := buildDataExport(timeoutCtx, user)
+		if err == nil {
+			objectPath := repository.DataExportPrefix(user.ID) + export.ID + ".zip"
+			if err = firebase.UploadExport(timeoutCtx, objectPath, data); err == nil {
+				err = repository.CompleteDataExport(timeoutCtx, export.ID, objectPath, int64(len(data)), config.Config.DataExportExpiry)
+			}
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "データのエクスポートの作成に失敗", "error", err, "user_id", user.ID, "export_id", export.ID)
+			if err := repository.FailDataExport(ctx, export.ID, "データの作成に失敗しました"); err != nil {
+				slog.ErrorContext(ctx, "エクスポートの状態の更新に失敗", "error", err, "export_id", export.ID)
+			}
+			return
+		}
+		slog.InfoContext(ctx, "データのエクスポートを作成", "user_id", user.ID, "export_id", export.ID, "size", len(data))
+	}()
+}
+
+// 作成中のエクスポートかどうか（作成を待つ最大時間を過ぎたものは、サーバーの停止などで中断したとみなす）
+func isDataExportInProgress(export domain.DataExport) bool {
+	return export.Status == domain.ExportPending && time.Since(export.CreatedAt) < dataExportTimeout
+}
+
+// 設定ページに表示するデータのエクスポートを取得する
+func getDataExportViews(ctx context.Context, userID string) ([]DataExportView, error) {
+	exports, err := repository.GetUserDataExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	views := make([]DataExportView, 0, len(exports))
+	for _, export := range exports {
+		view := DataExportView{DataExport: export, CanDownload: export.IsDownloadable(now)}
+		switch {
+		case isDataExportInProgress(export):
+			view.StatusLabel = "作成中"
+		case view.CanDownload:
+			view.StatusLabel = "ダウンロードできます（期限: " + export.ExpiresAt.Format("2006-01-02 15:04") + "）"
+		case export.Status == domain.ExportReady:
+			view.StatusLabel = "ダウンロードの期限が切れました"
+		default:
+			view.StatusLabel = "作成に失敗しました"
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
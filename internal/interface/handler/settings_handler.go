@@ -2,11 +2,12 @@ package handler
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"security_chat_app/internal/config"
 	"security_chat_app/internal/domain"
-	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/markup"
 	"security_chat_app/internal/interface/middleware"
 	"security_chat_app/internal/utils/uuid"
@@ -24,15 +25,51 @@ type SettingsPageData struct {
 	}
 	ValidationErrors         []string // バリデーションエラー
 	UsernameValidationErrors []string // ユーザー名のバリデーションエラー
+
+	APITokens                []APITokenView         // 発行済みのAPIトークン
+	APITokenScopes           []domain.APITokenScope // 発行できるスコープ
+	APITokenExpiryOptions    []int                  // 有効期限の選択肢（日数、0 は無期限）
+	ShowAPITokenForm         bool                   // APIトークン発行フォームの表示状態
+	APITokenForm             APITokenForm           // APIトークン発行フォーム
+	APITokenValidationErrors []string               // APIトークン発行フォームのバリデーションエラー
+	NewAPIToken              string                 // 発行したトークン（発行直後のみ表示）
+
+	Bots                []domain.User   // 作成したボット
+	ShowBotForm         bool            // ボット作成フォームの表示状態
+	BotForm             BotForm         // ボット作成フォーム
+	BotValidationErrors []string        // ボット作成フォームのバリデーションエラー
+	NewBotCredentials   *BotCredentials // 作成・再発行したボットの認証情報（直後のみ表示）
+
+	Webhooks                []WebhookView         // 登録済みのWebhook
+	WebhookEvents           []domain.WebhookEvent // 登録できるイベント
+	WebhookChats            []WebhookChatOption   // 対象として選べるチャット
+	ShowWebhookForm         bool                  // Webhook登録フォームの表示状態
+	WebhookForm             WebhookForm           // Webhook登録フォーム
+	WebhookValidationErrors []string              // Webhook登録フォームのバリデーションエラー
+	NewWebhookSecret        string                // 登録したWebhookの署名の秘密鍵（登録直後のみ表示）
+	WebhookFailureLimit     int                   // Webhookを停止する連続失敗回数
+
+	IncomingWebhooks                []IncomingWebhookView       // 作成した受信用Webhook
+	ShowIncomingWebhookForm         bool                        // 受信用Webhook作成フォームの表示状態
+	IncomingWebhookForm             IncomingWebhookForm         // 受信用Webhook作成フォーム
+	IncomingWebhookValidationErrors []string                    // 受信用Webhook作成フォームのバリデーションエラー
+	NewIncomingWebhook              *IncomingWebhookCredentials // 作成・再発行した受信用WebhookのURL（直後のみ表示）
+
+	BlockedUsers []domain.Contact // ブロックしたユーザー
+	MutedChats   []MutedChatView  // ミュート中のチャット
+
+	DataExports      []DataExportView // データのエクスポート（新しい順）
+	DataExportExpiry string           // エクスポートしたデータをダウンロードできる期間（表示用）
+
+	IsAdmin bool // 管理者かどうか（管理画面へのリンクを表示する）
 }
 
 // 設定ページのハンドラ
-func SettingsHandler(w http.ResponseWriter, r *http.Request) {
+func SettingsHandler(w http.ResponseWriter, r *http.Request) error {
 	// セッションの検証
 	session, err := middleware.ValidateSession(w, r)
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+		return domain.NewUnauthorizedError("ログインしてください", err)
 	}
 
 	// ユーザー名変更の処理
@@ -43,12 +80,8 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 
 		// バリデーション
 		var validationErrors []string
-		if newUsername == "" {
-			validationErrors = append(validationErrors, "新しいユーザー名を入力してください")
-		} else if len(newUsername) < 2 {
-			validationErrors = append(validationErrors, "ユーザー名は2文字以上で入力してください")
-		} else if len(newUsername) > 20 {
-			validationErrors = append(validationErrors, "ユーザー名は20文字以下で入力してください")
+		if message := validateUsername(newUsername); message != "" {
+			validationErrors = append(validationErrors, message)
 		}
 
 		if len(validationErrors) > 0 {
@@ -63,12 +96,11 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 				},
 				UsernameValidationErrors: validationErrors,
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
 		}
 
 		// ユーザー名の更新
-		err = firebase.UpdateField("users", session.User.ID, "Name", newUsername)
+		err = repository.UpdateUserField(session.User.ID, "Name", newUsername)
 		if err != nil {
 			validationErrors = append(validationErrors, "ユーザー名の更新に失敗しました")
 			data := SettingsPageData{
@@ -82,21 +114,14 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 				},
 				UsernameValidationErrors: validationErrors,
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
 		}
 
-		// セッションのユーザー情報を更新
-		session.User.Name = newUsername
-		err = middleware.UpdateSession(w, r, session)
-		if err != nil {
-			log.Fatalf("セッションの更新に失敗: %v", err)
-			return
-		}
+		recordAudit(r, domain.AuditUsernameChanged, session.User.ID, session.User.ID, map[string]string{"old_name": session.User.Name, "new_name": newUsername})
 
 		// 成功時は設定ページにリダイレクト
 		http.Redirect(w, r, "/settings?success=ユーザー名を更新しました", http.StatusSeeOther)
-		return
+		return nil
 	}
 
 	if r.Method == http.MethodPost {
@@ -129,7 +154,7 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(validationErrors) > 0 {
-			log.Printf("バリデーションエラー: %v", validationErrors)
+			slog.InfoContext(r.Context(), "バリデーションエラー", "errors", validationErrors)
 			data := SettingsPageData{
 				IsLoggedIn:       true,
 				User:             session.User,
@@ -137,14 +162,13 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 				PasswordForm:     form,
 				ValidationErrors: validationErrors,
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
 		}
 
 		// 新しいパスワードのハッシュ化
 		hashedPassword, err := uuid.HashPassword(form.NewPassword)
 		if err != nil {
-			log.Printf("パスワードハッシュ化エラー: %v", err)
+			slog.ErrorContext(r.Context(), "パスワードハッシュ化エラー", "error", err)
 			data := SettingsPageData{
 				IsLoggedIn:       true,
 				User:             session.User,
@@ -152,14 +176,13 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 				PasswordForm:     form,
 				ValidationErrors: []string{"パスワード更新エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
 		}
 
-		// パスワードの更新
-		err = firebase.UpdateField("users", session.User.ID, "Password", hashedPassword)
+		// パスワードの更新（既存のセッションはすべて無効になる）
+		err = repository.UpdateUserCredential(session.User.ID, "Password", hashedPassword)
 		if err != nil {
-			log.Printf("パスワード更新エラー: %v", err)
+			slog.ErrorContext(r.Context(), "パスワード更新エラー", "error", err)
 			data := SettingsPageData{
 				IsLoggedIn:       true,
 				User:             session.User,
@@ -167,24 +190,37 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 				PasswordForm:     form,
 				ValidationErrors: []string{"パスワード更新エラーが発生しました"},
 			}
-			markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
-			return
+			return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+		}
+
+		recordAudit(r, domain.AuditPasswordChanged, session.User.ID, session.User.ID, nil)
+
+		// 操作中の端末のみ新しいセッションでログイン状態を維持する
+		user, err := repository.GetUserByID(session.User.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "ユーザー情報の取得に失敗", "error", err)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return nil
+		}
+		if _, err := middleware.RotateSession(w, r, user); err != nil {
+			slog.ErrorContext(r.Context(), "セッションの再発行に失敗", "error", err)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return nil
 		}
 
 		// 成功時は設定ページにリダイレクト
 		http.Redirect(w, r, "/settings?success=パスワードを更新しました", http.StatusSeeOther)
-		return
+		return nil
 	}
 
 	// 設定ページのデータを取得
 	data, err := getSettingsPageData(session.User, r)
 	if err != nil {
-		log.Fatalf("設定ページのデータの取得に失敗: %v", err)
-		return
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
 	}
 
 	// テンプレートのレンダリング
-	markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+	return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
 }
 
 // 設定ページのデータを取得
@@ -197,10 +233,78 @@ func getSettingsPageData(user *domain.User, r *http.Request) (SettingsPageData,
 	showPasswordForm := r.URL.Query().Get("show_password_form") == "true"
 	showUsernameForm := r.URL.Query().Get("show_username_form") == "true"
 
+	// 発行済みのAPIトークン
+	apiTokens, err := getAPITokenViews(user.ID)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
+	// 作成したボット
+	bots, err := repository.GetBotsByOwner(user.ID)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
+	// 登録したWebhook
+	webhookChats, err := getWebhookChatOptions(r.Context(), user)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+	webhooks, err := getWebhookViews(user.ID, webhookChats)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
+	// 作成した受信用Webhook
+	incomingWebhooks, err := getIncomingWebhookViews(user.ID, webhookChats)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
+	// ブロックしたユーザー
+	blockedUsers, err := getBlockedUsers(r.Context(), user.ID)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
+	// データのエクスポート
+	dataExports, err := getDataExportViews(r.Context(), user.ID)
+	if err != nil {
+		return SettingsPageData{}, err
+	}
+
 	return SettingsPageData{
-		IsLoggedIn:       true,
-		User:             user,
-		ShowPasswordForm: showPasswordForm,
-		ShowUsernameForm: showUsernameForm,
+		IsLoggedIn:            true,
+		User:                  user,
+		ShowPasswordForm:      showPasswordForm,
+		ShowUsernameForm:      showUsernameForm,
+		APITokens:             apiTokens,
+		APITokenScopes:        domain.APITokenScopes,
+		APITokenExpiryOptions: apiTokenExpiryOptions,
+		APITokenForm:          APITokenForm{ExpiresIn: 30},
+		Bots:                  bots,
+		Webhooks:              webhooks,
+		WebhookEvents:         domain.WebhookEvents,
+		WebhookChats:          webhookChats,
+		WebhookForm:           WebhookForm{Events: []string{domain.EventMessageCreated}},
+		WebhookFailureLimit:   domain.WebhookFailureLimit,
+		IncomingWebhooks:      incomingWebhooks,
+		BlockedUsers:          blockedUsers,
+		MutedChats:            getMutedChatViews(user, webhookChats),
+		DataExports:           dataExports,
+		DataExportExpiry:      formatPeriod(config.Config.DataExportExpiry),
+		IsAdmin:               middleware.IsAdmin(user),
 	}, nil
 }
+
+// ユーザー名のバリデーション（問題がない場合は空文字列を返す）
+func validateUsername(name string) string {
+	if name == "" {
+		return "新しいユーザー名を入力してください"
+	} else if len(name) < 2 {
+		return "ユーザー名は2文字以上で入力してください"
+	} else if len(name) > 20 {
+		return "ユーザー名は20文字以下で入力してください"
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 1ユーザーが作成できる受信用Webhookの上限
+const maxIncomingWebhooksPerUser = 10
+
+// 設定ページに表示する受信用Webhook
+type IncomingWebhookView struct {
+	domain.IncomingWebhook
+	ChatName string // 投稿先のチャットの相手の名前
+}
+
+// 受信用Webhook作成フォーム
+type IncomingWebhookForm struct {
+	ChatID string // 投稿先のチャットのID
+	Name   string // 投稿したメッセージに表示する送信者の名前
+}
+
+// 作成・再発行した受信用Webhook（直後のみURLを表示する）
+type IncomingWebhookCredentials struct {
+	ID  string // 受信用WebhookのID
+	URL string // 投稿先のURL（トークンを含む）
+}
+
+// 受信用Webhookの管理（作成・URLの再発行・削除）のハンドラ
+func IncomingWebhookSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	if r.URL.Path == "/settings/incoming-webhooks" {
+		return createIncomingWebhook(w, r, session.User)
+	}
+
+	// 作成済みの受信用Webhookの操作（自分が作成した受信用Webhookのみ）
+	hook, err := repository.GetOwnedIncomingWebhook(session.User.ID, r.FormValue("incoming_webhook_id"))
+	if err != nil {
+		return domain.NewNotFoundError("受信用Webhookが見つかりません", err)
+	}
+
+	switch r.URL.Path {
+	case "/settings/incoming-webhooks/regenerate":
+		token, err := repository.RegenerateIncomingWebhookToken(hook.ID)
+		if err != nil {
+			return domain.NewInternalError("受信用WebhookのURLの再発行に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "受信用WebhookのURLを再発行", "user_id", session.User.ID, "incoming_webhook_id", hook.ID)
+		return renderIncomingWebhookURL(w, r, session.User, hook.ID, token)
+
+	case "/settings/incoming-webhooks/delete":
+		if err := repository.DeleteIncomingWebhook(hook.ID); err != nil {
+			return domain.NewInternalError("受信用Webhookの削除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "受信用Webhookを削除", "user_id", session.User.ID, "incoming_webhook_id", hook.ID)
+
+	default:
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	return nil
+}
+
+// 受信用Webhookを作成し、URLを表示する
+func createIncomingWebhook(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	form := IncomingWebhookForm{
+		ChatID: r.FormValue("incoming_webhook_chat_id"),
+		Name:   strings.TrimSpace(r.FormValue("incoming_webhook_name")),
+	}
+
+	data, err := getSettingsPageData(user, r)
+	if err != nil {
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+	}
+	validationErrors := validateIncomingWebhookForm(form, data.WebhookChats)
+	if len(data.IncomingWebhooks) >= maxIncomingWebhooksPerUser {
+		validationErrors = append(validationErrors, "受信用Webhookは"+strconv.Itoa(maxIncomingWebhooksPerUser)+"個まで作成できます")
+	}
+	if len(validationErrors) > 0 {
+		data.ShowIncomingWebhookForm = true
+		data.IncomingWebhookForm = form
+		data.IncomingWebhookValidationErrors = validationErrors
+		return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+	}
+
+	hook, token, err := repository.CreateIncomingWebhook(form.ChatID, user.ID, form.Name)
+	if err != nil {
+		return domain.NewInternalError("受信用Webhookの作成に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "受信用Webhookを作成", "user_id", user.ID, "incoming_webhook_id", hook.ID, "chat_id", hook.ChatID)
+	return renderIncomingWebhookURL(w, r, user, hook.ID, token)
+}
+
+// 受信用WebhookのURLを表示する
+// トークンは再表示できないため、リダイレクトせずにこのレスポンスでのみ表示する
+func renderIncomingWebhookURL(w http.ResponseWriter, r *http.Request, user *domain.User, hookID, token string) error {
+	data, err := getSettingsPageData(user, r)
+	if err != nil {
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+	}
+	data.NewIncomingWebhook = &IncomingWebhookCredentials{ID: hookID, URL: incomingWebhookURL(r, token)}
+	w.Header().Set("Cache-Control", "no-store")
+	return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+}
+
+// 受信用Webhook作成フォームのバリデーション
+func validateIncomingWebhookForm(form IncomingWebhookForm, chats []WebhookChatOption) []string {
+	var validationErrors []string
+	if form.Name == "" {
+		validationErrors = append(validationErrors, "表示名を入力してください")
+	} else if len([]rune(form.Name)) < 2 || len([]rune(form.Name)) > 20 {
+		validationErrors = append(validationErrors, "表示名は2〜20文字で入力してください")
+	}
+	chat, ok := findWebhookChat(chats, form.ChatID)
+	if !ok {
+		validationErrors = append(validationErrors, "投稿先のチャットを選択してください")
+	} else if chat.IsEncrypted {
+		validationErrors = append(validationErrors, "暗号化されたチャットには受信用Webhookを作成できません")
+	}
+	return validationErrors
+}
+
+// ユーザーの受信用Webhookを設定ページの表示用に取得する
+func getIncomingWebhookViews(userID string, chats []WebhookChatOption) ([]IncomingWebhookView, error) {
+	hooks, err := repository.GetIncomingWebhooksByCreator(userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]IncomingWebhookView, 0, len(hooks))
+	for _, hook := range hooks {
+		view := IncomingWebhookView{IncomingWebhook: hook}
+		if chat, ok := findWebhookChat(chats, hook.ChatID); ok {
+			view.ChatName = chat.Name
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
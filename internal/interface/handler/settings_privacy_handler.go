@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// プライバシー設定（チャットを開始できる相手の制限）のハンドラ
+func PrivacySettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	contactsOnly := r.FormValue("contacts_only") == "on"
+	if err := repository.UpdateUserField(session.User.ID, "ContactsOnly", contactsOnly); err != nil {
+		return domain.NewInternalError("プライバシー設定の更新に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "プライバシー設定を更新", "user_id", session.User.ID, "contacts_only", contactsOnly)
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	return nil
+}
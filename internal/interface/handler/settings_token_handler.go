@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 1ユーザーが発行できるAPIトークンの上限
+const maxAPITokensPerUser = 20
+
+// APIトークンの有効期限の選択肢（日数、0 は無期限）
+var apiTokenExpiryOptions = []int{7, 30, 90, 365, 0}
+
+// 設定ページに表示するAPIトークン
+type APITokenView struct {
+	domain.APIToken
+	ScopeLabels []string // スコープの表示名
+	Expired     bool     // 有効期限が切れているかどうか
+}
+
+// APIトークン発行フォーム
+type APITokenForm struct {
+	Name      string   // トークンの名前
+	Scopes    []string // 許可する操作の範囲
+	ExpiresIn int      // 有効期限（日数、0 は無期限）
+}
+
+// APIトークンの管理（発行・失効）のハンドラ
+func APITokenSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	// トークンの失効
+	if r.URL.Path == "/settings/tokens/revoke" {
+		tokenID := r.FormValue("token_id")
+		if tokenID == "" {
+			return domain.NewValidationError("トークンIDが指定されていません", nil)
+		}
+		if err := repository.DeleteAPIToken(session.User.ID, tokenID); err != nil {
+			return domain.NewNotFoundError("APIトークンが見つかりません", err)
+		}
+		slog.InfoContext(r.Context(), "APIトークンを失効", "user_id", session.User.ID, "token_id", tokenID)
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return nil
+	}
+
+	// トークンの発行
+	form := APITokenForm{
+		Name:   strings.TrimSpace(r.FormValue("token_name")),
+		Scopes: r.Form["token_scopes"],
+	}
+	form.ExpiresIn, _ = strconv.Atoi(r.FormValue("token_expires_in"))
+
+	tokens, err := repository.GetAPITokensByUser(session.User.ID)
+	if err != nil {
+		return domain.NewInternalError("APIトークンの取得に失敗しました", err)
+	}
+	validationErrors := validateAPITokenForm(form)
+	if len(tokens) >= maxAPITokensPerUser {
+		validationErrors = append(validationErrors, "APIトークンは"+strconv.Itoa(maxAPITokensPerUser)+"個まで発行できます")
+	}
+
+	data, err := getSettingsPageData(session.User, r)
+	if err != nil {
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+	}
+	data.APITokenForm = form
+	if len(validationErrors) > 0 {
+		data.ShowAPITokenForm = true
+		data.APITokenValidationErrors = validationErrors
+		return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+	}
+
+	var expiresAt time.Time
+	if form.ExpiresIn > 0 {
+		expiresAt = time.Now().AddDate(0, 0, form.ExpiresIn)
+	}
+	apiToken, token, err := repository.CreateAPIToken(session.User.ID, form.Name, form.Scopes, expiresAt)
+	if err != nil {
+		return domain.NewInternalError("APIトークンの発行に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "APIトークンを発行", "user_id", session.User.ID, "token_id", apiToken.ID, "scopes", form.Scopes)
+
+	// トークンは再表示できないため、リダイレクトせずにこのレスポンスでのみ表示する
+	data.APITokens = append([]APITokenView{newAPITokenView(*apiToken)}, data.APITokens...)
+	data.NewAPIToken = token
+	data.APITokenForm = APITokenForm{}
+	w.Header().Set("Cache-Control", "no-store")
+	return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+}
+
+// APIトークン発行フォームのバリデーション
+func validateAPITokenForm(form APITokenForm) []string {
+	var validationErrors []string
+	if form.Name == "" {
+		validationErrors = append(validationErrors, "トークンの名前を入力してください")
+	} else if len([]rune(form.Name)) > 50 {
+		validationErrors = append(validationErrors, "トークンの名前は50文字以下で入力してください")
+	}
+	if len(form.Scopes) == 0 {
+		validationErrors = append(validationErrors, "許可する操作を1つ以上選択してください")
+	}
+	for _, scope := range form.Scopes {
+		if !domain.IsValidAPITokenScope(scope) {
+			validationErrors = append(validationErrors, "許可する操作の指定が不正です")
+			break
+		}
+	}
+	if !slices.Contains(apiTokenExpiryOptions, form.ExpiresIn) {
+		validationErrors = append(validationErrors, "有効期限の指定が不正です")
+	}
+	return validationErrors
+}
+
+// ユーザーのAPIトークンを設定ページの表示用に取得する
+func getAPITokenViews(userID string) ([]APITokenView, error) {
+	tokens, err := repository.GetAPITokensByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]APITokenView, 0, len(tokens))
+	for _, token := range tokens {
+		views = append(views, newAPITokenView(token))
+	}
+	return views, nil
+}
+
+// APIトークンを表示用に変換する
+func newAPITokenView(token domain.APIToken) APITokenView {
+	view := APITokenView{APIToken: token, Expired: token.IsExpired()}
+	for _, scope := range domain.APITokenScopes {
+		if token.HasScope(scope.Name) {
+			view.ScopeLabels = append(view.ScopeLabels, scope.Label)
+		}
+	}
+	return view
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/infrastructure/webhook"
+	"security_chat_app/internal/interface/markup"
+	"security_chat_app/internal/interface/middleware"
+)
+
+// 1ユーザーが登録できるWebhookの上限
+const maxWebhooksPerUser = 10
+
+// 設定ページに表示する配信履歴の件数
+const webhookDeliveriesShown = 5
+
+// 設定ページに表示するWebhook
+type WebhookView struct {
+	domain.Webhook
+	ChatName    string                   // 対象のチャットの相手の名前（すべてのチャットの場合は空）
+	EventLabels []string                 // イベントの表示名
+	Deliveries  []domain.WebhookDelivery // 最近の配信履歴
+}
+
+// Webhookの対象として選べるチャット
+type WebhookChatOption struct {
+	ID          string // チャットのID
+	Name        string // チャットの相手の名前
+	IsEncrypted bool   // エンドツーエンド暗号化が有効かどうか（受信用Webhookは作成できない）
+}
+
+// Webhook登録フォーム
+type WebhookForm struct {
+	URL    string   // 送信先のURL
+	ChatID string   // 対象のチャットのID（空の場合はすべてのチャット）
+	Events []string // 送信するイベントの種類
+}
+
+// 送信用Webhookの管理（登録・再開・削除）のハンドラ
+func WebhookSettingsHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return domain.NewMethodNotAllowedError()
+	}
+
+	// セッションの検証
+	session, err := middleware.ValidateSession(w, r)
+	if err != nil {
+		return domain.NewUnauthorizedError("ログインしてください", err)
+	}
+	r.ParseForm()
+
+	if r.URL.Path == "/settings/webhooks" {
+		return createWebhook(w, r, session.User)
+	}
+
+	// 登録済みのWebhookの操作（自分が登録したWebhookのみ）
+	hook, err := repository.GetOwnedWebhook(session.User.ID, r.FormValue("webhook_id"))
+	if err != nil {
+		return domain.NewNotFoundError("Webhookが見つかりません", err)
+	}
+
+	switch r.URL.Path {
+	case "/settings/webhooks/enable":
+		if err := repository.EnableWebhook(hook.ID); err != nil {
+			return domain.NewInternalError("Webhookの再開に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "Webhookを再開", "user_id", session.User.ID, "webhook_id", hook.ID)
+
+	case "/settings/webhooks/delete":
+		if err := repository.DeleteWebhook(hook.ID); err != nil {
+			return domain.NewInternalError("Webhookの削除に失敗しました", err)
+		}
+		slog.InfoContext(r.Context(), "Webhookを削除", "user_id", session.User.ID, "webhook_id", hook.ID)
+
+	default:
+		return domain.NewNotFoundError("ページが見つかりません", nil)
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+	return nil
+}
+
+// Webhookを登録し、署名の秘密鍵を表示する
+func createWebhook(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	form := WebhookForm{
+		URL:    strings.TrimSpace(r.FormValue("webhook_url")),
+		ChatID: r.FormValue("webhook_chat_id"),
+		Events: r.Form["webhook_events"],
+	}
+
+	data, err := getSettingsPageData(user, r)
+	if err != nil {
+		return domain.NewInternalError("設定ページのデータの取得に失敗しました", err)
+	}
+	validationErrors := validateWebhookForm(form, data.WebhookChats)
+	if len(data.Webhooks) >= maxWebhooksPerUser {
+		validationErrors = append(validationErrors, "Webhookは"+strconv.Itoa(maxWebhooksPerUser)+"個まで登録できます")
+	}
+	if len(validationErrors) > 0 {
+		data.ShowWebhookForm = true
+		data.WebhookForm = form
+		data.WebhookValidationErrors = validationErrors
+		return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+	}
+
+	hook, err := repository.CreateWebhook(user.ID, form.ChatID, form.URL, form.Events)
+	if err != nil {
+		return domain.NewInternalError("Webhookの登録に失敗しました", err)
+	}
+	slog.InfoContext(r.Context(), "Webhookを登録", "user_id", user.ID, "webhook_id", hook.ID, "events", form.Events)
+
+	// 秘密鍵は再表示できないため、リダイレクトせずにこのレスポンスでのみ表示する
+	data.Webhooks = append([]WebhookView{newWebhookView(*hook, data.WebhookChats, nil)}, data.Webhooks...)
+	data.NewWebhookSecret = hook.Secret
+	w.Header().Set("Cache-Control", "no-store")
+	return markup.GenerateHTML(w, data, "layout", "header", "settings", "footer")
+}
+
+// Webhook登録フォームのバリデーション
+func validateWebhookForm(form WebhookForm, chats []WebhookChatOption) []string {
+	var validationErrors []string
+	if form.URL == "" {
+		validationErrors = append(validationErrors, "送信先のURLを入力してください")
+	} else if err := webhook.ValidateURL(form.URL); err != nil {
+		validationErrors = append(validationErrors, "送信先のURLが不正です: "+err.Error())
+	}
+	if form.ChatID != "" && !containsWebhookChat(chats, form.ChatID) {
+		validationErrors = append(validationErrors, "対象のチャットの指定が不正です")
+	}
+	if len(form.Events) == 0 {
+		validationErrors = append(validationErrors, "送信するイベントを1つ以上選択してください")
+	}
+	for _, event := range form.Events {
+		if !domain.IsValidWebhookEvent(event) {
+			validationErrors = append(validationErrors, "イベントの指定が不正です")
+			break
+		}
+	}
+	return validationErrors
+}
+
+// ユーザーのWebhookを設定ページの表示用に取得する
+func getWebhookViews(userID string, chats []WebhookChatOption) ([]WebhookView, error) {
+	hooks, err := repository.GetWebhooksByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]WebhookView, 0, len(hooks))
+	for _, hook := range hooks {
+		deliveries, err := repository.GetWebhookDeliveries(hook.ID)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, newWebhookView(hook, chats, deliveries))
+	}
+	return views, nil
+}
+
+// Webhookを表示用に変換する
+func newWebhookView(hook domain.Webhook, chats []WebhookChatOption, deliveries []domain.WebhookDelivery) WebhookView {
+	view := WebhookView{Webhook: hook}
+	for _, chat := range chats {
+		if chat.ID == hook.ChatID {
+			view.ChatName = chat.Name
+		}
+	}
+	for _, event := range domain.WebhookEvents {
+		if containsString(hook.Events, event.Name) {
+			view.EventLabels = append(view.EventLabels, event.Label)
+		}
+	}
+	if len(deliveries) > webhookDeliveriesShown {
+		deliveries = deliveries[:webhookDeliveriesShown]
+	}
+	view.Deliveries = deliveries
+	return view
+}
+
+// Webhookの対象として選べるチャット（参加しているチャット）を取得する
+func getWebhookChatOptions(ctx context.Context, user *domain.User) ([]WebhookChatOption, error) {
+	chats, err := getChatHistory(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]WebhookChatOption, 0, len(chats))
+	for _, chat := range chats {
+		options = append(options, WebhookChatOption{ID: chat.ID, Name: chat.Contact.Username, IsEncrypted: chat.IsEncrypted})
+	}
+	return options, nil
+}
+
+// チャットが選択肢に含まれているかどうか
+func containsWebhookChat(chats []WebhookChatOption, chatID string) bool {
+	_, ok := findWebhookChat(chats, chatID)
+	return ok
+}
+
+// 選択肢からチャットを探す
+func findWebhookChat(chats []WebhookChatOption, chatID string) (WebhookChatOption, bool) {
+	for _, chat := range chats {
+		if chat.ID == chatID {
+			return chat, true
+		}
+	}
+	return WebhookChatOption{}, false
+}
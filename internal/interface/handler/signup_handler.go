@@ -1,26 +1,24 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
-	"time"
 
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 	"security_chat_app/internal/interface/markup"
 	"security_chat_app/internal/interface/middleware"
-	"security_chat_app/internal/utils/uuid"
 )
 
 // 新規登録画面の表示と確認画面への遷移を処理
-func SignupHandler(w http.ResponseWriter, r *http.Request) {
+func SignupHandler(w http.ResponseWriter, r *http.Request) error {
 	if r.Method == http.MethodGet {
 		data := domain.TemplateData{
 			IsLoggedIn: false,
 		}
-		markup.GenerateHTML(w, data, "layout", "header", "register", "footer")
-		return
+		return markup.GenerateHTML(w, data, "layout", "header", "register", "footer")
 	}
 
 	// サインアップ処理
@@ -28,77 +26,71 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 		r.ParseForm()
 		form := domain.SignupForm{
 			Name:     r.FormValue("name"),
-			Email:    r.FormValue("email"),
+			Email:    domain.NormalizeEmail(r.FormValue("email")),
 			Password: r.FormValue("password"),
 		}
 
 		// バリデーション
 		validationErrors := validateSignupForm(form)
 		if len(validationErrors) > 0 {
-			log.Printf("バリデーションエラー: %v", validationErrors)
-			renderSignupError(w, form, validationErrors)
-			return
+			slog.InfoContext(r.Context(), "バリデーションエラー", "errors", validationErrors)
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		// メールアドレスの重複チェック
 		existingUsers, err := checkEmailDuplicate(form.Email)
 		if err != nil {
-			log.Printf("ユーザー検索エラー: %v", err)
+			slog.ErrorContext(r.Context(), "ユーザー検索エラー", "error", err)
 			validationErrors := []string{"エラーが発生しました"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		if existingUsers {
-			log.Printf("メールアドレス重複エラー: %s", form.Email)
+			slog.InfoContext(r.Context(), "メールアドレス重複エラー", "email", form.Email)
 			validationErrors := []string{"このメールアドレスは既に登録されています"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		// ユーザーの作成と保存
 		user, err := createAndSaveUser(form)
 		if err != nil {
-			log.Printf("ユーザー作成エラー: %v", err)
+			slog.ErrorContext(r.Context(), "ユーザー作成エラー", "error", err)
 			validationErrors := []string{"ユーザー作成エラーが発生しました"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
-		// セッションの作成
-		session, err := middleware.CreateSession(user)
+		// セッションの作成（既存のセッションIDは破棄してローテーションする）
+		_, err = middleware.RotateSession(w, r, user)
 		if err != nil {
-			log.Printf("セッション作成エラー: %v", err)
+			slog.ErrorContext(r.Context(), "セッション作成エラー", "error", err)
 			validationErrors := []string{"セッション作成エラーが発生しました"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
-		middleware.SetSessionCookie(w, session)
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+		return nil
 	}
 
 	// その他のHTTPメソッドは許可しない
-	log.Fatalf("メソッドが許可されていません")
+	return domain.NewMethodNotAllowedError()
 }
 
 // 登録内容の確認とFirebaseへの保存を処理
-func SignupConfirmHandler(w http.ResponseWriter, r *http.Request) {
+func SignupConfirmHandler(w http.ResponseWriter, r *http.Request) error {
 	switch r.Method {
 	case http.MethodGet, http.MethodPost:
 		var form domain.SignupForm
 		if r.Method == http.MethodGet {
 			form = domain.SignupForm{
 				Name:     r.URL.Query().Get("name"),
-				Email:    r.URL.Query().Get("email"),
+				Email:    domain.NormalizeEmail(r.URL.Query().Get("email")),
 				Password: r.URL.Query().Get("password"),
 			}
 		} else {
 			r.ParseForm()
 			form = domain.SignupForm{
 				Name:     r.FormValue("name"),
-				Email:    r.FormValue("email"),
+				Email:    domain.NormalizeEmail(r.FormValue("email")),
 				Password: r.FormValue("password"),
 			}
 		}
@@ -106,37 +98,33 @@ func SignupConfirmHandler(w http.ResponseWriter, r *http.Request) {
 		// バリデーション
 		validationErrors := validateSignupForm(form)
 		if len(validationErrors) > 0 {
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		// メールアドレスの重複チェック
 		existingUsers, err := checkEmailDuplicate(form.Email)
 		if err != nil {
-			log.Printf("ユーザー検索エラー: %v", err)
+			slog.ErrorContext(r.Context(), "ユーザー検索エラー", "error", err)
 			validationErrors := []string{"エラーが発生しました"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		if existingUsers {
 			validationErrors := []string{"このメールアドレスは既に登録されています"}
-			renderSignupError(w, form, validationErrors)
-			return
+			return renderSignupError(w, form, validationErrors)
 		}
 
 		if r.Method == http.MethodPost {
 			_, err := createAndSaveUser(form)
 			if err != nil {
-				log.Printf("ユーザー作成エラー: %v", err)
+				slog.ErrorContext(r.Context(), "ユーザー作成エラー", "error", err)
 				validationErrors := []string{"ユーザー作成エラーが発生しました"}
-				renderSignupError(w, form, validationErrors)
-				return
+				return renderSignupError(w, form, validationErrors)
 			}
 
 			// 登録成功後、ログインページにリダイレクト
 			http.Redirect(w, r, "/login?success=true", http.StatusSeeOther)
-			return
+			return nil
 		}
 
 		// 確認画面の表示
@@ -144,9 +132,9 @@ func SignupConfirmHandler(w http.ResponseWriter, r *http.Request) {
 			IsLoggedIn: false,
 			SignupForm: form,
 		}
-		markup.GenerateHTML(w, data, "layout", "header", "register_confirm", "footer")
+		return markup.GenerateHTML(w, data, "layout", "header", "register_confirm", "footer")
 	default:
-		log.Fatalf("メソッドが許可されていません")
+		return domain.NewMethodNotAllowedError()
 	}
 }
 
@@ -173,9 +161,9 @@ func validateSignupForm(form domain.SignupForm) []string {
 
 // メールアドレスの重複チェック
 func checkEmailDuplicate(email string) (bool, error) {
-	existingUsers, err := firebase.GetDataByQuery("users", "Email", "==", email)
+	existingUsers, err := firebase.GetDataByQuery("users", "Email", "==", domain.NormalizeEmail(email))
 	if err != nil {
-		log.Printf("ユーザー検索エラー: %v", err)
+		slog.Error("ユーザー検索エラー", "error", err)
 		return false, err
 	}
 	return len(existingUsers) > 0, nil
@@ -183,47 +171,20 @@ func checkEmailDuplicate(email string) (bool, error) {
 
 // ユーザーデータの作成と保存
 func createAndSaveUser(form domain.SignupForm) (*domain.User, error) {
-	// パスワードのハッシュ化
-	hashedPassword, err := uuid.HashPassword(form.Password)
+	user, err := repository.CreateUser(form.Name, form.Email, form.Password)
 	if err != nil {
-		log.Printf("パスワードハッシュ化エラー: %v", err)
+		slog.Error("ユーザー作成エラー", "error", err)
 		return nil, err
 	}
-
-	// UUIDの生成
-	userID, err := uuid.GenerateUUID()
-	if err != nil {
-		log.Printf("UUID生成エラー: %v", err)
-		return nil, err
-	}
-
-	// ユーザーの作成
-	user := &domain.User{
-		ID:        userID,
-		Name:      form.Name,
-		Email:     form.Email,
-		Password:  hashedPassword,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		IsOnline:  false,
-	}
-
-	// Firestoreにユーザーを保存
-	err = firebase.AddData("users", user, user.ID)
-	if err != nil {
-		log.Printf("ユーザー作成エラー: %v", err)
-		return nil, err
-	}
-
 	return user, nil
 }
 
 // エラー時のテンプレート表示
-func renderSignupError(w http.ResponseWriter, form domain.SignupForm, errors []string) {
+func renderSignupError(w http.ResponseWriter, form domain.SignupForm, errors []string) error {
 	data := domain.TemplateData{
 		IsLoggedIn:       false,
 		SignupForm:       form,
 		ValidationErrors: errors,
 	}
-	markup.GenerateHTML(w, data, "layout", "header", "register", "footer")
+	return markup.GenerateHTML(w, data, "layout", "header", "register", "footer")
 }
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+	"security_chat_app/internal/infrastructure/webhook"
+)
+
+// チャットの参加者が登録したWebhookにイベントを送信する
+// 送信のレスポンスを遅らせないよう、Webhookの取得と配信（再送を含む）は非同期で行う
+func dispatchWebhookEvent(ctx context.Context, participants []string, event apiEvent) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		hooks, err := repository.GetWebhooksForEvent(ctx, participants, event.ChatID, event.Type)
+		if err != nil {
+			slog.ErrorContext(ctx, "Webhookの取得に失敗", "error", err, "chat_id", event.ChatID, "event", event.Type)
+			return
+		}
+		if len(hooks) == 0 {
+			return
+		}
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			slog.ErrorContext(ctx, "Webhookの本文の作成に失敗", "error", err)
+			return
+		}
+		for _, hook := range hooks {
+			go deliverWebhook(ctx, hook, event, body)
+		}
+	}()
+}
+
+// Webhookにイベントを配信し、試行ごとに配信履歴を記録する
+// Webhookが停止・削除された場合は再送しない
+func deliverWebhook(ctx context.Context, hook domain.Webhook, event apiEvent, body []byte) {
+	webhook.Deliver(ctx, hook.URL, hook.Secret, event.Type, body, func(attempt webhook.Attempt) bool {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:  hook.ID,
+			EventID:    event.ID,
+			Event:      event.Type,
+			Attempt:    attempt.Number,
+			StatusCode: attempt.StatusCode,
+			Duration:   attempt.Duration,
+			CreatedAt:  attempt.SentAt,
+		}
+		if attempt.Err != nil {
+			delivery.Error = attempt.Err.Error()
+			slog.WarnContext(ctx, "Webhookの配信に失敗", "error", attempt.Err, "webhook_id", hook.ID,
+				"event", event.Type, "status", attempt.StatusCode, "attempt", attempt.Number)
+		}
+
+		disabled, err := repository.RecordWebhookDelivery(ctx, delivery)
+		if err != nil {
+			slog.ErrorContext(ctx, "Webhookの配信履歴の記録に失敗", "error", err, "webhook_id", hook.ID)
+			return true
+		}
+		return !disabled
+	})
+}
+
+// チャットの開始を参加者が登録したWebhookに知らせる（参加者ごとに member.joined を送信する）
+func dispatchChatStarted(ctx context.Context, chatID string, members ...*domain.User) {
+	participants := make([]string, 0, len(members))
+	for _, member := range members {
+		participants = append(participants, member.ID)
+	}
+	for _, member := range members {
+		dispatchWebhookEvent(ctx, participants, newMemberEvent(chatID, member))
+	}
+}
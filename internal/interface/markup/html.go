@@ -2,10 +2,18 @@ package markup
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/utils/icons"
@@ -51,30 +59,254 @@ var templateFuncs = template.FuncMap{
 		// デフォルトアイコンのパスを生成
 		return fmt.Sprintf("%s/%s.png", icons.DefaultIconPath, icons.DefaultIconNames[randomNum])
 	},
+	"contains": func(list []string, value string) bool {
+		return slices.Contains(list, value)
+	},
+	// 出力後にリクエストごとのnonceに差し替える（テンプレートを使い回すため）
+	"cspNonce": func() string {
+		return noncePlaceholder
+	},
+}
+
+// テンプレートの変更を確認する間隔（開発時の自動再読み込み）
+const templateWatchInterval = time.Second
+
+// すべてのページで共通のテンプレート
+var commonTemplates = []string{"layout", "header", "footer"}
+
+// nonceの差し替え位置を示す文字列（利用者の入力と衝突しないよう起動ごとにランダムに生成する）
+var noncePlaceholder = newNoncePlaceholder()
+
+// 読み込み済みのテンプレート（キーは layout,header,ページ,footer）
+var (
+	templateMu    sync.RWMutex
+	templateFS    fs.FS
+	templateCache map[string]*template.Template
+)
+
+// ErrorPageData エラーページのデータ構造体
+type ErrorPageData struct {
+	IsLoggedIn bool   // ログイン状態
+	Status     int    // HTTPステータスコード
+	StatusText string // HTTPステータスの説明
+	Message    string // 利用者に表示するメッセージ
 }
 
 // GenerateHTML layout.htmlをベースとしたHTMLを生成し、レスポンスに書きだす
-func GenerateHTML(writer http.ResponseWriter, data any, filenames ...string) {
-	var files []string
-	for _, file := range filenames {
-		path := fmt.Sprintf("internal/web/templates/%s.html", file)
-		files = append(files, path)
+func GenerateHTML(writer http.ResponseWriter, data any, filenames ...string) error {
+	var buf bytes.Buffer
+	if err := executeTemplate(&buf, writer, data, filenames...); err != nil {
+		return err
 	}
 
-	templates, err := template.New("layout").Funcs(templateFuncs).ParseFiles(files...)
-	if err != nil {
-		log.Fatalf("テンプレートの読み込みに失敗: %v", err)
-		return
+	// 成功したらまとめて出力
+	if _, err := buf.WriteTo(writer); err != nil {
+		return fmt.Errorf("レスポンスの書き出しに失敗: %w", err)
+	}
+	return nil
+}
+
+// RenderError エラーページを指定したステータスコードで書きだす
+func RenderError(writer http.ResponseWriter, status int, message string, isLoggedIn bool) {
+	data := ErrorPageData{
+		IsLoggedIn: isLoggedIn,
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
 	}
 
-	// テンプレートをバッファに出力
 	var buf bytes.Buffer
-	err = templates.ExecuteTemplate(&buf, "layout", data)
-	if err != nil {
-		log.Fatalf("テンプレートの実行に失敗: %v", err)
+	if err := executeTemplate(&buf, writer, data, "layout", "header", "error", "footer"); err != nil {
+		// エラーページ自体が描画できない場合はテキストで返す
+		slog.Error("エラーページの描画に失敗", "error", err)
+		http.Error(writer, message, status)
 		return
 	}
 
-	// 成功したらまとめて出力
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.WriteHeader(status)
 	buf.WriteTo(writer)
 }
+
+// nonceの差し替え位置を示す文字列を生成する
+func newNoncePlaceholder() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "csp-nonce-" + hex.EncodeToString(b)
+}
+
+// CheckTemplates テンプレートが読み込み済みかを確認する
+func CheckTemplates() error {
+	templateMu.RLock()
+	defer templateMu.RUnlock()
+	if len(templateCache) == 0 {
+		return fmt.Errorf("テンプレートが読み込まれていません")
+	}
+	return nil
+}
+
+// LoadTemplates assets の templates ディレクトリにあるすべてのページを共通テンプレートと組み合わせて読み込む
+// 読み込めないテンプレートがある場合はエラーを返す（実行時ではなく起動時に検出するため）
+func LoadTemplates(assets fs.FS) error {
+	fsys, err := fs.Sub(assets, "templates")
+	if err != nil {
+		return err
+	}
+	cache, err := parseTemplates(fsys)
+	if err != nil {
+		return err
+	}
+
+	templateMu.Lock()
+	templateFS = fsys
+	templateCache = cache
+	templateMu.Unlock()
+	return nil
+}
+
+// WatchTemplates テンプレートの変更を監視し、変更があれば読み込み直す（開発時のみ使用する）
+// 読み込みに失敗した場合はエラーを記録し、直前のテンプレートを使い続ける
+func WatchTemplates(ctx context.Context) {
+	templateMu.RLock()
+	fsys := templateFS
+	templateMu.RUnlock()
+	if fsys == nil {
+		return
+	}
+
+	ticker := time.NewTicker(templateWatchInterval)
+	defer ticker.Stop()
+	last := templatesSignature(fsys)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		signature := templatesSignature(fsys)
+		if signature == last {
+			continue
+		}
+		last = signature
+
+		cache, err := parseTemplates(fsys)
+		if err != nil {
+			slog.Error("テンプレートの再読み込みに失敗", "error", err)
+			continue
+		}
+		templateMu.Lock()
+		templateCache = cache
+		templateMu.Unlock()
+		slog.Info("テンプレートを再読み込みしました")
+	}
+}
+
+// すべてのページテンプレートを layout / header / ページ / footer の組み合わせで読み込む
+func parseTemplates(fsys fs.FS) (map[string]*template.Template, error) {
+	pages, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("テンプレートが見つかりません")
+	}
+
+	cache := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		name := strings.TrimSuffix(page, ".html")
+		if slices.Contains(commonTemplates, name) {
+			continue
+		}
+		names := []string{"layout", "header", name, "footer"}
+		t, err := parseTemplate(fsys, names)
+		if err != nil {
+			return nil, err
+		}
+		cache[templateKey(names)] = t
+	}
+	return cache, nil
+}
+
+// テンプレートファイルを読み込む
+func parseTemplate(fsys fs.FS, names []string) (*template.Template, error) {
+	var files []string
+	for _, name := range names {
+		files = append(files, name+".html")
+	}
+	t, err := template.New("layout").Funcs(templateFuncs).ParseFS(fsys, files...)
+	if err != nil {
+		return nil, fmt.Errorf("テンプレートの読み込みに失敗: %w", err)
+	}
+	return t, nil
+}
+
+// 読み込み済みのテンプレートを返す（起動時に読み込んでいない組み合わせはここで読み込んで保持する）
+func lookupTemplate(names []string) (*template.Template, error) {
+	key := templateKey(names)
+	templateMu.RLock()
+	t, ok := templateCache[key]
+	fsys := templateFS
+	templateMu.RUnlock()
+	if ok {
+		return t, nil
+	}
+	if fsys == nil {
+		return nil, fmt.Errorf("テンプレートが読み込まれていません")
+	}
+
+	t, err := parseTemplate(fsys, names)
+	if err != nil {
+		return nil, err
+	}
+	templateMu.Lock()
+	templateCache[key] = t
+	templateMu.Unlock()
+	return t, nil
+}
+
+// テンプレートの組み合わせを表すキー
+func templateKey(names []string) string {
+	return strings.Join(names, ",")
+}
+
+// テンプレートファイルの名前・更新日時・サイズをまとめた文字列（変更の検出に使う）
+func templatesSignature(fsys fs.FS) string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", entry.Name(), info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String()
+}
+
+// テンプレートを実行し、バッファに出力する
+func executeTemplate(buf *bytes.Buffer, writer http.ResponseWriter, data any, filenames ...string) error {
+	templates, err := lookupTemplate(filenames)
+	if err != nil {
+		return err
+	}
+
+	if err := templates.ExecuteTemplate(buf, "layout", data); err != nil {
+		return fmt.Errorf("テンプレートの実行に失敗: %w", err)
+	}
+
+	// セキュリティヘッダーのミドルウェアが生成したnonceをscriptタグに埋め込む
+	nonce := ""
+	if nw, ok := writer.(interface{ CSPNonce() string }); ok {
+		nonce = nw.CSPNonce()
+	}
+	replaced := bytes.ReplaceAll(buf.Bytes(), []byte(noncePlaceholder), []byte(nonce))
+	buf.Reset()
+	buf.Write(replaced)
+	return nil
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/config"
+	logging "security_chat_app/internal/utils/log"
+)
+
+// アクセスログのキー
+const accessLogKey contextKey = "accessLog"
+
+// Combined Log Format の日時の形式
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// 後段のミドルウェアがアクセスログに追記する情報
+type accessLogEntry struct {
+	userID string
+}
+
+// AccessLog リクエストごとにアクセスログを出力する
+// 形式は config の accessLogFormat に従う（combined / json / off）
+func AccessLog(next http.Handler) http.Handler {
+	if config.Config.AccessLogFormat == config.AccessLogOff {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogKey, entry))
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		duration := time.Since(start)
+		if config.Config.AccessLogFormat == config.AccessLogJSON {
+			writeJSONAccessLog(r, entry, status, rec.bytes, duration)
+		} else {
+			writeCombinedAccessLog(r, entry, start, status, rec.bytes, duration)
+		}
+	})
+}
+
+// setAccessLogUser 認証したユーザーのIDをアクセスログに記録する
+func setAccessLogUser(r *http.Request, userID string) {
+	if entry, ok := r.Context().Value(accessLogKey).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+}
+
+// Cloud Logging の httpRequest フィールドの形式で出力する
+// URLのクエリにはトークンや検索語が含まれるため、パスのみを記録する（パスの受信用Webhookのトークンはログの出力時に伏せる）
+func writeJSONAccessLog(r *http.Request, entry *accessLogEntry, status int, bytes int64, duration time.Duration) {
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	attrs := []slog.Attr{
+		slog.Group("httpRequest",
+			slog.String("requestMethod", r.Method),
+			slog.String("requestUrl", r.URL.Path),
+			slog.Int("status", status),
+			slog.String("responseSize", fmt.Sprint(bytes)),
+			slog.String("latency", fmt.Sprintf("%.6fs", duration.Seconds())),
+			slog.String("userAgent", r.UserAgent()),
+			slog.String("referer", r.Referer()),
+			slog.String("remoteIp", clientHost(r)),
+			slog.String("protocol", r.Proto),
+		),
+	}
+	if entry.userID != "" {
+		attrs = append(attrs, slog.String("user_id", entry.userID))
+	}
+	slog.LogAttrs(r.Context(), level, "アクセスログ", attrs...)
+}
+
+// Combined Log Format の末尾に処理時間とリクエストIDを付けて出力する
+// 例: 127.0.0.1 - user-id [19/Oct/2026:15:04:05 +0900] "GET /chat HTTP/1.1" 200 1234 "-" "Mozilla/5.0" duration=0.012345s request_id=abc
+func writeCombinedAccessLog(r *http.Request, entry *accessLogEntry, start time.Time, status int, bytes int64, duration time.Duration) {
+	size := "-"
+	if bytes > 0 {
+		size = fmt.Sprint(bytes)
+	}
+	requestID := logging.RequestIDFromContext(r.Context())
+	if requestID == "" {
+		requestID = "-"
+	}
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s %q %q duration=%.6fs request_id=%s\n",
+		clientHost(r),
+		orDash(entry.userID),
+		start.Format(combinedTimeFormat),
+		r.Method, r.URL.EscapedPath(), r.Proto,
+		status,
+		size,
+		orDash(r.Referer()),
+		orDash(r.UserAgent()),
+		duration.Seconds(),
+		requestID,
+	)
+	fmt.Fprint(logging.Output(), logging.RedactString(line))
+}
+
+// 接続元のホスト（ポートを除く）
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// リバースプロキシを考慮した接続元のIPアドレス
+// trustedProxies を設定した場合は、X-Forwarded-For の右から数えてプロキシの数だけ手前の値を使う
+// それより左の値はクライアントが自由に送れるため使わず、値が足りない・不正な場合は接続元のホストを使う
+func clientHost(r *http.Request) string {
+	proxies := config.Config.TrustedProxies
+	if proxies <= 0 {
+		return remoteHost(r)
+	}
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(value, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(addr))
+		}
+	}
+	if len(forwarded) < proxies {
+		return remoteHost(r)
+	}
+	ip := net.ParseIP(forwarded[len(forwarded)-proxies])
+	if ip == nil {
+		return remoteHost(r)
+	}
+	return ip.String()
+}
+
+// ClientIP 接続元のIPアドレス（監査ログに記録する）
+// アクセスログ・レート制限と同じく、信頼するプロキシの数に応じて X-Forwarded-For から求める
+func ClientIP(r *http.Request) string {
+	return clientHost(r)
+}
+
+// 空の値を "-" で表す
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"security_chat_app/internal/domain"
+)
+
+// IsAdmin 管理者かどうか（権限のみで判定する）
+// メールアドレスは登録時に確認していないため、設定の管理者のメールアドレスでは判定しない
+// 最初の管理者は管理用コマンド（user create -admin・user seed-admins）で用意する
+func IsAdmin(user *domain.User) bool {
+	return user.IsAdmin()
+}
+
+// RequireAdmin 管理者のみハンドラを実行する（Middleware の内側で使う）
+// ログインしていない場合はログインページへ、管理者でない場合は403エラーにする
+func RequireAdmin(next AppHandler) AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		user := SessionUser(r)
+		if user == nil {
+			return domain.NewUnauthorizedError("ログインしてください", nil)
+		}
+		if !IsAdmin(user) {
+			slog.WarnContext(r.Context(), "管理者以外による管理画面へのアクセス", "user_id", user.ID, "path", r.URL.Path)
+			return domain.NewForbiddenError("管理者のみ利用できます", nil)
+		}
+		return next(w, r)
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"security_chat_app/internal/domain"
+	logging "security_chat_app/internal/utils/log"
+)
+
+// APIで認証したユーザーのキー
+const apiUserKey contextKey = "apiUser"
+
+// APIErrorBody APIのエラーレスポンスの本体
+type APIErrorBody struct {
+	Code      string `json:"code" doc:"エラーの種類（not_found / validation_failed など）"`
+	Message   string `json:"message" doc:"利用者に表示できるメッセージ"`
+	RequestID string `json:"request_id,omitempty" doc:"問い合わせ時に伝えるリクエストID"`
+}
+
+// APIError APIのエラーレスポンス（すべてのエラーをこの形式で返す）
+type APIError struct {
+	Error APIErrorBody `json:"error"`
+}
+
+// APIHandler エラーを返すAPIのハンドラ
+// 返されたエラーは WriteAPIError でJSONのエラーレスポンスに変換される
+type APIHandler func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP ハンドラを実行し、エラーがあればレスポンスに変換する
+func (fn APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		WriteAPIError(w, r, err)
+	}
+}
+
+// WriteAPIError エラーの種類に応じたステータスコードとJSONのエラーレスポンスを書き出す
+func WriteAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := domain.AsAppError(err)
+	status := errorStatus(appErr.Kind)
+
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "APIエラー", "method", r.Method, "path", r.URL.Path, "status", status, "error", appErr)
+	} else {
+		slog.WarnContext(r.Context(), "APIリクエストエラー", "method", r.Method, "path", r.URL.Path, "status", status, "error", appErr)
+	}
+
+	body := APIError{Error: APIErrorBody{
+		Code:      ErrorCode(appErr.Kind),
+		Message:   appErr.Message,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+	}}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "JSONレスポンスの書き出しに失敗", "error", err)
+	}
+}
+
+// ErrorCode エラーの種類をAPIのエラーコードに変換する
+func ErrorCode(kind domain.ErrorKind) string {
+	switch kind {
+	case domain.ErrorKindNotFound:
+		return "not_found"
+	case domain.ErrorKindForbidden:
+		return "forbidden"
+	case domain.ErrorKindUnauthorized:
+		return "unauthorized"
+	case domain.ErrorKindValidation:
+		return "validation_failed"
+	case domain.ErrorKindMethodNotAllowed:
+		return "method_not_allowed"
+	case domain.ErrorKindConflict:
+		return "conflict"
+	case domain.ErrorKindTooManyRequests:
+		return "rate_limited"
+	default:
+		return "internal"
+	}
+}
+
+// APIAuth ログインしているユーザーのみAPIを呼び出せるようにする
+// セッションクッキーの代わりに Authorization: Bearer で個人用アクセストークンも受け付ける
+// 未認証の場合は画面遷移ではなく401のJSONを返す
+func APIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Authorization ヘッダーがある場合はトークンのみで認証し、クッキーには戻らない
+		if token, ok := bearerToken(r); ok {
+			apiToken, user, ok := authenticateAPIToken(w, r, token)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withAPIToken(r.Context(), apiToken, user)))
+			return
+		}
+
+		session, err := ValidateSession(w, r)
+		if err != nil {
+			WriteAPIError(w, r, domain.NewUnauthorizedError("認証されていません", err))
+			return
+		}
+		touchSession(w, r, session)
+		ctx := context.WithValue(r.Context(), apiUserKey, session.User)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CurrentUser APIAuth で認証したユーザーを返す
+func CurrentUser(r *http.Request) *domain.User {
+	user, _ := r.Context().Value(apiUserKey).(*domain.User)
+	return user
+}
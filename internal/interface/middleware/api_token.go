@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/repository"
+)
+
+// APIトークンで認証したリクエストのトークンのキー
+const apiTokenKey contextKey = "apiToken"
+
+// Authorization ヘッダーから Bearer トークンを取り出す
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// ValidateAPIToken Bearer トークンを検証し、トークンと所有者を返す
+func ValidateAPIToken(r *http.Request, token string) (*domain.APIToken, *domain.User, error) {
+	if !domain.IsAPIToken(token) {
+		return nil, nil, fmt.Errorf("APIトークンの形式が不正です")
+	}
+	apiToken, err := repository.GetAPITokenByToken(r.Context(), token)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "APIトークンの取得エラー", "error", err)
+		return nil, nil, err
+	}
+	if apiToken == nil {
+		return nil, nil, fmt.Errorf("APIトークンが見つかりません")
+	}
+	if apiToken.IsExpired() {
+		slog.InfoContext(r.Context(), "APIトークンの有効期限が切れています", "user_id", apiToken.UserID, "token_id", apiToken.ID)
+		return nil, nil, fmt.Errorf("APIトークンの有効期限が切れています")
+	}
+
+	user, err := repository.ReloadUser(apiToken.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "APIトークンのユーザー取得エラー", "error", err, "user_id", apiToken.UserID)
+		return nil, nil, err
+	}
+	if user.Suspended {
+		slog.InfoContext(r.Context(), "停止されたアカウントのAPIトークンです", "user_id", user.ID, "token_id", apiToken.ID)
+		return nil, nil, fmt.Errorf("アカウントが停止されています")
+	}
+	if user.PasswordResetRequired {
+		slog.InfoContext(r.Context(), "パスワードの再設定が必要なアカウントのAPIトークンです", "user_id", user.ID, "token_id", apiToken.ID)
+		return nil, nil, fmt.Errorf("パスワードの再設定が必要です")
+	}
+	return apiToken, user, nil
+}
+
+// トークンで認証したリクエストごとに、アクセスログへのユーザーの記録と最終使用日時の更新を行う
+// スクリプトなどからの利用を想定し、オンライン状態は変更しない
+func touchAPIToken(r *http.Request, apiToken *domain.APIToken) {
+	setAccessLogUser(r, apiToken.UserID)
+
+	// 毎リクエストの書き込みを避けるため、セッションの延長と同じ間隔でのみ保存する
+	now := time.Now()
+	if now.Sub(apiToken.LastUsedAt) < sessionRenewInterval {
+		return
+	}
+	if err := repository.TouchAPIToken(apiToken.ID, now); err != nil {
+		slog.ErrorContext(r.Context(), "APIトークンの最終使用日時の更新に失敗", "error", err, "token_id", apiToken.ID)
+	}
+}
+
+// CurrentAPIToken リクエストの認証に使われたAPIトークンを返す（セッションで認証した場合は nil）
+func CurrentAPIToken(r *http.Request) *domain.APIToken {
+	apiToken, _ := r.Context().Value(apiTokenKey).(*domain.APIToken)
+	return apiToken
+}
+
+// RequireScope APIトークンで認証したリクエストに、操作に必要なスコープがあるかを確認する
+// scope が空の場合はセッションでのみ実行できる操作として、トークンでの呼び出しを拒否する
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiToken := CurrentAPIToken(r); apiToken != nil && !checkScope(w, r, apiToken, scope) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Bearer トークンを検証し、トークンと所有者を返す
+// 無効なトークンの場合は401のエラーを書き出して false を返す
+func authenticateAPIToken(w http.ResponseWriter, r *http.Request, token string) (*domain.APIToken, *domain.User, bool) {
+	apiToken, user, err := ValidateAPIToken(r, token)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		WriteAPIError(w, r, domain.NewUnauthorizedError("APIトークンが無効です", err))
+		return nil, nil, false
+	}
+	touchAPIToken(r, apiToken)
+	return apiToken, user, true
+}
+
+// トークンに操作に必要なスコープがあるかを確認する
+// スコープがない場合（scope が空の場合を含む）は403のエラーを書き出して false を返す
+func checkScope(w http.ResponseWriter, r *http.Request, apiToken *domain.APIToken, scope string) bool {
+	if scope == "" {
+		WriteAPIError(w, r, domain.NewForbiddenError("この操作はAPIトークンでは実行できません", nil))
+		return false
+	}
+	if !apiToken.HasScope(scope) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+		WriteAPIError(w, r, domain.NewForbiddenError("APIトークンに "+scope+" のスコープがありません", nil))
+		return false
+	}
+	return true
+}
+
+// Bearer トークンで認証した情報をコンテキストに設定する
+func withAPIToken(ctx context.Context, apiToken *domain.APIToken, user *domain.User) context.Context {
+	ctx = context.WithValue(ctx, apiTokenKey, apiToken)
+	return context.WithValue(ctx, apiUserKey, user)
+}
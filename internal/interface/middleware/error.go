@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"security_chat_app/internal/domain"
+	"security_chat_app/internal/interface/markup"
+)
+
+// AppHandler エラーを返すハンドラ
+// 返されたエラーは WriteError でステータスコードとエラーページ（またはJSON）に変換される
+type AppHandler func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP ハンドラを実行し、エラーがあればレスポンスに変換する
+func (fn AppHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// Recover ハンドラ内のpanicを回復し、500エラーとして応答する
+// 1つのリクエストの不具合でサーバー全体が停止しないようにする
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// クライアントの切断による中断はそのまま伝える
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			slog.ErrorContext(r.Context(), "panicが発生しました", "method", r.Method, "path", r.URL.Path, "panic", rec, "stack", string(debug.Stack()))
+			WriteError(w, r, domain.NewInternalError("サーバーでエラーが発生しました", nil))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteError エラーの種類に応じたステータスコードでエラーを応答する
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := domain.AsAppError(err)
+	status := errorStatus(appErr.Kind)
+
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "エラー", "method", r.Method, "path", r.URL.Path, "status", status, "error", appErr)
+	} else {
+		slog.WarnContext(r.Context(), "リクエストエラー", "method", r.Method, "path", r.URL.Path, "status", status, "error", appErr)
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(map[string]string{"error": appErr.Message}); err != nil {
+			slog.ErrorContext(r.Context(), "JSONレスポンスの書き出しに失敗", "error", err)
+		}
+		return
+	}
+
+	// 画面遷移の場合、未認証はログインページへ誘導する
+	if appErr.Kind == domain.ErrorKindUnauthorized {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if appErr.Kind == domain.ErrorKindMethodNotAllowed {
+		w.Header().Set("Allow", "GET, POST")
+	}
+
+	markup.RenderError(w, status, appErr.Message, IsLoggedIn(r))
+}
+
+// エラーの種類をHTTPステータスコードに変換する
+func errorStatus(kind domain.ErrorKind) int {
+	switch kind {
+	case domain.ErrorKindNotFound:
+		return http.StatusNotFound
+	case domain.ErrorKindForbidden:
+		return http.StatusForbidden
+	case domain.ErrorKindUnauthorized:
+		return http.StatusUnauthorized
+	case domain.ErrorKindValidation:
+		return http.StatusBadRequest
+	case domain.ErrorKindMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case domain.ErrorKindConflict:
+		return http.StatusConflict
+	case domain.ErrorKindTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// JSONでの応答を求めるリクエストかどうか
+func wantsJSON(r *http.Request) bool {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		return true
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return true
+	}
+	return r.Header.Get("X-Requested-With") == "XMLHttpRequest"
+}
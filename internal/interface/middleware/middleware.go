@@ -2,10 +2,10 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"security_chat_app/internal/domain"
-	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 )
 
 // コンテキストのキーとして使用するカスタム型
@@ -14,9 +14,43 @@ type contextKey string
 // テンプレートデータのキー
 const templateDataKey contextKey = "templateData"
 
+// TokenScope 画面をAPIトークンで呼び出す場合に必要なスコープ
+// 参照（GET・HEAD）と変更（それ以外のメソッド）で分け、空の場合はトークンでは呼び出せない
+type TokenScope struct {
+	Read  string // 参照に必要なスコープ
+	Write string // 変更に必要なスコープ
+}
+
+// リクエストのメソッドに必要なスコープを返す
+func (s TokenScope) forMethod(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return s.Read
+	}
+	return s.Write
+}
+
 // セッション管理のミドルウェア
+// APIトークンでは呼び出せない、セッションでのみ利用できる画面に使う
 func Middleware(next http.Handler) http.Handler {
+	return ScopedMiddleware(TokenScope{}, next)
+}
+
+// ScopedMiddleware セッション管理のミドルウェア
+// セッションクッキーの代わりに Authorization: Bearer でAPIトークンも受け付け、トークンには scope のスコープを求める
+// Authorization ヘッダーがある場合はトークンのみで認証し、クッキーには戻らない
+func ScopedMiddleware(scope TokenScope, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			apiToken, user, ok := authenticateAPIToken(w, r, token)
+			if !ok || !checkScope(w, r, apiToken, scope.forMethod(r.Method)) {
+				return
+			}
+			data := domain.TemplateData{IsLoggedIn: true, User: user}
+			ctx := context.WithValue(withAPIToken(r.Context(), apiToken, user), templateDataKey, data)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		session, err := ValidateSession(w, r)
 		if err != nil {
 			// セッションが無効な場合は、ログインしていない状態として処理
@@ -29,12 +63,38 @@ func Middleware(next http.Handler) http.Handler {
 				User:       session.User,
 			}
 			r = r.WithContext(context.WithValue(r.Context(), templateDataKey, data))
-			
-			// Firebaseのユーザー状態をオンラインに更新
-			if err := firebase.UpdateField("users", session.User.ID, "IsOnline", true); err != nil {
-				log.Printf("ユーザー状態の更新に失敗: %v", err)
-			}
+			touchSession(w, r, session)
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// 認証済みのリクエストごとに、アクセスログへのユーザーの記録・セッションの延長・オンライン状態の更新を行う
+func touchSession(w http.ResponseWriter, r *http.Request, session *domain.Session) {
+	setAccessLogUser(r, session.User.ID)
+
+	// 操作があったのでセッションの有効期限を延長
+	if err := RenewSession(w, r, session); err != nil {
+		slog.ErrorContext(r.Context(), "セッションの延長に失敗", "error", err)
+	}
+
+	// Firebaseのユーザー状態をオンラインに更新（変化がある場合のみ）
+	if err := repository.MarkUserOnline(session.User); err != nil {
+		slog.ErrorContext(r.Context(), "ユーザー状態の更新に失敗", "error", err)
+	}
+}
+
+// IsLoggedIn ミドルウェアで判定したログイン状態を返す
+func IsLoggedIn(r *http.Request) bool {
+	data, ok := r.Context().Value(templateDataKey).(domain.TemplateData)
+	return ok && data.IsLoggedIn
+}
+
+// SessionUser Middleware で検証したセッションのユーザーを返す（ログインしていない場合は nil）
+func SessionUser(r *http.Request) *domain.User {
+	data, ok := r.Context().Value(templateDataKey).(domain.TemplateData)
+	if !ok || !data.IsLoggedIn {
+		return nil
+	}
+	return data.User
+}
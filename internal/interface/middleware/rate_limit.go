@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"security_chat_app/internal/domain"
+)
+
+// 使われなくなったキーを破棄するまでの時間
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimiter キーごと（Webhookごと・接続元ごとなど）にリクエストの頻度を制限する
+// 1分あたりの上限までは連続したリクエストも受け付け、超えた分は均等な間隔でのみ許可する
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	entries   map[string]*rateLimiterEntry
+	lastSweep time.Time
+}
+
+// キーごとの制限の状態
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter 1分あたり perMinute 回までリクエストを許可する RateLimiter を作成する
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return &RateLimiter{
+		limit:     rate.Limit(float64(perMinute) / 60),
+		burst:     perMinute,
+		entries:   make(map[string]*rateLimiterEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow キーのリクエストを許可するかどうか
+// 許可しない場合は、次に許可されるまでの待ち時間を返す
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.entries[key] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// しばらく使われていないキーを破棄する（接続元ごとのキーが増え続けないようにする）
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastSeen) > rateLimiterIdleTimeout {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Check キーのリクエストが上限を超えている場合は Retry-After を設定し、429のエラーを返す
+func (l *RateLimiter) Check(w http.ResponseWriter, key string) error {
+	ok, retryAfter := l.Allow(key)
+	if ok {
+		return nil
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return domain.NewTooManyRequestsError("リクエストが多すぎます。しばらくしてから再度お試しください")
+}
+
+// ClientKey 接続元ごとに制限する場合のキー（リバースプロキシを考慮した接続元のIPアドレス）
+func ClientKey(r *http.Request) string {
+	return clientHost(r)
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+
+	logging "security_chat_app/internal/utils/log"
+)
+
+// リクエストIDのヘッダー名
+const RequestIDHeader = "X-Request-ID"
+
+// 受け入れるリクエストIDの形式（ログへの不正な値の混入を防ぐ）
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// RequestID リクエストごとにIDを割り当て、コンテキストとレスポンスヘッダーに設定する
+// クライアントやロードバランサーが X-Request-ID を付与している場合はそれを引き継ぐ
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := logging.WithRequestID(r.Context(), requestID)
+		// Cloud Run が付与するトレースを Cloud Logging で関連付ける（TRACE_ID/SPAN_ID;o=OPTIONS）
+		if trace := r.Header.Get("X-Cloud-Trace-Context"); trace != "" {
+			traceID, _, _ := strings.Cut(trace, "/")
+			if requestIDPattern.MatchString(traceID) {
+				ctx = logging.WithTrace(ctx, traceID)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// 128bitのランダムなリクエストIDを生成する
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"security_chat_app/internal/config"
+)
+
+// CSP違反レポートの送信先
+const CSPReportPath = "/csp-report"
+
+// Firebase Storage 上のアイコン画像の配信元
+var cspImgOrigins = []string{
+	"https://firebasestorage.googleapis.com",
+	"https://storage.googleapis.com",
+}
+
+// nonceを保持するレスポンスライター
+type nonceResponseWriter struct {
+	http.ResponseWriter
+	nonce string
+}
+
+// CSPNonce リクエストごとに生成したCSPのnonceを返す
+func (w *nonceResponseWriter) CSPNonce() string {
+	return w.nonce
+}
+
+// Unwrap 元のレスポンスライターを返す（http.ResponseController 用）
+func (w *nonceResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SecurityHeaders すべてのレスポンスにセキュリティ関連のヘッダーを付与する
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce, err := generateNonce()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "CSPのnonce生成に失敗", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		header := w.Header()
+		cspHeader := "Content-Security-Policy"
+		if config.Config.CSPReportOnly {
+			cspHeader = "Content-Security-Policy-Report-Only"
+		}
+		header.Set(cspHeader, buildCSP(nonce))
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", config.Config.FrameOptions)
+		header.Set("Referrer-Policy", config.Config.ReferrerPolicy)
+		// HSTSはHTTPSで配信する場合のみ送信する
+		if config.Config.UseSecureCookie() && config.Config.HSTSMaxAge > 0 {
+			header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", config.Config.HSTSMaxAge))
+		}
+
+		next.ServeHTTP(&nonceResponseWriter{ResponseWriter: w, nonce: nonce}, r)
+	})
+}
+
+// Content-Security-Policy の値を組み立てる
+func buildCSP(nonce string) string {
+	imgSrc := append([]string{"'self'", "data:", "blob:"}, cspImgOrigins...)
+	if extra := strings.Fields(config.Config.CSPImgSrc); len(extra) > 0 {
+		imgSrc = append(imgSrc, extra...)
+	}
+
+	directives := []string{
+		"default-src 'self'",
+		fmt.Sprintf("script-src 'self' 'nonce-%s'", nonce),
+		"style-src 'self'",
+		"img-src " + strings.Join(imgSrc, " "),
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+		"report-uri " + CSPReportPath,
+	}
+	return strings.Join(directives, "; ")
+}
+
+// 128bitのランダムなnonceを生成する
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
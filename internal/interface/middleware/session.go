@@ -4,20 +4,33 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"security_chat_app/internal/config"
 	"security_chat_app/internal/domain"
 	"security_chat_app/internal/infrastructure/firebase"
+	"security_chat_app/internal/infrastructure/repository"
 )
 
+// 操作によるセッション延長を保存する最小間隔（毎リクエストの書き込みを避ける）
+const sessionRenewInterval = time.Minute
+
+// セッションクッキーの名前
+// Secure属性が有効な場合は __Host- 接頭辞を付け、Path=/ かつドメイン指定なしを強制する
+func SessionCookieName() string {
+	if config.Config.UseSecureCookie() {
+		return "__Host-session_id"
+	}
+	return "session_id"
+}
 
 // セッションを検証
 func ValidateSession(w http.ResponseWriter, r *http.Request) (*domain.Session, error) {
-	cookie, err := r.Cookie("session_id")
+	cookie, err := r.Cookie(SessionCookieName())
 	if err != nil {
-		log.Printf("セッションクッキー取得エラー: %v", err)
+		slog.DebugContext(r.Context(), "セッションクッキーがありません", "error", err)
 		return nil, err
 	}
 
@@ -26,7 +39,7 @@ func ValidateSession(w http.ResponseWriter, r *http.Request) (*domain.Session, e
 	// Firestoreからセッションを取得
 	client, err := firebase.InitFirebase()
 	if err != nil {
-		log.Printf("Firebase初期化エラー: %v", err)
+		slog.ErrorContext(r.Context(), "Firebase初期化エラー", "error", err)
 		return nil, err
 	}
 	defer client.Close()
@@ -34,20 +47,37 @@ func ValidateSession(w http.ResponseWriter, r *http.Request) (*domain.Session, e
 	ctx := r.Context()
 	doc, err := client.Collection("sessions").Doc(sessionID).Get(ctx)
 	if err != nil {
-		log.Printf("セッション取得エラー: %v, sessionID=%s", err, sessionID)
+		slog.WarnContext(r.Context(), "セッション取得エラー", "error", err)
 		return nil, err
 	}
 
 	var session domain.Session
 	if err := doc.DataTo(&session); err != nil {
-		log.Printf("セッションデータ変換エラー: %v", err)
+		slog.ErrorContext(r.Context(), "セッションデータ変換エラー", "error", err)
 		return nil, err
 	}
 
 	if !session.CheckSession() {
-		log.Printf("セッションが無効です: sessionID=%s", sessionID)
+		slog.InfoContext(r.Context(), "セッションが無効です", "user_id", session.UserID)
 		return nil, fmt.Errorf("セッションが無効です")
 	}
+
+	// ユーザー情報はセッションに保存せず、リクエスト毎に読み込む
+	// 停止や資格情報の変更を他のインスタンスの分も即座に反映するため、キャッシュは使わない
+	user, err := repository.ReloadUser(session.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "セッションのユーザー取得エラー", "error", err, "user_id", session.UserID)
+		return nil, err
+	}
+	if !session.CheckCredential(user) {
+		return nil, fmt.Errorf("セッションが無効です")
+	}
+	if user.Suspended {
+		slog.InfoContext(r.Context(), "停止されたアカウントのセッションです", "user_id", user.ID)
+		return nil, fmt.Errorf("アカウントが停止されています")
+	}
+	session.User = user
+
 	return &session, nil
 }
 
@@ -61,15 +91,18 @@ func CreateSession(user *domain.User) (*domain.Session, error) {
 	sessionID := base64.URLEncoding.EncodeToString(bytes)
 
 	// セッションの作成
+	now := time.Now()
 	session := &domain.Session{
-		ID:        sessionID,                           // セッションID
-		User:      user,                                // ユーザー
-		Token:     sessionID,                           // セッショントークン
-		CreatedAt: time.Now(),                          // セッションの作成日時
-		UpdatedAt: time.Now(),                          // セッションの更新日時
-		ExpiredAt: time.Now().Add(30 * 24 * time.Hour), // 30日間有効
-		IsValid:   true,                                // セッションが有効かどうか
+		ID:                sessionID,                                     // セッションID
+		UserID:            user.ID,                                       // ユーザーID
+		CredentialVersion: user.CredentialVersion,                        // 認証情報のバージョン
+		User:              user,                                          // ユーザー（保存されない）
+		Token:             sessionID,                                     // セッショントークン
+		CreatedAt:         now,                                           // セッションの作成日時
+		ExpiredAt:         now.Add(config.Config.SessionAbsoluteTimeout), // 絶対期限
+		IsValid:           true,                                          // セッションが有効かどうか
 	}
+	session.Touch(config.Config.SessionIdleTimeout)
 
 	// Firestoreにセッションを保存
 	err := firebase.AddData("sessions", session, sessionID)
@@ -83,13 +116,13 @@ func CreateSession(user *domain.User) (*domain.Session, error) {
 // セッションクッキーを設定
 func SetSessionCookie(w http.ResponseWriter, session *domain.Session) {
 	cookie := &http.Cookie{
-		Name:     "session_id",
+		Name:     SessionCookieName(),
 		Value:    session.ID,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false,                // 開発環境ではfalseに設定
-		SameSite: http.SameSiteLaxMode, // 開発環境ではLaxに設定
-		MaxAge:   86400 * 30,           // 30日
+		Secure:   config.Config.UseSecureCookie(),              // 本番環境では既定で有効
+		SameSite: config.Config.CookieSameSiteMode(),           // 設定ファイルで指定
+		MaxAge:   int(time.Until(session.ExpiredAt).Seconds()), // 絶対期限まで
 	}
 	http.SetCookie(w, cookie)
 }
@@ -108,9 +141,34 @@ func UpdateSession(w http.ResponseWriter, r *http.Request, session *domain.Sessi
 	return nil
 }
 
+// 操作があった場合にセッションの無操作による有効期限を延長する
+func RenewSession(w http.ResponseWriter, r *http.Request, session *domain.Session) error {
+	if time.Since(session.UpdatedAt) < sessionRenewInterval {
+		return nil
+	}
+	session.Touch(config.Config.SessionIdleTimeout)
+	return UpdateSession(w, r, session)
+}
+
+// セッションIDをローテーションする
+// ログイン時や権限・認証情報の変更時に、リクエストのセッションを破棄して新しいセッションを発行する
+func RotateSession(w http.ResponseWriter, r *http.Request, user *domain.User) (*domain.Session, error) {
+	session, err := CreateSession(user)
+	if err != nil {
+		return nil, err
+	}
+	if cookie, err := r.Cookie(SessionCookieName()); err == nil && cookie.Value != "" {
+		if err := firebase.DeleteData("sessions", cookie.Value); err != nil {
+			slog.ErrorContext(r.Context(), "旧セッションの削除に失敗", "error", err)
+		}
+	}
+	SetSessionCookie(w, session)
+	return session, nil
+}
+
 // セッションを削除
 func DeleteSession(w http.ResponseWriter, r *http.Request) error {
-	cookie, err := r.Cookie("session_id")
+	cookie, err := r.Cookie(SessionCookieName())
 	if err != nil {
 		return err
 	}
@@ -128,9 +186,16 @@ func DeleteSession(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	// クッキーを削除
-	cookie.MaxAge = -1
-	http.SetCookie(w, cookie)
+	// クッキーを削除（__Host- 接頭辞のクッキーは同じ属性で上書きする必要がある）
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookie.Name,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   config.Config.UseSecureCookie(),
+		SameSite: config.Config.CookieSameSiteMode(),
+		MaxAge:   -1,
+	})
 
 	return nil
 }
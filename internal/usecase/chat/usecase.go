@@ -3,9 +3,11 @@ package chat
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"security_chat_app/internal/domain"
+	"security_chat_app/internal/infrastructure/firebase"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -57,9 +59,25 @@ func (c *chatUsecaseImpl) GetChatHistory(user *domain.User) ([]domain.Chat, erro
 }
 
 // GetContactsメソッドの実装
+// オンラインの連絡先を先に、それ以外は最終接続日時の新しい順に並べる
 func (c *chatUsecaseImpl) GetContacts(user *domain.User) ([]domain.Contact, error) {
-	// TODO: 実装
-	return nil, fmt.Errorf("not implemented")
+	if user == nil {
+		return nil, fmt.Errorf("ユーザー情報が無効です")
+	}
+	contacts, err := c.repo.GetContacts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(contacts, func(i, j int) bool {
+		if contacts[i].IsOnline != contacts[j].IsOnline {
+			return contacts[i].IsOnline
+		}
+		if !contacts[i].LastSeen.Equal(contacts[j].LastSeen) {
+			return contacts[i].LastSeen.After(contacts[j].LastSeen)
+		}
+		return contacts[i].Username < contacts[j].Username
+	})
+	return contacts, nil
 }
 
 // **************************************************
@@ -123,12 +141,72 @@ func (r *chatRepository) GetMessages(chatID string) ([]domain.Message, error) {
 		if err := doc.DataTo(&message); err != nil {
 			return nil, err
 		}
+
+		// 保存時暗号化されている場合は復号する
+		data := doc.Data()
+		if err := firebase.DecryptMessage(context.Background(), r.client, chatID, doc.Ref.ID, data); err != nil {
+			return nil, err
+		}
+		message.Content, _ = data["content"].(string)
+		message.MediaURL, _ = data["media_url"].(string)
 		messages = append(messages, message)
 	}
 
 	return messages, nil
 }
 
+// GetContactsメソッドの実装
+func (r *chatRepository) GetContacts(userID string) ([]domain.Contact, error) {
+	ctx := context.Background()
+
+	// 連絡先の関係から相手のユーザーIDを集める
+	links, err := r.client.Collection("contactLinks").Where("UserIDs", "array-contains", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var refs []*firestore.DocumentRef
+	for _, doc := range links {
+		var link domain.ContactLink
+		if err := doc.DataTo(&link); err != nil {
+			return nil, err
+		}
+		for _, id := range link.UserIDs {
+			if id != userID {
+				refs = append(refs, r.client.Collection("users").Doc(id))
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return []domain.Contact{}, nil
+	}
+
+	// 相手のユーザー情報をまとめて取得する（削除されたユーザーは除く）
+	docs, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	contacts := make([]domain.Contact, 0, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var user domain.User
+		if err := doc.DataTo(&user); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, domain.Contact{
+			ID:       doc.Ref.ID,
+			Username: user.Name,
+			Icon:     user.Icon,
+			LastSeen: user.LastSeenAt,
+			IsOnline: user.IsOnline,
+			IsBot:    user.IsBot(),
+		})
+	}
+
+	return contacts, nil
+}
+
 // **************************************************
 // ChatControllerの定義 **************
 // **************************************************
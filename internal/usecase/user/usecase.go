@@ -19,9 +19,15 @@ func CreateUser(name, email, password string) (*domain.User, error) {
 		return nil, err
 	}
 
+	// UUIDの生成
+	userID, err := utils.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
 	// ユーザーを作成
 	user := &domain.User{
-		ID:        utils.GenerateUUID(), // UUIDを生成する関数は別途実装が必要
+		ID:        userID,
 		Name:      name,
 		Email:     email,
 		Password:  string(hashedPassword),
@@ -0,0 +1,52 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// ビルド時に -ldflags で埋め込む値
+//
+//	go build -ldflags "-X security_chat_app/internal/utils/buildinfo.Version=v1.2.0 \
+//	  -X security_chat_app/internal/utils/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X security_chat_app/internal/utils/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info ビルド情報
+type Info struct {
+	Version   string `json:"version"`    // バージョン
+	Commit    string `json:"commit"`     // コミットハッシュ
+	BuildTime string `json:"build_time"` // ビルド日時
+	GoVersion string `json:"go_version"` // Goのバージョン
+}
+
+// Get ビルド情報を取得する
+// ldflags で埋め込まれていない項目は、Goが記録したVCS情報で補完する
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,153 @@
+package envelope
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 鍵の長さ（AES-256）
+const KeySize = 32
+
+// マスター鍵の集合
+// 新しいデータ鍵は有効なマスター鍵で暗号化し、過去のマスター鍵は復号（再暗号化）のためだけに保持する
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+// 新しい鍵を生成する
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// AES-256-GCM で暗号化する（先頭にノンスを付与する）
+// additionalData は暗号化しないが改ざんを検出する関連データで、復号時にも同じ値が必要
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Seal で暗号化したデータを復号する（additionalData は暗号化時と同じ値を指定する）
+func Open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("暗号文が短すぎます")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("鍵の長さが不正です: %dバイト", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 空のキーリングを作成する
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string][]byte)}
+}
+
+// Base64 エンコードされたマスター鍵を追加する
+func (k *Keyring) Add(keyID, encodedKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return fmt.Errorf("マスター鍵 %s の形式が不正です: %v", keyID, err)
+	}
+	if len(key) != KeySize {
+		return fmt.Errorf("マスター鍵 %s の長さが不正です: %dバイト（%dバイトが必要）", keyID, len(key), KeySize)
+	}
+	k.keys[keyID] = key
+	// 明示的に指定されるまでは最後に追加した鍵を有効にする
+	k.activeID = keyID
+	return nil
+}
+
+// 鍵ファイルを読み込む
+// 1行に「鍵ID=Base64エンコードした32バイトの鍵」を記述し、# で始まる行はコメントとして扱う
+func (k *Keyring) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("鍵ファイルのオープンに失敗: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyID, encodedKey, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(keyID) == "" {
+			return fmt.Errorf("鍵ファイルの形式が不正です: %s", path)
+		}
+		if err := k.Add(strings.TrimSpace(keyID), encodedKey); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// 有効なマスター鍵を指定する
+func (k *Keyring) SetActive(keyID string) error {
+	if _, ok := k.keys[keyID]; !ok {
+		return fmt.Errorf("マスター鍵が見つかりません: %s", keyID)
+	}
+	k.activeID = keyID
+	return nil
+}
+
+// マスター鍵が1つ以上あるか
+func (k *Keyring) Enabled() bool {
+	return k != nil && len(k.keys) > 0
+}
+
+// 有効なマスター鍵のID
+func (k *Keyring) ActiveID() string {
+	return k.activeID
+}
+
+// データ鍵を有効なマスター鍵で暗号化する
+func (k *Keyring) Wrap(dataKey []byte) (string, []byte, error) {
+	wrapped, err := Seal(k.keys[k.activeID], dataKey, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return k.activeID, wrapped, nil
+}
+
+// 指定したマスター鍵で暗号化されたデータ鍵を復号する
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("マスター鍵が見つかりません: %s", keyID)
+	}
+	return Open(key, wrapped, nil)
+}
@@ -0,0 +1,233 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// テスト用の鍵を生成する
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+// テスト用のマスター鍵を Base64 エンコードして返す
+func newEncodedKey(t *testing.T) string {
+	t.Helper()
+	return base64.StdEncoding.EncodeToString(newTestKey(t))
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key := newTestKey(t)
+	tests := []struct {
+		name           string
+		plaintext      []byte
+		additionalData []byte
+	}{
+		{"text", []byte("こんにちは"), nil},
+		{"empty plaintext", []byte{}, nil},
+		{"with associated data", []byte("hello"), []byte("chat/message/content")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(key, tt.plaintext, tt.additionalData)
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+			if len(tt.plaintext) > 0 && bytes.Contains(sealed, tt.plaintext) {
+				t.Fatal("sealed data contains the plaintext")
+			}
+			opened, err := Open(key, sealed, tt.additionalData)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if !bytes.Equal(opened, tt.plaintext) {
+				t.Fatalf("Open() = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshNonce(t *testing.T) {
+	key := newTestKey(t)
+	first, _ := Seal(key, []byte("same"), nil)
+	second, _ := Seal(key, []byte("same"), nil)
+	if bytes.Equal(first, second) {
+		t.Fatal("sealing the same plaintext twice produced the same output")
+	}
+}
+
+func TestOpenRejectsDifferentAdditionalData(t *testing.T) {
+	key := newTestKey(t)
+	sealed, err := Seal(key, []byte("hello"), []byte("chat-1/msg-1/content"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	tests := []struct {
+		name           string
+		additionalData []byte
+	}{
+		{"other chat", []byte("chat-2/msg-1/content")},
+		{"other message", []byte("chat-1/msg-2/content")},
+		{"other field", []byte("chat-1/msg-1/media_url")},
+		{"none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(key, sealed, tt.additionalData); err == nil {
+				t.Fatalf("Open() with %q succeeded, want an error", tt.additionalData)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsTamperedCiphertext(t *testing.T) {
+	key := newTestKey(t)
+	sealed, err := Seal(key, []byte("hello"), nil)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	flip := func(i int) []byte {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x01
+		return tampered
+	}
+	tests := []struct {
+		name   string
+		key    []byte
+		sealed []byte
+	}{
+		{"nonce", key, flip(0)},
+		{"ciphertext", key, flip(12)},
+		{"tag", key, flip(len(sealed) - 1)},
+		{"truncated", key, sealed[:len(sealed)-1]},
+		{"shorter than nonce", key, sealed[:4]},
+		{"other key", newTestKey(t), sealed},
+		{"invalid key size", key[:16], sealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.key, tt.sealed, nil); err == nil {
+				t.Fatal("Open() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, newKey := newEncodedKey(t), newEncodedKey(t)
+	dataKey := newTestKey(t)
+
+	k := NewKeyring()
+	if k.Enabled() {
+		t.Fatal("empty keyring is enabled")
+	}
+	if err := k.Add("old", oldKey); err != nil {
+		t.Fatalf("Add(old) error = %v", err)
+	}
+	oldID, oldWrapped, err := k.Wrap(dataKey)
+	if err != nil || oldID != "old" {
+		t.Fatalf("Wrap() = %q, %v; want old, nil", oldID, err)
+	}
+
+	// 追加した鍵が有効になり、新しいデータ鍵は新しいマスター鍵で暗号化される
+	if err := k.Add("new", newKey); err != nil {
+		t.Fatalf("Add(new) error = %v", err)
+	}
+	if k.ActiveID() != "new" {
+		t.Fatalf("ActiveID() = %q after Add, want new", k.ActiveID())
+	}
+	newID, newWrapped, err := k.Wrap(dataKey)
+	if err != nil || newID != "new" {
+		t.Fatalf("Wrap() = %q, %v; want new, nil", newID, err)
+	}
+
+	// 有効でなくなったマスター鍵でも、キーリングに残っている間は復号できる
+	for _, tt := range []struct {
+		id      string
+		wrapped []byte
+	}{{oldID, oldWrapped}, {newID, newWrapped}} {
+		got, err := k.Unwrap(tt.id, tt.wrapped)
+		if err != nil || !bytes.Equal(got, dataKey) {
+			t.Fatalf("Unwrap(%s) = %x, %v; want the data key", tt.id, got, err)
+		}
+	}
+	if _, err := k.Unwrap(newID, oldWrapped); err == nil {
+		t.Fatal("Unwrap() with the wrong master key succeeded")
+	}
+
+	// 明示的に有効な鍵を戻せる
+	if err := k.SetActive("old"); err != nil || k.ActiveID() != "old" {
+		t.Fatalf("SetActive(old) = %v, ActiveID() = %q", err, k.ActiveID())
+	}
+	if err := k.SetActive("missing"); err == nil {
+		t.Fatal("SetActive(missing) succeeded")
+	}
+
+	// 廃止したマスター鍵（キーリングにない鍵）で暗号化されたデータ鍵は復号できない
+	retired := NewKeyring()
+	if err := retired.Add("new", newKey); err != nil {
+		t.Fatalf("Add(new) error = %v", err)
+	}
+	if _, err := retired.Unwrap(oldID, oldWrapped); err == nil {
+		t.Fatal("Unwrap() with a retired master key succeeded")
+	}
+	if got, err := retired.Unwrap(newID, newWrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Fatalf("Unwrap(new) = %x, %v; want the data key", got, err)
+	}
+}
+
+func TestKeyringAddRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"not base64", "not base64!"},
+		{"too short", base64.StdEncoding.EncodeToString(make([]byte, 16))},
+		{"too long", base64.StdEncoding.EncodeToString(make([]byte, 33))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewKeyring()
+			if err := k.Add("key", tt.key); err == nil {
+				t.Fatal("Add() succeeded, want an error")
+			}
+			if k.Enabled() {
+				t.Fatal("keyring is enabled after a rejected key")
+			}
+		})
+	}
+}
+
+func TestKeyringLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# マスター鍵\n\n2024=" + newEncodedKey(t) + "\n2025 = " + newEncodedKey(t) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	k := NewKeyring()
+	if err := k.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if k.ActiveID() != "2025" {
+		t.Fatalf("ActiveID() = %q, want the last key in the file", k.ActiveID())
+	}
+	if err := k.SetActive("2024"); err != nil {
+		t.Fatalf("SetActive(2024) error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("missing-separator\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewKeyring().LoadFile(path); err == nil {
+		t.Fatal("LoadFile() accepted a line without a key ID")
+	}
+}
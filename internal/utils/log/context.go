@@ -0,0 +1,33 @@
+package utils
+
+import "context"
+
+// コンテキストのキーとして使用するカスタム型
+type contextKey string
+
+const (
+	requestIDKey contextKey = "requestID"
+	traceKey     contextKey = "trace"
+)
+
+// WithRequestID リクエストIDをコンテキストに設定する
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext コンテキストからリクエストIDを取り出す
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithTrace Cloud Trace のトレースIDをコンテキストに設定する
+func WithTrace(ctx context.Context, trace string) context.Context {
+	return context.WithValue(ctx, traceKey, trace)
+}
+
+// TraceFromContext コンテキストからトレースIDを取り出す
+func TraceFromContext(ctx context.Context) string {
+	trace, _ := ctx.Value(traceKey).(string)
+	return trace
+}
@@ -1,17 +1,169 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 )
 
-func LoggingSettings(logFile string) {
-	logfile, err := os.OpenFile(logFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o666)
+// ログの出力形式
+const (
+	FormatText = "text" // 開発向けの key=value 形式
+	FormatJSON = "json" // Cloud Logging 向けのJSON形式
+)
+
+// Options ロガーの設定
+type Options struct {
+	File      string // 出力先ファイル（標準出力と両方に出力する）
+	Level     string // 出力する最低レベル（debug / info / warn / error）
+	Format    string // 出力形式（text / json）
+	ProjectID string // Cloud Logging でトレースを関連付けるためのプロジェクトID
+
+	MaxSize    int64         // ログファイルをローテーションするサイズ（バイト、0の場合はサイズでローテーションしない）
+	MaxAge     time.Duration // ログファイルをローテーションするまでの時間（0の場合は時間でローテーションしない）
+	MaxBackups int           // 残すローテーション済みファイルの数（0の場合はすべて残す）
+}
+
+// ログの出力先ファイル
+var logfile *rotatingFile
+
+// ログの出力先（標準出力とログファイル）
+var output io.Writer = os.Stdout
+
+// 現在の設定（ログファイルを閉じた後に標準出力のみで作り直すため）
+var current Options
+
+// LoggingSettings slogの既定のロガーを設定する
+// 標準の log パッケージの出力も同じロガーに INFO レベルで流れる
+func LoggingSettings(opts Options) {
+	var out io.Writer = os.Stdout
+	if opts.File != "" {
+		file, err := openRotatingFile(opts.File, opts.MaxSize, opts.MaxAge, opts.MaxBackups)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		logfile = file
+		out = io.MultiWriter(os.Stdout, logfile)
+	}
+	current = opts
+	output = out
+	slog.SetDefault(slog.New(newHandler(out, opts)))
+}
+
+// CloseLogFile ログファイルの内容をディスクに書き出して閉じる
+// 以降のログは標準出力のみに出力する
+func CloseLogFile() error {
+	if logfile == nil {
+		return nil
+	}
+	output = os.Stdout
+	slog.SetDefault(slog.New(newHandler(os.Stdout, current)))
+	if err := logfile.Sync(); err != nil {
+		logfile.Close()
+		return err
+	}
+	err := logfile.Close()
+	logfile = nil
+	return err
+}
+
+// Output ログの出力先を返す（アクセスログなど、slogを通さずに1行ずつ書き出す場合に使う）
+func Output() io.Writer {
+	return output
+}
+
+// ParseLevel ログレベルの文字列を解析する
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("不明なログレベルです: %s", level)
+	}
+}
+
+// 出力形式に応じたハンドラを作成する
+func newHandler(out io.Writer, opts Options) slog.Handler {
+	level, err := ParseLevel(opts.Level)
 	if err != nil {
-		log.Fatalln(err)
+		log.Printf("%v（info を使用します）", err)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   level == slog.LevelDebug,
+		ReplaceAttr: replaceAttr(opts.Format),
+	}
+
+	var handler slog.Handler
+	if opts.Format == FormatJSON {
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(out, handlerOpts)
+	}
+	return &contextHandler{Handler: handler, projectID: opts.ProjectID}
+}
+
+// 属性を書き出す前に、Cloud Logging 向けのキー名への変換と個人情報の伏せ字化を行う
+func replaceAttr(format string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && format == FormatJSON {
+			switch a.Key {
+			case slog.LevelKey:
+				// Cloud Logging は severity を参照する（WARN は WARNING と表記する）
+				level := a.Value.Any().(slog.Level)
+				severity := level.String()
+				if level == slog.LevelWarn {
+					severity = "WARNING"
+				}
+				return slog.String("severity", severity)
+			case slog.MessageKey:
+				return slog.String("message", RedactString(a.Value.String()))
+			}
+		}
+		if a.Key == slog.MessageKey {
+			return slog.String(a.Key, RedactString(a.Value.String()))
+		}
+		return redactAttr(a)
 	}
-	multiLoadFile := io.MultiWriter(os.Stdout, logfile)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.SetOutput(multiLoadFile)
+}
+
+// コンテキストのリクエストIDとトレースをログに付与するハンドラ
+type contextHandler struct {
+	slog.Handler
+	projectID string
+}
+
+// Handle リクエストに紐づく情報を付与して書き出す
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			record.AddAttrs(slog.String("request_id", requestID))
+		}
+		if trace := TraceFromContext(ctx); trace != "" && h.projectID != "" {
+			record.AddAttrs(slog.String("logging.googleapis.com/trace", fmt.Sprintf("projects/%s/traces/%s", h.projectID, trace)))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs 属性を追加したハンドラを返す
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), projectID: h.projectID}
+}
+
+// WithGroup グループを追加したハンドラを返す
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), projectID: h.projectID}
 }
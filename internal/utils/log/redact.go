@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// 伏せ字
+const redacted = "[REDACTED]"
+
+// メールアドレスに一致する正規表現
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// セッションのドキュメントパスに一致する正規表現（Firestoreのエラーにセッション ID が含まれるため）
+var sessionPathPattern = regexp.MustCompile(`sessions/[^\s"'/]+`)
+
+// 受信用Webhookのパスに一致する正規表現（パスのトークンがそのまま投稿の認証情報になるため）
+// アクセスログやエラーログのパスは、ルートに一致しなかった場合も含めてここで伏せる
+var hookPathPattern = regexp.MustCompile(`/hooks/[^\s"'/?#]+`)
+
+// 値をすべて伏せる属性のキー（小文字で比較する）
+var secretKeys = map[string]bool{
+	"session_id":    true,
+	"sessionid":     true,
+	"session":       true,
+	"token":         true,
+	"password":      true,
+	"authorization": true,
+	"cookie":        true,
+}
+
+// 長さのみを残す属性のキー（メッセージ本文など）
+var contentKeys = map[string]bool{
+	"content":   true,
+	"text":      true,
+	"body":      true,
+	"media_url": true,
+}
+
+// メールアドレスを表す属性のキー
+var emailKeys = map[string]bool{
+	"email": true,
+	"mail":  true,
+}
+
+// RedactEmail メールアドレスの先頭1文字とドメイン以外を伏せる（例: t***@example.com）
+func RedactEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// RedactString 文字列に含まれるメールアドレスとセッション ID、受信用Webhookのトークンを伏せる
+func RedactString(s string) string {
+	s = emailPattern.ReplaceAllStringFunc(s, RedactEmail)
+	s = hookPathPattern.ReplaceAllString(s, "/hooks/"+redacted)
+	return sessionPathPattern.ReplaceAllString(s, "sessions/"+redacted)
+}
+
+// 属性のキーと値に応じて個人情報を伏せる
+func redactAttr(a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(a.Key, redacted)
+	case contentKeys[key]:
+		return slog.String(a.Key, fmt.Sprintf("[REDACTED len=%d]", len(a.Value.String())))
+	case emailKeys[key]:
+		return slog.String(a.Key, RedactEmail(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		// エラーなどは文字列にしてからメールアドレスを伏せる
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
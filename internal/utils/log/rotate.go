@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ローテーションしたファイル名に付ける日時の形式
+const rotateTimeFormat = "20060102-150405"
+
+// サイズまたは経過時間でローテーションするログファイル
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64         // ローテーションするサイズ（バイト、0の場合はサイズでローテーションしない）
+	maxAge     time.Duration // ローテーションするまでの時間（0の場合は時間でローテーションしない）
+	maxBackups int           // 残すローテーション済みファイルの数（0の場合はすべて残す）
+	file       *os.File
+	size       int64
+	openedAt   time.Time
+}
+
+// ログファイルを開く（既存のファイルには追記する）
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write 必要に応じてローテーションしてから書き込む
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			// ローテーションに失敗しても、ログは現在のファイルに書き続ける
+			fmt.Fprintf(os.Stderr, "ログファイルのローテーションに失敗: %v\n", err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync ファイルの内容をディスクに書き出す
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close ファイルを閉じる
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// 書き込む前にローテーションが必要かどうか
+func (f *rotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+int64(n) > f.maxSize {
+		return true
+	}
+	return f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge
+}
+
+// ファイルを開き、現在のサイズを記録する
+// 経過時間はファイルを開いた時点（起動時またはローテーション時）から数える
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// 現在のファイルを日時付きの名前に変更し、新しいファイルを開く
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	backup := f.path + "." + time.Now().Format(rotateTimeFormat)
+	if _, err := os.Stat(backup); err == nil {
+		// 同じ秒に複数回ローテーションした場合は連番を付ける
+		for i := 1; ; i++ {
+			candidate := fmt.Sprintf("%s.%d", backup, i)
+			if _, err := os.Stat(candidate); os.IsNotExist(err) {
+				backup = candidate
+				break
+			}
+		}
+	}
+	renameErr := os.Rename(f.path, backup)
+	// 名前の変更に失敗しても、書き込みを続けるためにファイルを開き直す
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	return f.removeOldBackups()
+}
+
+// 保持数を超えた古いローテーション済みファイルを削除する
+func (f *rotatingFile) removeOldBackups() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+	if len(backups) <= f.maxBackups {
+		return nil
+	}
+	// ファイル名の日時の順に並べ、古いものから削除する
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-f.maxBackups] {
+		if err := os.Remove(backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,77 @@
-document.addEventListener("DOMContentLoaded", function () {
+document.addEventListener("DOMContentLoaded", async function () {
+  const chatRoot = document.getElementById("js-chat");
   const messageForm = document.getElementById("messageForm");
   const messageInput = document.getElementById("js-messageInput");
   const messageArea = document.getElementById("js-messageArea");
   const sendButton = document.getElementById("js-sendButton");
+  const enableE2EButton = document.getElementById("js-enableE2E");
+  const currentUserId = chatRoot.dataset.userId;
+
+  // 他の参加者が暗号化できるよう、この端末の公開鍵を登録しておく
+  try {
+    await E2E.ensureDevice(currentUserId);
+  } catch (error) {
+    console.error("Error:", error);
+  }
+
+  // チャットが選択されていない場合は何もしない
+  if (!messageForm) {
+    return;
+  }
+
   const buttonText = sendButton.querySelector(".js-buttonText");
+  const chatId = messageForm.dataset.chatId;
+  const isEncrypted = messageForm.dataset.encrypted === "true";
+  let chatKey = null;
+
+  // エンドツーエンド暗号化を有効にする
+  if (enableE2EButton) {
+    enableE2EButton.addEventListener("click", async function () {
+      if (!confirm("このチャットのエンドツーエンド暗号化を有効にしますか？\n有効にした後は元に戻せません。")) {
+        return;
+      }
+      enableE2EButton.disabled = true;
+      try {
+        await E2E.enableEncryption(chatId, currentUserId);
+        window.location.reload();
+      } catch (error) {
+        console.error("Error:", error);
+        alert(error.message);
+        enableE2EButton.disabled = false;
+      }
+    });
+  }
+
+  // 暗号化されたチャットの場合は鍵を読み込み、メッセージを復号する
+  if (isEncrypted) {
+    try {
+      const state = await E2E.loadChatKey(chatId, currentUserId);
+      chatKey = state.key;
+    } catch (error) {
+      console.error("Error:", error);
+    }
+
+    const encryptedTexts = messageArea.querySelectorAll(
+      ".p-message__text[data-encrypted='true']"
+    );
+    for (const el of encryptedTexts) {
+      if (!chatKey) {
+        el.textContent = "この端末では復号できないメッセージです";
+        continue;
+      }
+      try {
+        el.textContent = await E2E.decryptMessage(chatKey, el.textContent.trim());
+      } catch (error) {
+        el.textContent = "メッセージを復号できませんでした";
+      }
+    }
+
+    if (!chatKey) {
+      sendButton.disabled = true;
+      messageInput.placeholder =
+        "この端末にはチャット鍵がありません。相手が再度チャットを開くと共有されます";
+    }
+  }
 
   // テキストエリアの高さを自動調整する関数
   function adjustTextareaHeight(textarea) {
@@ -31,13 +99,23 @@ document.addEventListener("DOMContentLoaded", function () {
       return;
     }
 
+    const plaintext = messageInput.value;
     const formData = new FormData(messageForm);
     sendButton.disabled = true;
     buttonText.textContent = "送信中";
 
     try {
+      // 暗号化されたチャットでは暗号文のみを送信する
+      if (isEncrypted) {
+        if (!chatKey) {
+          throw new Error("チャット鍵がありません");
+        }
+        formData.set("content", await E2E.encryptMessage(chatKey, plaintext));
+      }
+
       const response = await fetch("/chat", {
         method: "POST",
+        headers: { Accept: "application/json" },
         body: formData,
       });
 
@@ -52,7 +130,7 @@ document.addEventListener("DOMContentLoaded", function () {
       messageDiv.className = "l-chatMain__message p-message --sent";
       messageDiv.innerHTML = `
         <div class="l-chatMain__content p-message__content">
-          <p class="p-message__text c-txt">${escapeHtml(data.content)}</p>
+          <p class="p-message__text c-txt">${escapeHtml(plaintext)}</p>
           <time class="p-message__time c-time">${data.created_at}</time>
         </div>
       `;
@@ -3,3 +3,36 @@ const successMessage = urlParams.get("success");
 if (successMessage) {
   alert(successMessage);
 }
+
+// 画像の読み込みに失敗した場合、data-fallback-src の画像に差し替える
+// （CSPでインラインのイベントハンドラを禁止しているため、onerror属性の代わりに使用する）
+function applyFallbackSrc(img) {
+  if (img.dataset.fallbackSrc && !img.dataset.fallbackApplied) {
+    img.dataset.fallbackApplied = "true";
+    img.src = img.dataset.fallbackSrc;
+  }
+}
+
+document.addEventListener(
+  "error",
+  function (e) {
+    if (e.target instanceof HTMLImageElement) {
+      applyFallbackSrc(e.target);
+    }
+  },
+  true
+);
+
+document.addEventListener("DOMContentLoaded", function () {
+  // スクリプト読み込み前に失敗していた画像
+  document.querySelectorAll("img[data-fallback-src]").forEach((img) => {
+    if (img.complete && img.naturalWidth === 0) {
+      applyFallbackSrc(img);
+    }
+  });
+
+  // 前のページに戻るボタン
+  document.querySelectorAll(".js-historyBack").forEach((button) => {
+    button.addEventListener("click", () => history.back());
+  });
+});
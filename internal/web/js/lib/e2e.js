@@ -0,0 +1,271 @@
+// エンドツーエンド暗号化（E2E）の共通処理
+// - 端末ごとに ECDH(P-256) の鍵ペアを生成し、秘密鍵は IndexedDB に取り出し不可の状態で保存する
+// - チャットごとに AES-GCM のチャット鍵を生成し、参加者の各端末の公開鍵で暗号化してサーバーに預ける
+// - メッセージはチャット鍵でブラウザ上で暗号化し、サーバーには暗号文のみを送信する
+const E2E = (() => {
+  const DB_NAME = "chatapp-e2e";
+  const STORE_NAME = "devices";
+  const CONTENT_PREFIX = "e2e.v1.";
+  const WRAP_INFO = new TextEncoder().encode("chatapp-e2e-wrap-v1");
+
+  // Base64 とバイト列の相互変換
+  function toBase64(buffer) {
+    const bytes = new Uint8Array(buffer);
+    let binary = "";
+    bytes.forEach((b) => (binary += String.fromCharCode(b)));
+    return btoa(binary);
+  }
+
+  function fromBase64(value) {
+    const binary = atob(value);
+    const bytes = new Uint8Array(binary.length);
+    for (let i = 0; i < binary.length; i++) {
+      bytes[i] = binary.charCodeAt(i);
+    }
+    return bytes;
+  }
+
+  // IndexedDB を開く
+  function openDB() {
+    return new Promise((resolve, reject) => {
+      const request = indexedDB.open(DB_NAME, 1);
+      request.onupgradeneeded = () => {
+        request.result.createObjectStore(STORE_NAME, { keyPath: "userId" });
+      };
+      request.onsuccess = () => resolve(request.result);
+      request.onerror = () => reject(request.error);
+    });
+  }
+
+  async function loadDevice(userId) {
+    const db = await openDB();
+    return new Promise((resolve, reject) => {
+      const request = db
+        .transaction(STORE_NAME, "readonly")
+        .objectStore(STORE_NAME)
+        .get(userId);
+      request.onsuccess = () => resolve(request.result || null);
+      request.onerror = () => reject(request.error);
+    });
+  }
+
+  async function saveDevice(device) {
+    const db = await openDB();
+    return new Promise((resolve, reject) => {
+      const request = db
+        .transaction(STORE_NAME, "readwrite")
+        .objectStore(STORE_NAME)
+        .put(device);
+      request.onsuccess = () => resolve(device);
+      request.onerror = () => reject(request.error);
+    });
+  }
+
+  // この端末の鍵ペアを取得する（未登録の場合は生成してサーバーに公開鍵を登録する）
+  async function ensureDevice(userId) {
+    const existing = await loadDevice(userId);
+    if (existing) {
+      return existing;
+    }
+
+    const keyPair = await crypto.subtle.generateKey(
+      { name: "ECDH", namedCurve: "P-256" },
+      false,
+      ["deriveBits"]
+    );
+    const publicKey = toBase64(
+      await crypto.subtle.exportKey("spki", keyPair.publicKey)
+    );
+
+    const response = await fetch("/keys/devices", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        deviceName: navigator.platform || "ブラウザ",
+        publicKey: publicKey,
+      }),
+    });
+    if (!response.ok) {
+      throw new Error("公開鍵の登録に失敗しました");
+    }
+    const registered = await response.json();
+
+    return saveDevice({
+      userId: userId,
+      deviceId: registered.id,
+      publicKey: publicKey,
+      privateKey: keyPair.privateKey,
+    });
+  }
+
+  // ECDH の共有秘密から HKDF でチャット鍵を暗号化する鍵を導出する
+  async function deriveWrappingKey(privateKey, publicKeyBase64) {
+    const publicKey = await crypto.subtle.importKey(
+      "spki",
+      fromBase64(publicKeyBase64),
+      { name: "ECDH", namedCurve: "P-256" },
+      false,
+      []
+    );
+    const sharedBits = await crypto.subtle.deriveBits(
+      { name: "ECDH", public: publicKey },
+      privateKey,
+      256
+    );
+    const hkdfKey = await crypto.subtle.importKey(
+      "raw",
+      sharedBits,
+      "HKDF",
+      false,
+      ["deriveKey"]
+    );
+    return crypto.subtle.deriveKey(
+      { name: "HKDF", hash: "SHA-256", salt: new Uint8Array(32), info: WRAP_INFO },
+      hkdfKey,
+      { name: "AES-GCM", length: 256 },
+      false,
+      ["encrypt", "decrypt"]
+    );
+  }
+
+  // チャット鍵を端末の公開鍵で暗号化する
+  async function wrapChatKey(chatKey, device) {
+    const ephemeral = await crypto.subtle.generateKey(
+      { name: "ECDH", namedCurve: "P-256" },
+      true,
+      ["deriveBits"]
+    );
+    const wrappingKey = await deriveWrappingKey(
+      ephemeral.privateKey,
+      device.publicKey
+    );
+    const iv = crypto.getRandomValues(new Uint8Array(12));
+    const rawKey = await crypto.subtle.exportKey("raw", chatKey);
+    const wrapped = await crypto.subtle.encrypt(
+      { name: "AES-GCM", iv: iv },
+      wrappingKey,
+      rawKey
+    );
+    return {
+      deviceId: device.id,
+      ephemeralPublicKey: toBase64(
+        await crypto.subtle.exportKey("spki", ephemeral.publicKey)
+      ),
+      iv: toBase64(iv),
+      wrappedKey: toBase64(wrapped),
+    };
+  }
+
+  // 自分の端末宛てに暗号化されたチャット鍵を復号する
+  async function unwrapChatKey(device, payload) {
+    const wrappingKey = await deriveWrappingKey(
+      device.privateKey,
+      payload.ephemeralPublicKey
+    );
+    const rawKey = await crypto.subtle.decrypt(
+      { name: "AES-GCM", iv: fromBase64(payload.iv) },
+      wrappingKey,
+      fromBase64(payload.wrappedKey)
+    );
+    return crypto.subtle.importKey("raw", rawKey, "AES-GCM", true, [
+      "encrypt",
+      "decrypt",
+    ]);
+  }
+
+  async function fetchChatKeys(chatId, deviceId) {
+    const params = new URLSearchParams({ chat_id: chatId, device_id: deviceId });
+    const response = await fetch(`/chat/keys?${params}`);
+    if (!response.ok) {
+      throw new Error("チャット鍵の取得に失敗しました");
+    }
+    return response.json();
+  }
+
+  async function postChatKeys(chatId, enable, keys) {
+    const params = new URLSearchParams({ chat_id: chatId });
+    const response = await fetch(`/chat/keys?${params}`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ enable: enable, keys: keys }),
+    });
+    if (!response.ok) {
+      const body = await response.json().catch(() => ({}));
+      throw new Error(body.error || "チャット鍵の保存に失敗しました");
+    }
+  }
+
+  // チャット鍵を読み込む
+  // 鍵をまだ持たない参加者の端末があれば、この端末から鍵を共有する
+  async function loadChatKey(chatId, userId) {
+    const device = await ensureDevice(userId);
+    const state = await fetchChatKeys(chatId, device.deviceId);
+    if (!state.encrypted) {
+      return { encrypted: false, key: null };
+    }
+    if (!state.key) {
+      return { encrypted: true, key: null };
+    }
+
+    const chatKey = await unwrapChatKey(device, state.key);
+    if (state.missingDevices.length > 0) {
+      const keys = await Promise.all(
+        state.missingDevices.map((d) => wrapChatKey(chatKey, d))
+      );
+      postChatKeys(chatId, false, keys).catch((e) => console.error(e));
+    }
+    return { encrypted: true, key: chatKey };
+  }
+
+  // チャットのエンドツーエンド暗号化を有効にする
+  async function enableEncryption(chatId, userId) {
+    const device = await ensureDevice(userId);
+    const state = await fetchChatKeys(chatId, device.deviceId);
+    if (state.encrypted) {
+      return;
+    }
+
+    const chatKey = await crypto.subtle.generateKey(
+      { name: "AES-GCM", length: 256 },
+      true,
+      ["encrypt", "decrypt"]
+    );
+    const keys = await Promise.all(
+      state.devices.map((d) => wrapChatKey(chatKey, d))
+    );
+    await postChatKeys(chatId, true, keys);
+  }
+
+  // メッセージを暗号化する
+  async function encryptMessage(chatKey, text) {
+    const iv = crypto.getRandomValues(new Uint8Array(12));
+    const ciphertext = await crypto.subtle.encrypt(
+      { name: "AES-GCM", iv: iv },
+      chatKey,
+      new TextEncoder().encode(text)
+    );
+    return `${CONTENT_PREFIX}${toBase64(iv)}.${toBase64(ciphertext)}`;
+  }
+
+  // メッセージを復号する
+  async function decryptMessage(chatKey, content) {
+    if (!content.startsWith(CONTENT_PREFIX)) {
+      throw new Error("暗号化されたメッセージではありません");
+    }
+    const [iv, ciphertext] = content.slice(CONTENT_PREFIX.length).split(".");
+    const plaintext = await crypto.subtle.decrypt(
+      { name: "AES-GCM", iv: fromBase64(iv) },
+      chatKey,
+      fromBase64(ciphertext)
+    );
+    return new TextDecoder().decode(plaintext);
+  }
+
+  return {
+    ensureDevice,
+    loadChatKey,
+    enableEncryption,
+    encryptMessage,
+    decryptMessage,
+  };
+})();
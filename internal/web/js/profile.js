@@ -17,6 +17,12 @@ function handleIconChange(event) {
 
 // ページ読み込み時の処理
 document.addEventListener("DOMContentLoaded", function () {
+  // アイコン画像の選択
+  const iconUpload = document.getElementById("icon-upload");
+  if (iconUpload) {
+    iconUpload.addEventListener("change", handleIconChange);
+  }
+
   // 保存された画像を復元
   const savedIcon = localStorage.getItem("selectedIcon");
   if (savedIcon) {
@@ -24,3 +30,24 @@ document.addEventListener("DOMContentLoaded", function () {
     img.src = savedIcon;
   }
 });
+
+// 自分のプロフィールの場合、この端末の公開鍵を登録して一覧で示す
+document.addEventListener("DOMContentLoaded", async function () {
+  const keysSection = document.getElementById("e2e");
+  const profileIcon = document.getElementById("profile-icon");
+  if (!keysSection || !profileIcon) {
+    return;
+  }
+
+  try {
+    const device = await E2E.ensureDevice(keysSection.dataset.userId);
+    const item = keysSection.querySelector(
+      `[data-device-id="${device.deviceId}"] .js-currentDevice`
+    );
+    if (item) {
+      item.textContent = "（この端末）";
+    }
+  } catch (error) {
+    console.error("Error:", error);
+  }
+});
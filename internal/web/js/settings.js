@@ -1,16 +1,24 @@
-// ページ読み込み時にフォームの表示状態を設定
+// フォームの表示状態は is-active クラスで切り替える（CSP でインラインの style 属性を許可していないため）
 document.addEventListener("DOMContentLoaded", function () {
-  // ユーザー名変更フォームの表示状態を設定
-  const usernameForm = document.querySelector(".l-settings__usernameForm");
-  if (usernameForm && usernameForm.classList.contains("is-active")) {
-    usernameForm.style.display = "block";
-  }
-
-  // パスワード変更フォームの表示状態を設定
-  const passwordForm = document.querySelector(".l-settings__passwordForm");
-  if (passwordForm && passwordForm.classList.contains("is-active")) {
-    passwordForm.style.display = "block";
-  }
+  // フォームの表示切り替えボタン
+  document.querySelectorAll(".js-toggleUsernameForm").forEach((button) => {
+    button.addEventListener("click", toggleUsernameForm);
+  });
+  document.querySelectorAll(".js-togglePasswordForm").forEach((button) => {
+    button.addEventListener("click", togglePasswordForm);
+  });
+  document.querySelectorAll(".js-toggleAPITokenForm").forEach((button) => {
+    button.addEventListener("click", toggleAPITokenForm);
+  });
+  document.querySelectorAll(".js-toggleBotForm").forEach((button) => {
+    button.addEventListener("click", toggleBotForm);
+  });
+  document.querySelectorAll(".js-toggleWebhookForm").forEach((button) => {
+    button.addEventListener("click", toggleWebhookForm);
+  });
+  document.querySelectorAll(".js-toggleIncomingWebhookForm").forEach((button) => {
+    button.addEventListener("click", toggleIncomingWebhookForm);
+  });
 });
 
 // パスワード変更フォームの表示/非表示を切り替える
@@ -18,13 +26,7 @@ function togglePasswordForm() {
   const form = document.querySelector(".l-settings__passwordForm");
   if (form) {
     // フォームの表示状態を切り替え
-    if (form.style.display === "none" || form.style.display === "") {
-      form.style.display = "block";
-      form.classList.add("is-active");
-    } else {
-      form.style.display = "none";
-      form.classList.remove("is-active");
-
+    if (!form.classList.toggle("is-active")) {
       // フォームが非表示になった場合、入力をクリア
       const inputs = form.querySelectorAll("input[type='password']");
       inputs.forEach((input) => (input.value = ""));
@@ -40,13 +42,7 @@ function toggleUsernameForm() {
   const form = document.querySelector(".l-settings__usernameForm");
   if (form) {
     // フォームの表示状態を切り替え
-    if (form.style.display === "none" || form.style.display === "") {
-      form.style.display = "block";
-      form.classList.add("is-active");
-    } else {
-      form.style.display = "none";
-      form.classList.remove("is-active");
-
+    if (!form.classList.toggle("is-active")) {
       // フォームが非表示になった場合、入力をクリア
       const inputs = form.querySelectorAll("input[type='text']");
       inputs.forEach((input) => (input.value = ""));
@@ -56,3 +52,39 @@ function toggleUsernameForm() {
     window.location.href = "/settings?show_username_form=true";
   }
 }
+
+// APIトークン発行フォームの表示/非表示を切り替える
+function toggleAPITokenForm() {
+  const form = document.querySelector(".l-settings__tokenForm");
+  if (!form) {
+    return;
+  }
+  form.classList.toggle("is-active");
+}
+
+// ボット作成フォームの表示/非表示を切り替える
+function toggleBotForm() {
+  const form = document.querySelector(".l-settings__botForm");
+  if (!form) {
+    return;
+  }
+  form.classList.toggle("is-active");
+}
+
+// Webhook登録フォームの表示/非表示を切り替える
+function toggleWebhookForm() {
+  const form = document.querySelector(".l-settings__webhookForm");
+  if (!form) {
+    return;
+  }
+  form.classList.toggle("is-active");
+}
+
+// 受信用Webhook作成フォームの表示/非表示を切り替える
+function toggleIncomingWebhookForm() {
+  const form = document.querySelector(".l-settings__incomingWebhookForm");
+  if (!form) {
+    return;
+  }
+  form.classList.toggle("is-active");
+}
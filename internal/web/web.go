@@ -0,0 +1,24 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// 画面で使用するファイルのディレクトリ（作業ディレクトリからの相対パス）
+const Dir = "internal/web"
+
+// バイナリに埋め込んだテンプレートと静的ファイル
+//
+//go:embed templates css js images
+var embedded embed.FS
+
+// FS テンプレートと静的ファイル（css / js / images）を返す
+// embedAssets が true の場合はバイナリに埋め込んだファイルを、それ以外はディスク上のファイルを使う
+func FS(embedAssets bool) fs.FS {
+	if embedAssets {
+		return embedded
+	}
+	return os.DirFS(Dir)
+}